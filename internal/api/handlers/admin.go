@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 處理管理員對用戶和房間的管理請求
+type AdminHandler struct {
+	adminService *service.AdminService
+}
+
+// NewAdminHandler 創建新的管理處理器
+func NewAdminHandler(adminService *service.AdminService) *AdminHandler {
+	return &AdminHandler{adminService: adminService}
+}
+
+// ListUsers 查詢用戶，支援 q（用戶名）、role、disabled、limit、offset 參數
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	params := service.UserListParams{
+		Query: c.Query("q"),
+		Role:  c.Query("role"),
+	}
+	if value := c.Query("disabled"); value != "" {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "無效的 disabled 參數"})
+			return
+		}
+		params.Disabled = &disabled
+	}
+	limit, ok := queryUint(c, "limit", "無效的 limit 參數")
+	if !ok {
+		return
+	}
+	offset, ok := queryUint(c, "offset", "無效的 offset 參數")
+	if !ok {
+		return
+	}
+	params.Limit, params.Offset = int(limit), int(offset)
+
+	list, err := h.adminService.ListUsers(params)
+	if err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// DisableUser 停用帳號
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	h.userAction(c, h.adminService.DisableUser)
+}
+
+// EnableUser 重新啟用帳號
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.userAction(c, h.adminService.EnableUser)
+}
+
+// DisconnectUser 斷開用戶的所有 WebSocket 連接
+func (h *AdminHandler) DisconnectUser(c *gin.Context) {
+	h.userAction(c, h.adminService.DisconnectUser)
+}
+
+// ResetPassword 重設用戶的密碼
+func (h *AdminHandler) ResetPassword(c *gin.Context) {
+	var input struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.userAction(c, func(adminID, userID uint) error {
+		return h.adminService.ResetPassword(adminID, userID, input.Password)
+	})
+}
+
+// ResetTwoFactor 為遺失驗證器的用戶停用兩步驟驗證
+func (h *AdminHandler) ResetTwoFactor(c *gin.Context) {
+	h.userAction(c, h.adminService.ResetTwoFactor)
+}
+
+// ChangeRole 變更用戶的全站角色
+func (h *AdminHandler) ChangeRole(c *gin.Context) {
+	var input struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	h.userAction(c, func(adminID, userID uint) error {
+		return h.adminService.ChangeRole(adminID, userID, models.UserRole(input.Role))
+	})
+}
+
+// FinishRoom 強制結束房間的辯論
+func (h *AdminHandler) FinishRoom(c *gin.Context) {
+	h.roomAction(c, h.adminService.FinishRoom)
+}
+
+// DeleteRoom 刪除房間
+func (h *AdminHandler) DeleteRoom(c *gin.Context) {
+	h.roomAction(c, h.adminService.DeleteRoom)
+}
+
+// DisconnectRoom 斷開房間內的所有 WebSocket 連接
+func (h *AdminHandler) DisconnectRoom(c *gin.Context) {
+	h.roomAction(c, h.adminService.DisconnectRoom)
+}
+
+// userAction 解析路徑中的用戶 ID 並執行操作
+func (h *AdminHandler) userAction(c *gin.Context, action func(adminID, userID uint) error) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的用戶ID",
+		})
+		return
+	}
+
+	if err := action(c.GetUint("userID"), uint(userID)); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "操作成功"})
+}
+
+// roomAction 解析路徑中的房間 ID 並執行操作
+func (h *AdminHandler) roomAction(c *gin.Context, action func(adminID, roomID uint) error) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	if err := action(c.GetUint("userID"), uint(roomID)); err != nil {
+		adminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "操作成功"})
+}
+
+// adminError 根據管理服務的錯誤回應對應的狀態碼
+func adminError(c *gin.Context, err error) {
+	var policy *service.PasswordPolicyError
+	if errors.As(err, &policy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err.Error() {
+	case "用戶不存在", "房間不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "不能對自己執行此操作":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "無效的角色":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "辯論已經結束":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失敗"})
+	}
+}
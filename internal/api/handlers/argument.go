@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ArgumentHandler 處理辯論論點結構相關的請求
+type ArgumentHandler struct {
+	argumentService *service.ArgumentService
+}
+
+// NewArgumentHandler 創建新的論點處理器
+func NewArgumentHandler(argumentService *service.ArgumentService) *ArgumentHandler {
+	return &ArgumentHandler{argumentService: argumentService}
+}
+
+// GetArgumentTree 返回房間辯論場上的論點樹
+func (h *ArgumentHandler) GetArgumentTree(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	tree, err := h.argumentService.Tree(uint(roomID))
+	if err != nil {
+		if err.Error() == "房間不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取論點樹失敗"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"arguments": tree,
+	})
+}
+
+// GetArgumentMap 以 Graphviz DOT 或 JSON 圖格式返回房間的論點圖
+func (h *ArgumentHandler) GetArgumentMap(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "dot" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "不支援的格式，請使用 dot 或 json",
+		})
+		return
+	}
+
+	graph, err := h.argumentService.Graph(uint(roomID))
+	if err != nil {
+		if err.Error() == "房間不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取論點圖失敗"})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, graph)
+		return
+	}
+
+	c.Header("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d.dot"`, roomID))
+	c.Status(http.StatusOK)
+	if err := graph.WriteDOT(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
@@ -3,7 +3,12 @@ package handlers
 import (
 	"debate_web/internal/repository/models"
 	"debate_web/internal/service"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -11,13 +16,21 @@ import (
 
 // AuthHandler 處理認證相關的請求
 type AuthHandler struct {
-	userService *service.UserService
+	userService      *service.UserService
+	tokenService     *service.TokenService
+	passwordService  *service.PasswordService
+	emailService     *service.EmailService
+	twoFactorService *service.TwoFactorService
 }
 
 // NewAuthHandler 創建新的認證處理器
-func NewAuthHandler(userService *service.UserService) *AuthHandler {
+func NewAuthHandler(userService *service.UserService, tokenService *service.TokenService, passwordService *service.PasswordService, emailService *service.EmailService, twoFactorService *service.TwoFactorService) *AuthHandler {
 	return &AuthHandler{
-		userService: userService,
+		userService:      userService,
+		tokenService:     tokenService,
+		passwordService:  passwordService,
+		emailService:     emailService,
+		twoFactorService: twoFactorService,
 	}
 }
 
@@ -27,10 +40,11 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
-// RegisterInput 定義註冊請求的結構
+// RegisterInput 定義註冊請求的結構，密碼的規則由設定檔決定，電子郵件可以省略
 type RegisterInput struct {
 	Username string `json:"username" binding:"required,min=3,max=32"`
-	Password string `json:"password" binding:"required,min=6,max=32"`
+	Password string `json:"password" binding:"required"`
+	Email    string `json:"email"`
 }
 
 // Register 處理用戶註冊
@@ -44,6 +58,24 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	if err := h.passwordService.Validate(input.Username, input.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	email, err := h.emailService.PrepareEmail(input.Email)
+	if err != nil {
+		switch err.Error() {
+		case "無效的電子郵件地址", "電子郵件已被使用":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "創建用戶失敗"})
+		}
+		return
+	}
+
 	// 檢查用戶名是否已存在
 	exist, _ := h.userService.CheckUserExists(input.Username)
 	if exist {
@@ -66,6 +98,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	user := &models.User{
 		Username: input.Username,
 		Password: string(hashedPassword),
+		Email:    email,
 	}
 
 	if err := h.userService.CreateUser(user); err != nil {
@@ -75,11 +108,19 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// 寄出驗證郵件，失敗時用戶之後可以重新寄送
+	if email != nil {
+		if err := h.emailService.SendVerification(user.ID); err != nil {
+			log.Printf("send verification mail to user %d error: %v", user.ID, err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "註冊成功",
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
+			"email":    user.Email,
 		},
 	})
 }
@@ -95,25 +136,119 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// 查找用戶
-	user, err := h.userService.GetUserByUsername(input.Username)
+	// 驗證用戶名和密碼
+	user, err := h.userService.Authenticate(input.Username, input.Password)
+	if err != nil {
+		var locked *service.AccountLockedError
+		switch {
+		case errors.As(err, &locked):
+			accountLocked(c, locked)
+		case err.Error() == "用戶名或密碼錯誤":
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+		case err.Error() == "帳號已被停用":
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "登入失敗",
+			})
+		}
+		return
+	}
+
+	h.signIn(c, user)
+}
+
+// signIn 在第一步的驗證通過後完成登入
+// 啟用兩步驟驗證或角色必須啟用時，先發出 challenge token，輸入驗證碼後才發出憑證
+func (h *AuthHandler) signIn(c *gin.Context, user *models.User) {
+	challenge, err := h.twoFactorService.Challenge(user)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "用戶名或密碼錯誤",
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "登入失敗",
 		})
 		return
 	}
+	if challenge != nil {
+		message := "請輸入兩步驟驗證碼"
+		if challenge.SetupRequired {
+			message = "你的角色必須啟用兩步驟驗證，請先完成設定"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":             message,
+			"two_factor_required": true,
+			"challenge_token":     challenge.Token,
+			"expires_in":          challenge.ExpiresIn,
+			"setup_required":      challenge.SetupRequired,
+		})
+		return
+	}
+
+	h.issueTokens(c, user, nil)
+}
+
+// TwoFactorLoginInput 定義登入第二步的結構，code 可以是驗證碼或備用碼
+type TwoFactorLoginInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// LoginTwoFactor 以驗證碼或備用碼完成登入
+// 登入時才完成設定的用戶會在回應中得到只顯示這一次的備用碼
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	var input TwoFactorLoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, codes, err := h.twoFactorService.CompleteLogin(input.ChallengeToken, input.Code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+
+	var extra gin.H
+	if codes != nil {
+		extra = gin.H{"recovery_codes": codes}
+	}
+	h.issueTokens(c, user, extra)
+}
 
-	// 驗證密碼
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "用戶名或密碼錯誤",
+// TwoFactorSetupInput 定義登入時設定兩步驟驗證的結構
+type TwoFactorSetupInput struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+// LoginTwoFactorSetup 為角色必須啟用兩步驟驗證的用戶產生密鑰和 QR Code，之後以 LoginTwoFactor 確認並完成登入
+func (h *AuthHandler) LoginTwoFactorSetup(c *gin.Context) {
+	var input TwoFactorSetupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
 		})
 		return
 	}
 
-	// 生成 JWT token
-	token, err := h.userService.GenerateToken(user.ID)
+	enrollment, err := h.twoFactorService.ChallengeEnroll(input.ChallengeToken)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// issueTokens 發出 access token 和 refresh token，extra 中的欄位會加入回應
+func (h *AuthHandler) issueTokens(c *gin.Context, user *models.User, extra gin.H) {
+	tokens, err := h.tokenService.Issue(user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "生成token失敗",
@@ -121,12 +256,83 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "登入成功",
-		"token":   token,
+	response := gin.H{
+		"message":            "登入成功",
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"token_type":         tokens.TokenType,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
+			"role":     user.Role,
 		},
+	}
+	for key, value := range extra {
+		response[key] = value
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// accountLocked 回應帳號被鎖定，Retry-After 是解除鎖定前的秒數
+func accountLocked(c *gin.Context, locked *service.AccountLockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(locked.Until).Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":        locked.Error(),
+		"locked_until": locked.Until,
 	})
 }
+
+// RefreshInput 定義換發 token 請求的結構
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh 以 refresh token 換發新的 access token 和 refresh token
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(input.RefreshToken)
+	if err != nil {
+		if err.Error() == "無效的 refresh token" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "換發token失敗"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout 登出目前的裝置
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if err := h.tokenService.Logout(c.GetUint("userID"), c.GetString("tokenID"), c.GetTime("tokenExpiresAt")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失敗"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已登出"})
+}
+
+// LogoutAll 登出用戶的所有裝置
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.tokenService.LogoutAll(c.GetUint("userID"), c.GetString("tokenID"), c.GetTime("tokenExpiresAt")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "登出失敗"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已登出所有裝置"})
+}
+
+// JWKS 返回驗證 access token 用的公鑰，供其他服務驗證本服務簽發的 token
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokenService.JWKS())
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailHandler 處理電子郵件地址和驗證的請求
+type EmailHandler struct {
+	emailService *service.EmailService
+}
+
+// NewEmailHandler 創建新的電子郵件處理器
+func NewEmailHandler(emailService *service.EmailService) *EmailHandler {
+	return &EmailHandler{emailService: emailService}
+}
+
+// SetEmailInput 定義設定電子郵件請求的結構，空字串表示移除
+type SetEmailInput struct {
+	Email string `json:"email"`
+}
+
+// SetEmail 設定目前用戶的電子郵件地址，並寄出驗證郵件
+func (h *EmailHandler) SetEmail(c *gin.Context) {
+	var input SetEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.emailService.SetEmail(c.GetUint("userID"), input.Email); err != nil {
+		emailError(c, err)
+		return
+	}
+	if input.Email == "" {
+		c.JSON(http.StatusOK, gin.H{"message": "電子郵件已移除"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "驗證郵件已寄出"})
+}
+
+// ResendVerification 重新寄出驗證郵件
+func (h *EmailHandler) ResendVerification(c *gin.Context) {
+	if err := h.emailService.SendVerification(c.GetUint("userID")); err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "驗證郵件已寄出"})
+}
+
+// VerifyEmailInput 定義驗證電子郵件請求的結構
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmail 以驗證郵件中的 token 完成驗證，不需要登入
+func (h *EmailHandler) VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.emailService.Verify(input.Token); err != nil {
+		emailError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "電子郵件已驗證"})
+}
+
+// emailError 根據電子郵件服務的錯誤回應對應的狀態碼
+func emailError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "無效的電子郵件地址", "無效或已過期的驗證連結", "沒有需要驗證的電子郵件":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "電子郵件已被使用", "電子郵件已經驗證過了":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "用戶不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失敗"})
+	}
+}
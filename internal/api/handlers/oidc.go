@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCHandler 處理以外部 OpenID Connect 提供者登入和連結帳號的請求
+type OIDCHandler struct {
+	oidcService *service.OIDCService
+	auth        *AuthHandler // 完成第一步驗證後沿用密碼登入的流程，包括兩步驟驗證
+}
+
+// NewOIDCHandler 創建新的 OIDC 處理器
+func NewOIDCHandler(oidcService *service.OIDCService, auth *AuthHandler) *OIDCHandler {
+	return &OIDCHandler{oidcService: oidcService, auth: auth}
+}
+
+// OIDCCallbackInput 定義提供者導回後前端送回的授權碼和 state
+type OIDCCallbackInput struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// GetProvider 返回是否啟用 OIDC 登入，供前端決定是否顯示登入按鈕
+func (h *OIDCHandler) GetProvider(c *gin.Context) {
+	c.JSON(http.StatusOK, h.oidcService.Provider())
+}
+
+// BeginLogin 發起 OIDC 登入，返回提供者的授權網址
+func (h *OIDCHandler) BeginLogin(c *gin.Context) {
+	authorization, err := h.oidcService.BeginLogin(c.Request.Context())
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, authorization)
+}
+
+// Callback 以提供者導回的授權碼完成登入，回應與密碼登入相同
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var input OIDCCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := h.oidcService.CompleteLogin(c.Request.Context(), input.Code, input.State)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	h.auth.signIn(c, user)
+}
+
+// ListIdentities 返回目前用戶已連結的提供者帳號
+func (h *OIDCHandler) ListIdentities(c *gin.Context) {
+	identities, err := h.oidcService.ListIdentities(c.GetUint("userID"))
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// BeginLink 為目前的用戶發起連結，返回提供者的授權網址
+func (h *OIDCHandler) BeginLink(c *gin.Context) {
+	authorization, err := h.oidcService.BeginLink(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, authorization)
+}
+
+// CompleteLink 以提供者導回的授權碼將提供者帳號連結到目前的用戶
+func (h *OIDCHandler) CompleteLink(c *gin.Context) {
+	var input OIDCCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	identity, err := h.oidcService.CompleteLink(c.Request.Context(), c.GetUint("userID"), input.Code, input.State)
+	if err != nil {
+		oidcError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "已連結帳號",
+		"identity": identity,
+	})
+}
+
+// oidcError 根據 OIDC 服務的錯誤回應對應的狀態碼
+func oidcError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "未啟用 OIDC 登入", "用戶不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "無效或已過期的登入狀態", "OIDC 登入失敗":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "帳號已被停用", "此提供者帳號尚未連結，請先以密碼登入後連結":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "此提供者帳號已連結到其他用戶":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "無法連線到身分提供者":
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失敗"})
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordHandler 處理修改密碼和忘記密碼的請求
+type PasswordHandler struct {
+	passwordService *service.PasswordService
+}
+
+// NewPasswordHandler 創建新的密碼處理器
+func NewPasswordHandler(passwordService *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{passwordService: passwordService}
+}
+
+// ChangePasswordInput 定義修改密碼請求的結構
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// ChangePassword 修改目前用戶的密碼，其他裝置會被登出
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	var input ChangePasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	err := h.passwordService.Change(c.GetUint("userID"), c.GetString("tokenID"), input.CurrentPassword, input.NewPassword)
+	if err != nil {
+		passwordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "密碼已更新，其他裝置已登出"})
+}
+
+// ForgotPasswordInput 定義忘記密碼請求的結構，username 也可以填寫電子郵件地址
+type ForgotPasswordInput struct {
+	Username string `json:"username" binding:"required"`
+}
+
+// ForgotPassword 將重設密碼的連結寄到用戶已驗證的電子郵件，無論用戶是否存在都回應相同的內容
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.passwordService.RequestReset(input.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "申請重設密碼失敗"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "如果帳號存在，重設密碼的連結已經寄出"})
+}
+
+// ResetPasswordInput 定義重設密碼請求的結構
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword 以郵件中的 token 設定新密碼，所有裝置都會被登出
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.passwordService.Reset(input.Token, input.Password); err != nil {
+		passwordError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "密碼已重設，請重新登入"})
+}
+
+// passwordError 根據密碼服務的錯誤回應對應的狀態碼
+func passwordError(c *gin.Context, err error) {
+	var policy *service.PasswordPolicyError
+	if errors.As(err, &policy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err.Error() {
+	case "目前的密碼不正確":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "新密碼不能與目前的密碼相同", "無效或已過期的重設連結":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "用戶不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新密碼失敗"})
+	}
+}
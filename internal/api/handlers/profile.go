@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileHandler 處理個人資料和頭像的請求
+type ProfileHandler struct {
+	profileService *service.ProfileService
+}
+
+// NewProfileHandler 創建新的個人資料處理器
+func NewProfileHandler(profileService *service.ProfileService) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService}
+}
+
+// GetMyProfile 返回目前用戶的完整個人資料
+func (h *ProfileHandler) GetMyProfile(c *gin.Context) {
+	profile, err := h.profileService.GetProfile(c.GetUint("userID"))
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateMyProfile 修改目前用戶的個人資料，只更新請求中出現的欄位
+func (h *ProfileHandler) UpdateMyProfile(c *gin.Context) {
+	var input service.ProfileUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	profile, err := h.profileService.UpdateProfile(c.GetUint("userID"), input)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// GetProfile 返回用戶公開的個人資料
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的用戶ID",
+		})
+		return
+	}
+
+	profile, err := h.profileService.GetPublicProfile(uint(userID))
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// UploadAvatar 以 multipart 表單的 avatar 欄位上傳頭像
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	// 保留表單其餘部分的空間，實際的檔案大小由服務檢查
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.profileService.MaxAvatarBytes()+64<<10)
+
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		if strings.Contains(err.Error(), "request body too large") {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "頭像檔案過大"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少頭像檔案"})
+		return
+	}
+	defer file.Close()
+
+	profile, err := h.profileService.UploadAvatar(c.GetUint("userID"), file)
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+// DeleteAvatar 移除目前用戶的頭像
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	if err := h.profileService.DeleteAvatar(c.GetUint("userID")); err != nil {
+		profileError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "頭像已移除"})
+}
+
+// GetAvatar 返回用戶的頭像圖片，size 參數選擇縮圖的尺寸
+func (h *ProfileHandler) GetAvatar(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的用戶ID",
+		})
+		return
+	}
+	size, ok := queryUint(c, "size", "無效的 size 參數")
+	if !ok {
+		return
+	}
+
+	avatar, err := h.profileService.Avatar(uint(userID), int(size))
+	if err != nil {
+		profileError(c, err)
+		return
+	}
+	defer avatar.Close()
+
+	// 帶版本號的網址內容不會改變，可以長期快取
+	if c.Query("v") != "" {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	c.Header("Content-Type", "image/png")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, avatar)
+}
+
+// profileError 根據個人資料服務的錯誤回應對應的狀態碼
+func profileError(c *gin.Context, err error) {
+	switch msg := err.Error(); {
+	case msg == "用戶不存在", msg == "沒有頭像":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case msg == "頭像檔案過大":
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": msg})
+	case msg == "不支援的圖片格式", msg == "無效的圖片", msg == "圖片尺寸過大",
+		msg == "不支援的語言", msg == "無效的辯論方", msg == "顯示名稱包含無效的字元",
+		strings.HasPrefix(msg, "顯示名稱不能超過"), strings.HasPrefix(msg, "個人簡介不能超過"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失敗"})
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuestionHandler 處理觀眾問答相關的請求
+type QuestionHandler struct {
+	questionService *service.QuestionService
+}
+
+// NewQuestionHandler 創建新的問答處理器
+func NewQuestionHandler(questionService *service.QuestionService) *QuestionHandler {
+	return &QuestionHandler{questionService: questionService}
+}
+
+// ListQuestions 返回按點讚數排序的待處理問題，僅限房間主持人
+func (h *QuestionHandler) ListQuestions(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	questions, err := h.questionService.ListQueue(uint(roomID), c.GetUint("userID"))
+	if err != nil {
+		switch err.Error() {
+		case "房間不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "只有主持人可以查看問題隊列":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取問題隊列失敗"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": questions,
+	})
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"debate_web/internal/service"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandler 處理辯論報告匯出的請求
+type ReportHandler struct {
+	reportService *service.ReportService
+}
+
+// NewReportHandler 創建新的報告處理器
+func NewReportHandler(reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{reportService: reportService}
+}
+
+// GetReportPDF 返回已結束辯論的 PDF 報告
+func (h *ReportHandler) GetReportPDF(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.reportService.WriteReport(&buf, uint(roomID)); err != nil {
+		switch err.Error() {
+		case "房間不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "辯論尚未結束":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "產生報告失敗"})
+		}
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="room-%d-report.pdf"`, roomID))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReviewHandler 處理消息檢舉和審核相關的請求
+type ReviewHandler struct {
+	reviewService *service.ReviewService
+}
+
+// NewReviewHandler 創建新的審核處理器
+func NewReviewHandler(reviewService *service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{reviewService: reviewService}
+}
+
+// ReportMessage 檢舉消息
+func (h *ReviewHandler) ReportMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的消息ID",
+		})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.reviewService.ReportMessage(uint(messageID), c.GetUint("userID"), req.Reason)
+	if err != nil {
+		switch {
+		case err.Error() == "消息不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case err.Error() == "只有房間的參與者可以檢舉消息":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case err.Error() == "您已檢舉過此消息":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err.Error() == "不能檢舉自己的消息", strings.HasPrefix(err.Error(), "檢舉原因長度"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "檢舉消息失敗"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": "已收到檢舉"})
+}
+
+// ListReviewQueue 返回等待審核和被檢舉的消息，僅限房間主持人
+func (h *ReviewHandler) ListReviewQueue(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	queue, err := h.reviewService.ReviewQueue(uint(roomID), c.GetUint("userID"))
+	if err != nil {
+		switch err.Error() {
+		case "房間不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "只有主持人可以審核消息":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取審核隊列失敗"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": queue,
+	})
+}
+
+// ReviewMessage 對消息執行審核動作
+func (h *ReviewHandler) ReviewMessage(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+	messageID, err := strconv.ParseUint(c.Param("message_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的消息ID",
+		})
+		return
+	}
+
+	var action service.ReviewAction
+	if err := c.ShouldBindJSON(&action); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.reviewService.Review(uint(roomID), uint(messageID), c.GetUint("userID"), action)
+	if err != nil {
+		switch err.Error() {
+		case "房間不存在", "消息不存在", "用戶不在此房間中":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "只有主持人可以審核消息", "不能對主持人執行此操作":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case "無效的審核動作", "無效的禁言時長":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "審核消息失敗"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "審核完成"})
+}
@@ -10,11 +10,15 @@ import (
 )
 
 type RoomHandler struct {
-	roomService *service.RoomService
+	roomService     *service.RoomService
+	reactionService *service.ReactionService
 }
 
-func NewRoomHandler(roomService *service.RoomService) *RoomHandler {
-	return &RoomHandler{roomService: roomService}
+func NewRoomHandler(roomService *service.RoomService, reactionService *service.ReactionService) *RoomHandler {
+	return &RoomHandler{
+		roomService:     roomService,
+		reactionService: reactionService,
+	}
 }
 
 func (h *RoomHandler) CreateRoom(c *gin.Context) {
@@ -24,7 +28,7 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 		return
 	}
 
-	if err := h.roomService.CreateRoom(&room); err != nil {
+	if err := h.roomService.CreateRoom(&room, c.GetUint("userID")); err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
@@ -39,6 +43,10 @@ func (h *RoomHandler) JoinRoom(c *gin.Context) {
 
 	err := h.roomService.JoinRoom(uint(roomID), userID, role)
 	if err != nil {
+		if err.Error() == "您已被禁止加入此房間" || err.Error() == "請先驗證電子郵件" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
@@ -69,11 +77,21 @@ func (h *RoomHandler) GetRoom(c *gin.Context) {
 	response := gin.H{
 		"id":           room.ID,
 		"name":         room.Name,
+		"format":       room.Format,
 		"status":       room.Status,
+		"owner_id":     room.OwnerID,
 		"created_at":   room.CreatedAt,
 		"proponent_id": room.ProponentID,
 		"opponent_id":  room.OpponentID,
 		"spectators":   room.Spectators,
+		"tags":         room.Tags,
+	}
+
+	// 辯論結束後提供雙方的掌聲指標
+	if room.Status == models.RoomStatusFinished {
+		if applause, err := h.reactionService.Applause(room.ID); err == nil {
+			response["applause"] = applause
+		}
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SanctionHandler 處理主持人禁言、踢出和封鎖參與者的請求
+type SanctionHandler struct {
+	sanctionService *service.SanctionService
+}
+
+// NewSanctionHandler 創建新的處分處理器
+func NewSanctionHandler(sanctionService *service.SanctionService) *SanctionHandler {
+	return &SanctionHandler{sanctionService: sanctionService}
+}
+
+// Mute 禁言參與者
+func (h *SanctionHandler) Mute(c *gin.Context) {
+	h.apply(c, h.sanctionService.Mute)
+}
+
+// Kick 將參與者踢出房間
+func (h *SanctionHandler) Kick(c *gin.Context) {
+	h.apply(c, h.sanctionService.Kick)
+}
+
+// Ban 禁止用戶加入房間
+func (h *SanctionHandler) Ban(c *gin.Context) {
+	h.apply(c, h.sanctionService.Ban)
+}
+
+// Unmute 解除參與者的禁言
+func (h *SanctionHandler) Unmute(c *gin.Context) {
+	h.revoke(c, h.sanctionService.Unmute)
+}
+
+// Unban 解除用戶的封鎖
+func (h *SanctionHandler) Unban(c *gin.Context) {
+	h.revoke(c, h.sanctionService.Unban)
+}
+
+// ListSanctions 返回房間的處分紀錄
+func (h *SanctionHandler) ListSanctions(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	sanctions, err := h.sanctionService.ListSanctions(uint(roomID), c.GetUint("userID"))
+	if err != nil {
+		sanctionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sanctions": sanctions,
+	})
+}
+
+// apply 解析請求並執行處分
+func (h *SanctionHandler) apply(c *gin.Context, action func(roomID, moderatorID uint, req service.SanctionRequest) error) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	var req service.SanctionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := action(uint(roomID), c.GetUint("userID"), req); err != nil {
+		sanctionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "操作成功"})
+}
+
+// revoke 解除路徑中指定用戶的處分
+func (h *SanctionHandler) revoke(c *gin.Context, action func(roomID, moderatorID, userID uint) error) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的用戶ID",
+		})
+		return
+	}
+
+	if err := action(uint(roomID), c.GetUint("userID"), uint(userID)); err != nil {
+		sanctionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "操作成功"})
+}
+
+// sanctionError 根據處分服務的錯誤回應對應的狀態碼
+func sanctionError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "房間不存在", "用戶不在此房間中", "用戶沒有被禁言", "用戶沒有被封鎖":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "只有主持人可以管理參與者", "不能對主持人執行此操作":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "無效的用戶ID", "無效的禁言時長", "無效的封鎖時長":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失敗"})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler 處理消息搜尋相關的請求
+type SearchHandler struct {
+	searchService *service.SearchService
+}
+
+// NewSearchHandler 創建新的搜尋處理器
+func NewSearchHandler(searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// SearchMessages 全文搜尋消息
+// 參數：q 關鍵字，room_id、user_id、side、tag 過濾條件，from、to 日期範圍 (RFC3339 或 2006-01-02)，limit、offset 分頁
+func (h *SearchHandler) SearchMessages(c *gin.Context) {
+	params := service.SearchParams{
+		Query: c.Query("q"),
+		Side:  c.Query("side"),
+		Tag:   c.Query("tag"),
+	}
+
+	var ok bool
+	if params.RoomID, ok = queryUint(c, "room_id", "無效的房間ID"); !ok {
+		return
+	}
+	if params.UserID, ok = queryUint(c, "user_id", "無效的用戶ID"); !ok {
+		return
+	}
+	if params.From, ok = queryDate(c, "from", false); !ok {
+		return
+	}
+	if params.To, ok = queryDate(c, "to", true); !ok {
+		return
+	}
+	limit, ok := queryUint(c, "limit", "無效的分頁參數")
+	if !ok {
+		return
+	}
+	offset, ok := queryUint(c, "offset", "無效的分頁參數")
+	if !ok {
+		return
+	}
+	params.Limit, params.Offset = int(limit), int(offset)
+
+	hits, err := h.searchService.Search(params)
+	if err != nil {
+		switch err.Error() {
+		case "搜尋關鍵字不能為空", "無效的發言方", "無效的日期範圍":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "搜尋消息失敗"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": hits,
+		"limit":   params.Limit,
+		"offset":  params.Offset,
+	})
+}
+
+// queryUint 解析非負整數的查詢參數，未提供時返回 0，格式錯誤時回應 400
+func queryUint(c *gin.Context, key, message string) (uint, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(n), true
+}
+
+// queryDate 解析日期查詢參數，只有日期的結束時間包含當天
+func queryDate(c *gin.Context, key string, end bool) (time.Time, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的日期格式"})
+		return time.Time{}, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatsHandler 處理發言統計相關的請求
+type StatsHandler struct {
+	statsService *service.StatsService
+}
+
+// NewStatsHandler 創建新的統計處理器
+func NewStatsHandler(statsService *service.StatsService) *StatsHandler {
+	return &StatsHandler{statsService: statsService}
+}
+
+// GetRoomStats 返回房間內每位發言者的統計
+func (h *StatsHandler) GetRoomStats(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	stats, err := h.statsService.RoomStats(uint(roomID))
+	if err != nil {
+		if err.Error() == "房間不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取房間統計失敗"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetUserStats 返回用戶擔任辯手的累計統計
+func (h *StatsHandler) GetUserStats(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的用戶ID",
+		})
+		return
+	}
+
+	stats, err := h.statsService.UserStats(uint(userID))
+	if err != nil {
+		if err.Error() == "用戶不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取用戶統計失敗"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranscriptHandler 處理辯論紀錄匯出的請求
+type TranscriptHandler struct {
+	transcriptService *service.TranscriptService
+}
+
+// NewTranscriptHandler 創建新的紀錄匯出處理器
+func NewTranscriptHandler(transcriptService *service.TranscriptService) *TranscriptHandler {
+	return &TranscriptHandler{transcriptService: transcriptService}
+}
+
+// GetTranscript 以 format 指定的格式串流輸出房間紀錄，exclude_system=true 時略過系統消息
+func (h *TranscriptHandler) GetTranscript(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "無效的房間ID",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "markdown")
+	info, ok := service.TranscriptFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "不支援的格式，請使用 markdown、json、csv 或 text",
+		})
+		return
+	}
+
+	meta, err := h.transcriptService.Meta(uint(roomID))
+	if err != nil {
+		if err.Error() == "房間不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "獲取房間紀錄失敗"})
+		return
+	}
+
+	c.Header("Content-Type", info.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="room-%d-transcript.%s"`, roomID, info.Extension))
+	c.Status(http.StatusOK)
+
+	// 開始輸出後無法再改變狀態碼，只能記錄錯誤
+	err = h.transcriptService.Write(c.Writer, meta, service.TranscriptOptions{
+		Format:        format,
+		IncludeSystem: c.Query("exclude_system") != "true",
+	})
+	if err != nil {
+		log.Printf("transcript export error: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"debate_web/internal/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorHandler 處理用戶設定兩步驟驗證的請求
+type TwoFactorHandler struct {
+	twoFactorService *service.TwoFactorService
+}
+
+// NewTwoFactorHandler 創建新的兩步驟驗證處理器
+func NewTwoFactorHandler(twoFactorService *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactorService: twoFactorService}
+}
+
+// GetStatus 返回目前用戶兩步驟驗證的狀態
+func (h *TwoFactorHandler) GetStatus(c *gin.Context) {
+	status, err := h.twoFactorService.Status(c.GetUint("userID"))
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Enroll 驗證密碼後產生新的密鑰，返回 otpauth 網址和 QR Code
+func (h *TwoFactorHandler) Enroll(c *gin.Context) {
+	var input struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := h.twoFactorService.BeginEnroll(c.GetUint("userID"), input.Password)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm 以驗證碼確認密鑰並啟用兩步驟驗證，返回只顯示這一次的備用碼，其他裝置會被登出
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.twoFactorService.ConfirmEnroll(c.GetUint("userID"), c.GetString("tokenID"), input.Code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "已啟用兩步驟驗證，其他裝置已登出",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable 驗證密碼和驗證碼後停用兩步驟驗證
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	var input struct {
+		Password string `json:"password" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(c.GetUint("userID"), input.Password, input.Code); err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已停用兩步驟驗證"})
+}
+
+// RegenerateRecoveryCodes 以驗證碼確認後產生新的備用碼，舊的備用碼隨即失效
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "資料格式不正確",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(c.GetUint("userID"), input.Code)
+	if err != nil {
+		twoFactorError(c, err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// twoFactorError 根據兩步驟驗證服務的錯誤回應對應的狀態碼
+func twoFactorError(c *gin.Context, err error) {
+	var locked *service.AccountLockedError
+	if errors.As(err, &locked) {
+		accountLocked(c, locked)
+		return
+	}
+
+	switch err.Error() {
+	case "用戶不存在":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "驗證碼錯誤", "密碼不正確", "無效或已過期的登入驗證":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "帳號已被停用", "你的角色必須啟用兩步驟驗證":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "已經啟用兩步驟驗證", "尚未啟用兩步驟驗證", "請先開始設定兩步驟驗證":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "操作失敗"})
+	}
+}
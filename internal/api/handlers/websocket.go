@@ -11,8 +11,9 @@ import (
 
 // WebSocketHandler 處理 WebSocket 連接
 type WebSocketHandler struct {
-	wsService   *service.WebSocketService
-	roomService *service.RoomService
+	wsService     *service.WebSocketService
+	roomService   *service.RoomService
+	replayService *service.ReplayService
 }
 
 // 設定 WebSocket 升級器
@@ -25,17 +26,18 @@ var upgrader = websocket.Upgrader{
 }
 
 // NewWebSocketHandler 創建新的 WebSocket 處理器
-func NewWebSocketHandler(wsService *service.WebSocketService, roomService *service.RoomService) *WebSocketHandler {
+func NewWebSocketHandler(wsService *service.WebSocketService, roomService *service.RoomService, replayService *service.ReplayService) *WebSocketHandler {
 	return &WebSocketHandler{
-		wsService:   wsService,
-		roomService: roomService,
+		wsService:     wsService,
+		roomService:   roomService,
+		replayService: replayService,
 	}
 }
 
 // HandleWebSocket 處理 WebSocket 連接請求
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
-	// 從 URL 獲取房間 ID
-	roomID, err := strconv.ParseUint(c.Query("room_id"), 10, 64)
+	// 從路徑 /rooms/:id/ws 獲取房間 ID，與重播連接一致
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的房間ID"})
 		return
@@ -64,3 +66,33 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	// 開始處理 WebSocket 連接
 	h.wsService.HandleConnection(conn, uint(roomID), userID.(uint), role)
 }
+
+// HandleReplay 處理已結束辯論的重播連接
+func (h *WebSocketHandler) HandleReplay(c *gin.Context) {
+	roomID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "無效的房間ID"})
+		return
+	}
+
+	messages, err := h.replayService.LoadReplay(uint(roomID))
+	if err != nil {
+		switch err.Error() {
+		case "房間不存在":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "辯論尚未結束":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "讀取辯論紀錄失敗"})
+		}
+		return
+	}
+
+	// 升級 HTTP 連接為 WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	h.replayService.HandleReplay(conn, messages)
+}
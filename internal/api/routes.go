@@ -9,11 +9,26 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, services *service.Services) {
+// SetupRoutes 註冊所有路由，authLimit 是套用在註冊和登入上的限流中間件
+func SetupRoutes(r *gin.Engine, services *service.Services, authLimit gin.HandlerFunc) {
 	// 初始化 handlers
-	authHandler := handlers.NewAuthHandler(services.User)
-	roomHandler := handlers.NewRoomHandler(services.Room)
-	wsHandler := handlers.NewWebSocketHandler(services.WebSocket, services.Room)
+	authHandler := handlers.NewAuthHandler(services.User, services.Token, services.Password, services.Email, services.TwoFactor)
+	roomHandler := handlers.NewRoomHandler(services.Room, services.Reaction)
+	questionHandler := handlers.NewQuestionHandler(services.Question)
+	argumentHandler := handlers.NewArgumentHandler(services.Argument)
+	transcriptHandler := handlers.NewTranscriptHandler(services.Transcript)
+	reportHandler := handlers.NewReportHandler(services.Report)
+	statsHandler := handlers.NewStatsHandler(services.Stats)
+	searchHandler := handlers.NewSearchHandler(services.Search)
+	reviewHandler := handlers.NewReviewHandler(services.Review)
+	sanctionHandler := handlers.NewSanctionHandler(services.Sanction)
+	adminHandler := handlers.NewAdminHandler(services.Admin)
+	profileHandler := handlers.NewProfileHandler(services.Profile)
+	passwordHandler := handlers.NewPasswordHandler(services.Password)
+	emailHandler := handlers.NewEmailHandler(services.Email)
+	twoFactorHandler := handlers.NewTwoFactorHandler(services.TwoFactor)
+	oidcHandler := handlers.NewOIDCHandler(services.OIDC, authHandler)
+	wsHandler := handlers.NewWebSocketHandler(services.WebSocket, services.Room, services.Replay)
 
 	// API 路由群組
 	api := r.Group("/api")
@@ -27,9 +42,28 @@ func SetupRoutes(r *gin.Engine, services *service.Services) {
 
 	// 公開路由
 	{
+		// 驗證 token 用的公鑰
+		r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 		// 用戶認證相關
-		api.POST("/register", authHandler.Register)
-		api.POST("/login", authHandler.Login)
+		api.POST("/register", authLimit, authHandler.Register)
+		api.POST("/login", authLimit, authHandler.Login)
+		api.POST("/login/2fa", authLimit, authHandler.LoginTwoFactor)            // 以驗證碼或備用碼完成登入
+		api.POST("/login/2fa/setup", authLimit, authHandler.LoginTwoFactorSetup) // 角色必須啟用兩步驟驗證時，在登入途中設定
+		api.GET("/login/oidc", oidcHandler.GetProvider)                          // 是否啟用 OIDC 登入
+		api.POST("/login/oidc", authLimit, oidcHandler.BeginLogin)               // 取得提供者的授權網址
+		api.POST("/login/oidc/callback", authLimit, oidcHandler.Callback)        // 以提供者導回的授權碼完成登入
+		api.POST("/token/refresh", authLimit, authHandler.Refresh)
+
+		// 忘記密碼
+		api.POST("/password/forgot", authLimit, passwordHandler.ForgotPassword) // 寄出重設密碼的連結
+		api.POST("/password/reset", authLimit, passwordHandler.ResetPassword)   // 以連結中的 token 設定新密碼
+
+		// 電子郵件驗證
+		api.POST("/email/verify", authLimit, emailHandler.VerifyEmail) // 以驗證郵件中的 token 完成驗證
+
+		// 頭像圖片，供 <img> 直接載入
+		api.GET("/users/:id/avatar", profileHandler.GetAvatar)
 
 		// 基本的健康檢查
 		api.GET("/health", func(c *gin.Context) {
@@ -41,22 +75,101 @@ func SetupRoutes(r *gin.Engine, services *service.Services) {
 
 	// 需要驗證的路由
 	authorized := api.Group("/")
-	authorized.Use(middleware.AuthMiddleware())
+	authorized.Use(middleware.AuthMiddleware(services.Token))
 	{
+		// 登出
+		authorized.POST("/logout", authHandler.Logout)        // 登出目前的裝置
+		authorized.POST("/logout/all", authHandler.LogoutAll) // 登出所有裝置
+
 		// 辯論室相關
+		canCreateRoom := middleware.RequirePermission(service.PermCreateRoom)
 		rooms := authorized.Group("/rooms")
 		{
 			// 基本操作
-			rooms.GET("", roomHandler.ListRooms)   // 獲取房間列表
-			rooms.POST("", roomHandler.CreateRoom) // 創建房間
-			rooms.GET("/:id", roomHandler.GetRoom) // 獲取房間信息
+			rooms.GET("", roomHandler.ListRooms)                  // 獲取房間列表
+			rooms.POST("", canCreateRoom, roomHandler.CreateRoom) // 創建房間
+			rooms.GET("/:id", roomHandler.GetRoom)                // 獲取房間信息
 
 			// 房間參與
 			rooms.POST("/:id/join", roomHandler.JoinRoom)   // 加入房間
 			rooms.POST("/:id/leave", roomHandler.LeaveRoom) // 離開房間
 
+			// 觀眾問答
+			rooms.GET("/:id/questions", questionHandler.ListQuestions) // 主持人查看問題隊列
+
+			// 消息審核
+			rooms.GET("/:id/review", reviewHandler.ListReviewQueue)            // 主持人查看審核隊列
+			rooms.POST("/:id/review/:message_id", reviewHandler.ReviewMessage) // 審核消息 (approve|delete|warn|mute)
+
+			// 參與者管理
+			rooms.GET("/:id/sanctions", sanctionHandler.ListSanctions) // 處分紀錄
+			rooms.POST("/:id/mute", sanctionHandler.Mute)              // 禁言
+			rooms.DELETE("/:id/mute/:user_id", sanctionHandler.Unmute) // 解除禁言
+			rooms.POST("/:id/kick", sanctionHandler.Kick)              // 踢出房間
+			rooms.POST("/:id/ban", sanctionHandler.Ban)                // 封鎖
+			rooms.DELETE("/:id/ban/:user_id", sanctionHandler.Unban)   // 解除封鎖
+
+			// 辯論紀錄
+			rooms.GET("/:id/arguments", argumentHandler.GetArgumentTree)   // 論點樹
+			rooms.GET("/:id/argument-map", argumentHandler.GetArgumentMap) // 論點圖 (format=dot|json)
+			rooms.GET("/:id/transcript", transcriptHandler.GetTranscript)  // 完整紀錄 (format=markdown|json|csv|text)
+			rooms.GET("/:id/report.pdf", reportHandler.GetReportPDF)       // PDF 報告
+			rooms.GET("/:id/stats", statsHandler.GetRoomStats)             // 發言統計
+
 			// WebSocket 連接（移到房間路由下）
-			rooms.GET("/:id/ws", wsHandler.HandleWebSocket) // WebSocket 連接點
+			rooms.GET("/:id/ws", wsHandler.HandleWebSocket)  // WebSocket 連接點
+			rooms.GET("/:id/replay", wsHandler.HandleReplay) // 已結束辯論的重播
+		}
+
+		// 消息相關
+		messages := authorized.Group("/messages")
+		{
+			messages.GET("/search", searchHandler.SearchMessages)     // 全文搜尋
+			messages.POST("/:id/report", reviewHandler.ReportMessage) // 檢舉消息
+		}
+
+		// 用戶相關
+		users := authorized.Group("/users")
+		{
+			users.GET("/me", profileHandler.GetMyProfile)                                             // 自己的個人資料
+			users.PATCH("/me", profileHandler.UpdateMyProfile)                                        // 修改個人資料
+			users.POST("/me/avatar", profileHandler.UploadAvatar)                                     // 上傳頭像 (multipart, avatar)
+			users.DELETE("/me/avatar", profileHandler.DeleteAvatar)                                   // 移除頭像
+			users.PUT("/me/password", passwordHandler.ChangePassword)                                 // 修改密碼，其他裝置會被登出
+			users.PUT("/me/email", emailHandler.SetEmail)                                             // 設定電子郵件並寄出驗證郵件
+			users.POST("/me/email/verification", authLimit, emailHandler.ResendVerification)          // 重新寄出驗證郵件
+			users.GET("/me/2fa", twoFactorHandler.GetStatus)                                          // 兩步驟驗證的狀態
+			users.POST("/me/2fa", twoFactorHandler.Enroll)                                            // 產生密鑰和 QR Code
+			users.POST("/me/2fa/confirm", authLimit, twoFactorHandler.Confirm)                        // 以驗證碼確認並啟用
+			users.DELETE("/me/2fa", authLimit, twoFactorHandler.Disable)                              // 停用兩步驟驗證
+			users.POST("/me/2fa/recovery-codes", authLimit, twoFactorHandler.RegenerateRecoveryCodes) // 產生新的備用碼
+			users.GET("/me/identities", oidcHandler.ListIdentities)                                   // 已連結的提供者帳號
+			users.POST("/me/identities", oidcHandler.BeginLink)                                       // 發起連結提供者帳號
+			users.POST("/me/identities/callback", oidcHandler.CompleteLink)                           // 以提供者導回的授權碼完成連結
+			users.GET("/:id", profileHandler.GetProfile)                                              // 公開的個人資料
+			users.GET("/:id/stats", statsHandler.GetUserStats)                                        // 用戶生涯統計
+		}
+
+		// 管理員
+		admin := authorized.Group("/admin")
+		{
+			adminUsers := admin.Group("/users", middleware.RequirePermission(service.PermManageUsers))
+			{
+				adminUsers.GET("", adminHandler.ListUsers)                      // 查詢用戶 (q, role, disabled)
+				adminUsers.POST("/:id/disable", adminHandler.DisableUser)       // 停用帳號
+				adminUsers.POST("/:id/enable", adminHandler.EnableUser)         // 重新啟用帳號
+				adminUsers.POST("/:id/password", adminHandler.ResetPassword)    // 重設密碼
+				adminUsers.PUT("/:id/role", adminHandler.ChangeRole)            // 變更角色
+				adminUsers.DELETE("/:id/2fa", adminHandler.ResetTwoFactor)      // 為遺失驗證器的用戶停用兩步驟驗證
+				adminUsers.POST("/:id/disconnect", adminHandler.DisconnectUser) // 斷開 WebSocket 連接
+			}
+
+			adminRooms := admin.Group("/rooms", middleware.RequirePermission(service.PermManageRooms))
+			{
+				adminRooms.POST("/:id/finish", adminHandler.FinishRoom)         // 強制結束辯論
+				adminRooms.DELETE("/:id", adminHandler.DeleteRoom)              // 刪除房間
+				adminRooms.POST("/:id/disconnect", adminHandler.DisconnectRoom) // 斷開 WebSocket 連接
+			}
 		}
 	}
 }
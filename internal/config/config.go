@@ -1,16 +1,33 @@
 package config
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Server ServerConfig
-	DB     DBConfig
+	Server     ServerConfig
+	DB         DBConfig
+	Auth       AuthConfig
+	JWT        JWTConfig `mapstructure:"jwt"`
+	Password   PasswordConfig
+	TwoFactor  TwoFactorConfig `mapstructure:"two_factor"`
+	OIDC       OIDCConfig      `mapstructure:"oidc"`
+	Mail       MailConfig
+	Email      EmailConfig
+	Blob       BlobConfig
+	Avatar     AvatarConfig
+	Moderation ModerationConfig
+	RateLimit  RateLimitConfig `mapstructure:"rate_limit"`
 }
 
 type ServerConfig struct {
 	Address string
+	// TrustedProxies 是可信任的反向代理 IP 或 CIDR，只有來自這些地址的 X-Forwarded-For 才會被採用
+	// 預設為空，直接使用連線的來源地址作為客戶端 IP
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DBConfig struct {
@@ -21,11 +38,180 @@ type DBConfig struct {
 	Port     int
 }
 
+// AuthConfig 是登入憑證的設定
+type AuthConfig struct {
+	AccessTTL  time.Duration `mapstructure:"access_ttl"`  // access token 的有效期，應盡量短
+	RefreshTTL time.Duration `mapstructure:"refresh_ttl"` // refresh token 的有效期，每次換發時重新計算
+	Admins     []string      // 啟動時設為管理員的用戶名，用於建立第一個管理員
+}
+
+// JWTConfig 是簽發和驗證 JWT 的金鑰設定
+// 輪替金鑰時先加入新金鑰並將 SigningKey 指向它，舊金鑰保留到以它簽發的 token 全部到期後再移除
+type JWTConfig struct {
+	Issuer     string
+	SigningKey string `mapstructure:"signing_key"` // 簽發新 token 使用的金鑰 ID
+	Keys       []JWTKeyConfig
+}
+
+// JWTKeyConfig 是單一金鑰，Algorithm 可以是 HS256、RS256 或 EdDSA
+type JWTKeyConfig struct {
+	ID         string
+	Algorithm  string
+	Secret     string // HS256 的密鑰，至少 32 位元組
+	SecretEnv  string `mapstructure:"secret_env"`  // 設定時優先從此環境變數讀取 HS256 的密鑰
+	PrivateKey string `mapstructure:"private_key"` // RS256 或 EdDSA 私鑰的 PEM 檔案路徑，只用於驗證的金鑰可以省略
+	PublicKey  string `mapstructure:"public_key"`  // RS256 或 EdDSA 公鑰的 PEM 檔案路徑，省略時由私鑰推導
+}
+
+// PasswordConfig 是密碼規則和重設密碼的設定，規則只在設定新密碼時檢查
+type PasswordConfig struct {
+	MinLength      int           `mapstructure:"min_length"`
+	MaxLength      int           `mapstructure:"max_length"` // 以位元組計算，bcrypt 最多使用前 72 個位元組
+	RequireLetter  bool          `mapstructure:"require_letter"`
+	RequireDigit   bool          `mapstructure:"require_digit"`
+	RequireSymbol  bool          `mapstructure:"require_symbol"`
+	RejectUsername bool          `mapstructure:"reject_username"` // 密碼不能包含用戶名
+	ResetTTL       time.Duration `mapstructure:"reset_ttl"`       // 重設密碼連結的有效期
+	ResetURL       string        `mapstructure:"reset_url"`       // 重設密碼頁面的網址，token 會附加在 token 參數中
+}
+
+// TwoFactorConfig 是兩步驟驗證的設定
+type TwoFactorConfig struct {
+	Issuer        string        // 顯示在驗證器應用程式中的服務名稱
+	RequiredRoles []string      `mapstructure:"required_roles"` // 必須啟用兩步驟驗證的角色，尚未啟用的用戶登入時需先完成設定
+	ChallengeTTL  time.Duration `mapstructure:"challenge_ttl"`  // 登入時輸入驗證碼的時限
+	Digits        int           // 驗證碼位數，6 或 8，預設為 6；變更後已啟用的用戶需要重新設定驗證器
+}
+
+// OIDCConfig 是以外部 OpenID Connect 提供者（例如學校的帳號系統）登入的設定
+type OIDCConfig struct {
+	Enabled           bool
+	Name              string        // 登入按鈕上顯示的提供者名稱
+	Issuer            string        // 提供者的 issuer，各端點從 {issuer}/.well-known/openid-configuration 取得
+	ClientID          string        `mapstructure:"client_id"`
+	ClientSecret      string        `mapstructure:"client_secret"` // 可以用 OIDC_CLIENT_SECRET 環境變數設定，public client 留空
+	RedirectURL       string        `mapstructure:"redirect_url"`  // 前端接收提供者導回的頁面，需在提供者登記
+	Scopes            []string      // 必須包含 openid
+	StateTTL          time.Duration `mapstructure:"state_ttl"`           // 在提供者完成登入的時限
+	AutoProvision     bool          `mapstructure:"auto_provision"`      // 尚未連結的提供者帳號登入時自動建立用戶
+	LinkVerifiedEmail bool          `mapstructure:"link_verified_email"` // 提供者驗證過的電子郵件與本地已驗證的電子郵件相同時自動連結，只應用於可信任的提供者
+	UsernameClaim     string        `mapstructure:"username_claim"`      // 自動建立用戶時作為用戶名的聲明
+}
+
+// MailConfig 是寄送郵件的設定
+type MailConfig struct {
+	Driver string // log 只寫入日誌，file 將郵件寫入 Dir 中的 .eml 檔案，smtp 透過 SMTP 伺服器寄送
+	From   string
+	Dir    string
+	SMTP   SMTPConfig `mapstructure:"smtp"`
+}
+
+// SMTPConfig 是 SMTP 伺服器的設定，密碼可以用 MAIL_SMTP_PASSWORD 環境變數設定
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Security string // starttls、tls 或 none
+}
+
+// EmailConfig 是電子郵件驗證的設定
+type EmailConfig struct {
+	RequireVerified bool          `mapstructure:"require_verified"` // 驗證電子郵件後才能加入房間
+	VerifyTTL       time.Duration `mapstructure:"verify_ttl"`       // 驗證連結的有效期
+	VerifyURL       string        `mapstructure:"verify_url"`       // 驗證頁面的網址，token 會附加在 token 參數中
+}
+
+// BlobConfig 是上傳檔案的存放設定
+type BlobConfig struct {
+	Driver string // 目前只支援 local
+	Dir    string // local 存放檔案的目錄
+}
+
+// AvatarConfig 是頭像上傳的限制
+type AvatarConfig struct {
+	MaxBytes     int64 `mapstructure:"max_bytes"`     // 上傳檔案的大小上限
+	MaxDimension int   `mapstructure:"max_dimension"` // 圖片寬高的上限，避免解碼過大的圖片
+	Sizes        []int // 產生的正方形縮圖邊長
+}
+
+// ModerationConfig 是聊天消息內容審核的設定
+type ModerationConfig struct {
+	Words     WordListConfig
+	URLs      URLListConfig  `mapstructure:"urls"`
+	MaxRepeat int            `mapstructure:"max_repeat"` // 同一字元最多連續出現的次數，0 表示不限制
+	MaxLength map[string]int `mapstructure:"max_length"` // 各頻道的字數上限，與賽制的上限取較小者
+}
+
+// WordListConfig 是敏感詞列表，依處理方式分組，中英文皆可，英文不分大小寫
+type WordListConfig struct {
+	Mask   []string // 以星號遮蔽後照常發送
+	Hold   []string // 暫緩發送，等待主持人審核
+	Reject []string // 拒絕發送
+}
+
+// URLListConfig 是連結的網域名單，子網域視同所屬網域
+type URLListConfig struct {
+	Allow []string // 非空時，不在名單中的連結需要審核
+	Deny  []string // 拒絕包含這些網域的連結
+}
+
+// RateLimitConfig 是各類請求的速率限制設定
+type RateLimitConfig struct {
+	WebSocket WebSocketRateLimitConfig
+	HTTP      HTTPRateLimitConfig
+	Lockout   LockoutConfig
+}
+
+// HTTPRateLimitConfig 是登入和註冊端點的固定窗口限制
+type HTTPRateLimitConfig struct {
+	Store    string      // 計數的存放位置：memory 或 postgres，多個實例部署時使用 postgres
+	IP       WindowLimit `mapstructure:"ip"`
+	Username WindowLimit
+}
+
+// WindowLimit 限制每個窗口內的請求數，Limit 為 0 表示不限制
+type WindowLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// LockoutConfig 是登入連續失敗後的帳號鎖定設定，每次鎖定的時長加倍
+type LockoutConfig struct {
+	MaxFailures int           `mapstructure:"max_failures"` // 連續失敗幾次後鎖定，0 表示不鎖定
+	Duration    time.Duration // 第一次鎖定的時長
+	MaxDuration time.Duration `mapstructure:"max_duration"` // 鎖定時長的上限
+}
+
+// WebSocketRateLimitConfig 是 WebSocket 消息的令牌桶限制，辯手和觀眾分別設定
+type WebSocketRateLimitConfig struct {
+	Debater         RoleRateLimit
+	Spectator       RoleRateLimit
+	ViolationWindow time.Duration `mapstructure:"violation_window"` // 計算違規次數的時間窗口
+	DisconnectAfter int           `mapstructure:"disconnect_after"` // 窗口內被丟棄的消息達到此數量時斷開連接，0 表示不斷開
+}
+
+// RoleRateLimit 是單一角色的限制，Connection 限制每個連接，User 限制同一用戶的所有連接合計
+type RoleRateLimit struct {
+	Connection TokenBucketConfig
+	User       TokenBucketConfig
+}
+
+// TokenBucketConfig 是令牌桶的參數，Rate 為 0 表示不限制
+type TokenBucketConfig struct {
+	Rate  float64 // 每秒補充的令牌數
+	Burst int     // 最多累積的令牌數
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./internal/config")
 
+	// 環境變數覆蓋設定檔，例如 DB_HOST 對應 db.host
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
 	}
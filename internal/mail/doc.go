@@ -0,0 +1,5 @@
+// Package mail 負責寄送系統郵件。
+//
+// 服務層只依賴 Mailer 介面，實際的寄送方式由設定決定。
+// 本機開發時可以使用 LogMailer 或 FileMailer，不需要架設郵件伺服器。
+package mail
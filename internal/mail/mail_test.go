@@ -0,0 +1,42 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatRejectsHeaderInjection(t *testing.T) {
+	data := string(Format("noreply@example.com", Message{
+		To:      "alice\r\nBcc: mallory@example.com",
+		Subject: "重設密碼",
+		Body:    "body",
+	}))
+	if strings.Contains(data, "\r\nBcc:") {
+		t.Fatalf("header injection was not removed:\n%s", data)
+	}
+	if !strings.Contains(data, "Subject: =?utf-8?q?") {
+		t.Errorf("subject was not MIME encoded:\n%s", data)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer("noreply@example.com", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(Message{To: "alice", Subject: "hello", Body: "body"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "To: alice\r\n") || !strings.HasSuffix(string(data), "\r\n\r\nbody") {
+		t.Errorf("unexpected mail:\n%s", data)
+	}
+}
@@ -0,0 +1,121 @@
+package mail
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTP 連接的加密方式
+const (
+	SMTPStartTLS = "starttls" // 以明文連接後升級為 TLS，通常使用 587 埠
+	SMTPTLS      = "tls"      // 直接以 TLS 連接，通常使用 465 埠
+	SMTPNone     = "none"     // 不加密，只適用於本機的測試伺服器
+)
+
+// smtpTimeout 是連接和寄送一封郵件的時限
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer 透過 SMTP 伺服器寄送郵件，每封郵件使用一個新的連接
+type SMTPMailer struct {
+	host     string
+	addr     string
+	from     string
+	sender   string // 信封的寄件地址，從 from 中解析
+	username string
+	password string
+	security string
+}
+
+func NewSMTPMailer(host string, port int, username, password, security, from string) (*SMTPMailer, error) {
+	sender, err := netmail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail from address: %v", err)
+	}
+	switch security {
+	case "":
+		security = SMTPStartTLS
+	case SMTPStartTLS, SMTPTLS, SMTPNone:
+	default:
+		return nil, fmt.Errorf("unknown smtp security %q", security)
+	}
+	return &SMTPMailer{
+		host:     host,
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		from:     from,
+		sender:   sender.Address,
+		username: username,
+		password: password,
+		security: security,
+	}, nil
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %v", err)
+	}
+
+	client, err := m.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.security == SMTPStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(m.sender); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(Format(m.from, msg)); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 建立連接並完成 SMTP 問候
+func (m *SMTPMailer) dial() (*smtp.Client, error) {
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	var err error
+	if m.security == SMTPTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", m.addr, &tls.Config{ServerName: m.host})
+	} else {
+		conn, err = dialer.Dial("tcp", m.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,97 @@
+package mail
+
+import (
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer 接受一個連接並記錄收到的信封和內容
+func fakeSMTPServer(t *testing.T) (port int, received chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received = make(chan []string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		text := textproto.NewConn(conn)
+		var lines []string
+
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line)[0]); verb {
+			case "EHLO", "HELO":
+				text.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 send data")
+				body, _ := text.ReadDotLines()
+				lines = append(lines, body...)
+				text.PrintfLine("250 queued")
+			case "QUIT":
+				text.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				text.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+
+	_, portText, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ = strconv.Atoi(portText)
+	return port, received
+}
+
+func TestSMTPMailer(t *testing.T) {
+	port, received := fakeSMTPServer(t)
+	mailer, err := NewSMTPMailer("127.0.0.1", port, "", "", SMTPNone, "Debate Web <noreply@example.com>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := Render("alice@example.com", "verify_email", "en", map[string]interface{}{"Name": "Alice", "Link": "http://localhost/verify?token=abc", "Minutes": 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(msg); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	lines := strings.Join(<-received, "\n")
+	for _, want := range []string{"MAIL FROM:<noreply@example.com>", "RCPT TO:<alice@example.com>", "Subject: Verify your email address", "Hi Alice,", "token=abc"} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("missing %q in:\n%s", want, lines)
+		}
+	}
+}
+
+func TestRenderFallsBackToDefaultLanguage(t *testing.T) {
+	data := map[string]interface{}{"Name": "小明", "Link": "http://localhost", "Minutes": 30}
+	msg, err := Render("a@example.com", "password_reset", "fr", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Subject != "重設密碼" || !strings.HasPrefix(msg.Body, "小明 您好") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if _, err := Render("a@example.com", "missing", "en", data); err == nil {
+		t.Error("expected an error for an unknown template")
+	}
+}
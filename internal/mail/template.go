@@ -0,0 +1,74 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"strings"
+	"text/template"
+)
+
+// DefaultLanguage 是用戶沒有設定語言或範本沒有該語言時使用的語言
+const DefaultLanguage = "zh-TW"
+
+// templateFS 中的範本以 <名稱>.<語言>.tmpl 命名，每個範本定義 subject 和 body
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templates 是名稱 -> 語言 -> 範本
+var templates = mustLoadTemplates()
+
+func mustLoadTemplates() map[string]map[string]*template.Template {
+	files, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]map[string]*template.Template)
+	for _, file := range files {
+		base := strings.TrimSuffix(strings.TrimPrefix(file, "templates/"), ".tmpl")
+		name, language, ok := strings.Cut(base, ".")
+		if !ok {
+			panic(fmt.Sprintf("mail template %s has no language", file))
+		}
+		tmpl := template.Must(template.ParseFS(templateFS, file))
+		if tmpl.Lookup("subject") == nil || tmpl.Lookup("body") == nil {
+			panic(fmt.Sprintf("mail template %s must define subject and body", file))
+		}
+		if loaded[name] == nil {
+			loaded[name] = make(map[string]*template.Template)
+		}
+		loaded[name][language] = tmpl
+	}
+	return loaded
+}
+
+// Render 以指定語言的範本產生郵件，沒有該語言的範本時使用 DefaultLanguage
+func Render(to, name, language string, data interface{}) (Message, error) {
+	byLanguage := templates[name]
+	if byLanguage == nil {
+		return Message{}, fmt.Errorf("unknown mail template %q", name)
+	}
+	tmpl := byLanguage[language]
+	if tmpl == nil {
+		tmpl = byLanguage[DefaultLanguage]
+	}
+	if tmpl == nil {
+		return Message{}, fmt.Errorf("mail template %q has no %s version", name, DefaultLanguage)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    strings.TrimLeft(body.String(), "\n"),
+	}, nil
+}
@@ -1,15 +1,23 @@
 package middleware
 
 import (
-	"debate_web/internal/storage/utils"
+	"debate_web/internal/service"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// AccessTokenVerifier 驗證 access token，並檢查其是否已在到期前被撤銷或其用戶已被停用
+type AccessTokenVerifier interface {
+	ParseAccessToken(token string) (*service.TokenClaims, error)
+	IsRevoked(tokenID string, userID uint) (bool, error)
+}
+
 // AuthMiddleware 是一個 Gin 中間件，用於驗證請求的 JWT token
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(tokens AccessTokenVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 從請求頭中獲取 Authorization 字段
 		authHeader := c.GetHeader("Authorization")
@@ -28,16 +36,33 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		// 解析 JWT token
-		claims, err := utils.ParseToken(parts[1])
-		if err != nil {
+		// 沒有 ID 的 token 無法撤銷，一律拒絕
+		claims, err := tokens.ParseAccessToken(parts[1])
+		if err != nil || claims.Id == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		// 檢查 token 是否已被撤銷
+		revoked, err := tokens.IsRevoked(claims.Id, claims.UserID)
+		if err != nil {
+			log.Printf("token revocation check error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// 將用戶信息設置到上下文中
 		c.Set("userID", claims.UserID)
 		c.Set("userRole", claims.Role)
+		c.Set("tokenID", claims.Id)
+		c.Set("tokenExpiresAt", time.Unix(claims.ExpiresAt, 0))
 		c.Next() // 繼續處理請求
 	}
 }
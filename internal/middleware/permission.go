@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePermission 只允許角色擁有指定權限的用戶通過，必須放在 AuthMiddleware 之後
+func RequirePermission(perm service.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.HasPermission(models.UserRole(c.GetString("userRole")), perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "權限不足"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
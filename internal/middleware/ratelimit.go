@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"bytes"
+	"debate_web/internal/config"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRateLimitBody 讀取請求內容以取得用戶名時的上限
+const maxRateLimitBody = 64 << 10
+
+// RateLimitStore 保存固定窗口的請求計數
+// 單一實例使用 MemoryRateLimitStore，多個實例共用計數時使用資料庫實作
+type RateLimitStore interface {
+	// Hit 將 key 在目前窗口的計數加一，返回加一後的計數和窗口結束的時間
+	Hit(key string, window time.Duration) (count int, reset time.Time, err error)
+	// Prune 刪除窗口已結束的計數
+	Prune(now time.Time) error
+}
+
+// RateLimitRule 是一條限流規則，Key 返回空字串時該請求不受此規則限制
+type RateLimitRule struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+	Key    func(c *gin.Context) string
+}
+
+// LimitByIP 依客戶端 IP 限流
+// 客戶端 IP 由 gin 的 ClientIP 決定，引擎必須透過 SetTrustedProxies 設定可信任的代理，
+// 否則任何人都能以偽造的 X-Forwarded-For 繞過限制
+func LimitByIP(limit config.WindowLimit) RateLimitRule {
+	return RateLimitRule{
+		Name:   "ip",
+		Limit:  limit.Limit,
+		Window: limit.Window,
+		Key: func(c *gin.Context) string {
+			return c.ClientIP()
+		},
+	}
+}
+
+// LimitByUsername 依請求內容中的用戶名限流，不分大小寫
+// 同一帳號被多個 IP 嘗試時，仍會受到此規則限制
+func LimitByUsername(limit config.WindowLimit) RateLimitRule {
+	return RateLimitRule{
+		Name:   "username",
+		Limit:  limit.Limit,
+		Window: limit.Window,
+		Key:    usernameFromBody,
+	}
+}
+
+// usernameFromBody 從 JSON 請求內容中讀取用戶名，並還原請求內容讓後續的 handler 可以再次讀取
+func usernameFromBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRateLimitBody))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var input struct {
+		Username string `json:"username"`
+	}
+	if json.Unmarshal(body, &input) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(input.Username))
+}
+
+// RateLimit 是依規則限制請求速率的 Gin 中間件
+// 每條規則各自計數，任一規則超過限制即返回 429；回應帶有剩餘次數最少的規則的 RateLimit 標頭
+// 計數保存失敗時放行請求，避免資料庫故障導致所有人無法登入
+func RateLimit(store RateLimitStore, rules ...RateLimitRule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now()
+		var (
+			tightest  *RateLimitRule
+			remaining = math.MaxInt
+			reset     time.Time
+			exceeded  bool
+		)
+
+		for i := range rules {
+			rule := &rules[i]
+			if rule.Limit <= 0 || rule.Window <= 0 {
+				continue
+			}
+			key := rule.Key(c)
+			if key == "" {
+				continue
+			}
+
+			count, ruleReset, err := store.Hit(rule.Name+":"+c.FullPath()+":"+key, rule.Window)
+			if err != nil {
+				log.Printf("rate limit store error: %v", err)
+				continue
+			}
+
+			left := max(rule.Limit-count, 0)
+			over := count > rule.Limit
+			// 已超過限制時以最晚解除的規則為準，否則以剩餘次數最少的規則為準
+			if (over && (!exceeded || ruleReset.After(reset))) || (!over && !exceeded && left < remaining) {
+				tightest, remaining, reset = rule, left, ruleReset
+			}
+			exceeded = exceeded || over
+		}
+
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		resetSeconds := strconv.Itoa(int(math.Ceil(reset.Sub(now).Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Header("RateLimit-Reset", resetSeconds)
+
+		if exceeded {
+			c.Header("Retry-After", resetSeconds)
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "請求過於頻繁，請稍後再試"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// PruneRateLimits 定期清除已結束的計數窗口
+func PruneRateLimits(store RateLimitStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := store.Prune(now); err != nil {
+			log.Printf("rate limit prune error: %v", err)
+		}
+	}
+}
+
+// MemoryRateLimitStore 將計數保存在記憶體中，只適用於單一實例部署
+type MemoryRateLimitStore struct {
+	counters map[string]*memoryCounter
+	mu       sync.Mutex
+}
+
+type memoryCounter struct {
+	count int
+	reset time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{counters: make(map[string]*memoryCounter)}
+}
+
+func (s *MemoryRateLimitStore) Hit(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	counter := s.counters[key]
+	if counter == nil || !now.Before(counter.reset) {
+		counter = &memoryCounter{reset: now.Add(window)}
+		s.counters[key] = counter
+	}
+	counter.count++
+	return counter.count, counter.reset, nil
+}
+
+func (s *MemoryRateLimitStore) Prune(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, counter := range s.counters {
+		if !now.Before(counter.reset) {
+			delete(s.counters, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"debate_web/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedRouter(rules ...RateLimitRule) *gin.Engine {
+	return newRateLimitedRouterBehind(nil, rules...)
+}
+
+// newRateLimitedRouterBehind 建立只信任指定代理的路由，與 main 中的設定相同
+func newRateLimitedRouterBehind(trustedProxies []string, rules ...RateLimitRule) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		panic(err)
+	}
+	r.POST("/login", RateLimit(NewMemoryRateLimitStore(), rules...), func(c *gin.Context) {
+		var input struct {
+			Username string `json:"username"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.String(http.StatusOK, input.Username)
+	})
+	return r
+}
+
+func login(r *gin.Engine, ip, username string) *httptest.ResponseRecorder {
+	return loginForwarded(r, ip, "", username)
+}
+
+// loginForwarded 從 ip 發出登入請求，forwardedFor 不為空時帶上 X-Forwarded-For
+func loginForwarded(r *gin.Engine, ip, forwardedFor, username string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"`+username+`"}`))
+	req.RemoteAddr = ip + ":1234"
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	r := newRateLimitedRouter(LimitByIP(config.WindowLimit{Limit: 2, Window: time.Minute}))
+
+	for i, remaining := range []string{"1", "0"} {
+		w := login(r, "10.0.0.1", "alice")
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != remaining {
+			t.Errorf("request %d: RateLimit-Remaining = %q, want %q", i+1, got, remaining)
+		}
+	}
+
+	w := login(r, "10.0.0.1", "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("RateLimit-Limit") != "2" {
+		t.Errorf("headers = %v, want Retry-After and RateLimit-Limit 2", w.Header())
+	}
+
+	if w := login(r, "10.0.0.2", "alice"); w.Code != http.StatusOK {
+		t.Errorf("other IP: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitByIPIgnoresSpoofedForwardedFor(t *testing.T) {
+	r := newRateLimitedRouter(LimitByIP(config.WindowLimit{Limit: 1, Window: time.Minute}))
+
+	if w := loginForwarded(r, "10.0.0.1", "192.0.2.1", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want 200", w.Code)
+	}
+	// 沒有設定可信任的代理時，更換 X-Forwarded-For 不會重置計數
+	if w := loginForwarded(r, "10.0.0.1", "192.0.2.2", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed X-Forwarded-For: status = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimitByIPBehindTrustedProxy(t *testing.T) {
+	r := newRateLimitedRouterBehind([]string{"10.0.0.0/8"}, LimitByIP(config.WindowLimit{Limit: 1, Window: time.Minute}))
+
+	if w := loginForwarded(r, "10.0.0.1", "192.0.2.1", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("first client: status = %d, want 200", w.Code)
+	}
+	if w := loginForwarded(r, "10.0.0.1", "192.0.2.1", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("same client through proxy: status = %d, want 429", w.Code)
+	}
+	if w := loginForwarded(r, "10.0.0.1", "192.0.2.2", "alice"); w.Code != http.StatusOK {
+		t.Errorf("other client through proxy: status = %d, want 200", w.Code)
+	}
+	// 不可信任的來源仍以連線地址計算
+	if w := loginForwarded(r, "203.0.113.1", "192.0.2.3", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("untrusted source: status = %d, want 200", w.Code)
+	}
+	if w := loginForwarded(r, "203.0.113.1", "192.0.2.4", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("spoofed header from untrusted source: status = %d, want 429", w.Code)
+	}
+}
+
+func TestRateLimitByUsernameKeepsBody(t *testing.T) {
+	r := newRateLimitedRouter(LimitByUsername(config.WindowLimit{Limit: 1, Window: time.Minute}))
+
+	w := login(r, "10.0.0.1", "Alice")
+	if w.Code != http.StatusOK || w.Body.String() != "Alice" {
+		t.Fatalf("status = %d, body = %q, want 200 Alice", w.Code, w.Body.String())
+	}
+	if w := login(r, "10.0.0.2", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("same username from another IP: status = %d, want 429", w.Code)
+	}
+	if w := login(r, "10.0.0.2", "bob"); w.Code != http.StatusOK {
+		t.Errorf("other username: status = %d, want 200", w.Code)
+	}
+}
+
+func TestMemoryRateLimitStoreWindow(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	store.Hit("k", time.Millisecond)
+	store.Hit("k", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	if count, _, _ := store.Hit("k", time.Minute); count != 1 {
+		t.Errorf("count after window = %d, want 1", count)
+	}
+	store.Prune(time.Now().Add(2 * time.Minute))
+	if len(store.counters) != 0 {
+		t.Errorf("counters after prune = %d, want 0", len(store.counters))
+	}
+}
@@ -0,0 +1,6 @@
+// Package oidc 實現 OpenID Connect 授權碼流程的客戶端。
+//
+// Provider 從 issuer 的 discovery 文件取得各端點，以 PKCE (S256) 發起授權，
+// 用授權碼換取 ID token，並以提供者公布的 JWKS 驗證其簽名和聲明。
+// 只依賴標準庫和 JWT 庫，oidctest 子包提供測試用的本機提供者。
+package oidc
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"context"
+	"debate_web/internal/oidc/oidctest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRedirect = "http://localhost/oidc/callback"
+
+func newTestProvider(t *testing.T, secret string) (*Provider, *oidctest.Server) {
+	t.Helper()
+	server := oidctest.NewServer("debate", secret, testRedirect)
+	t.Cleanup(server.Close)
+	return NewProvider(Config{
+		Issuer:       server.Issuer,
+		ClientID:     "debate",
+		ClientSecret: secret,
+		RedirectURL:  testRedirect,
+	}), server
+}
+
+// login 走完一次授權流程，返回授權碼
+func login(t *testing.T, p *Provider, server *oidctest.Server, verifier, nonce string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q, want state-1", state)
+	}
+	return code
+}
+
+func TestExchange(t *testing.T) {
+	for _, secret := range []string{"s3cret:+/", ""} {
+		p, server := newTestProvider(t, secret)
+		code := login(t, p, server, "verifier-1", "nonce-1")
+
+		claims, err := p.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+		if err != nil {
+			t.Fatalf("secret %q: %v", secret, err)
+		}
+		if claims.Issuer != server.Issuer || claims.Subject != "user-1" || claims.Email != "user@example.edu" || !claims.EmailVerified || claims.PreferredUsername != "test.user" {
+			t.Errorf("claims = %+v", claims)
+		}
+
+		// 授權碼只能兌換一次
+		if _, err := p.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err == nil {
+			t.Error("authorization code was accepted twice")
+		}
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	p, server := newTestProvider(t, "secret")
+
+	code := login(t, p, server, "verifier-1", "nonce-1")
+	if _, err := p.Exchange(context.Background(), code, "other-verifier", "nonce-1"); err == nil {
+		t.Error("wrong PKCE verifier was accepted")
+	}
+
+	code = login(t, p, server, "verifier-1", "nonce-1")
+	if _, err := p.Exchange(context.Background(), code, "verifier-1", "other-nonce"); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Errorf("wrong nonce: err = %v", err)
+	}
+
+	server.TokenTTL = -5 * time.Minute
+	code = login(t, p, server, "verifier-1", "nonce-1")
+	if _, err := p.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expired id_token: err = %v", err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	p, server := newTestProvider(t, "secret")
+	code := login(t, p, server, "v", "n")
+	if _, err := p.Exchange(context.Background(), code, "v", "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 剛取得 JWKS 時不會因為未知的 kid 重新取得
+	server.RotateKey()
+	code = login(t, p, server, "v", "n")
+	if _, err := p.Exchange(context.Background(), code, "v", "n"); err == nil {
+		t.Fatal("token signed with an unknown key was accepted")
+	}
+
+	p.keysFetched = time.Now().Add(-jwksRefreshInterval)
+	code = login(t, p, server, "v", "n")
+	if _, err := p.Exchange(context.Background(), code, "v", "n"); err != nil {
+		t.Errorf("after refreshing keys: %v", err)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	server := oidctest.NewServer("debate", "", testRedirect)
+	defer server.Close()
+	p := NewProvider(Config{Issuer: server.Issuer + "/", ClientID: "debate", RedirectURL: testRedirect})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil || !strings.Contains(err.Error(), "issuer") {
+		t.Errorf("err = %v, want issuer mismatch", err)
+	}
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// RFC 7636 附錄 B 的範例
+	if got := CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"); got != "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM" {
+		t.Errorf("CodeChallenge = %s", got)
+	}
+}
@@ -0,0 +1,6 @@
+// Package pdf 提供產生簡單 PDF 文件的功能。
+//
+// 這個包以純 Go 實現了 PDF 的基本結構，不依賴外部程式或字型檔。
+// 文字使用 PDF 閱讀器內建的繁體中文 CID 字型顯示，支援中英文混排、
+// 自動換行和分頁，適合用來產生辯論報告這類以文字為主的文件。
+package pdf
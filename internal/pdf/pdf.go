@@ -0,0 +1,251 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// A4 頁面尺寸和版面邊界，單位為點 (1/72 英吋)
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+	Margin     = 56.0
+)
+
+// lineSpacing 行高相對於字級的倍數
+const lineSpacing = 1.5
+
+// 使用 PDF 閱讀器內建的 Adobe-CNS1 明體，不需要嵌入字型檔
+const (
+	fontName     = "MSung-Light"
+	fontEncoding = "UniCNS-UCS2-H"
+)
+
+// Style 描述一段文字的外觀
+type Style struct {
+	Size   float64 // 字級，單位為點
+	Indent float64 // 左側縮排
+	Gray   float64 // 灰階，0 為黑色，1 為白色
+	Center bool    // 是否置中
+}
+
+// Document 是以文字為主的 PDF 文件，內容由上而下排列並自動分頁
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // 目前位置到頁面底部的距離
+}
+
+// New 創建一份空白文件，title 會寫入文件資訊並顯示在每頁頁尾
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.NewPage()
+	return d
+}
+
+// NewPage 開始新的一頁
+func (d *Document) NewPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = PageHeight - Margin
+}
+
+// Space 在目前位置留下指定高度的空白，空間不足時換頁
+func (d *Document) Space(height float64) {
+	d.y -= height
+	if d.y < Margin {
+		d.NewPage()
+	}
+}
+
+// Rule 在目前位置畫一條橫線
+func (d *Document) Rule() {
+	d.ensure(12)
+	d.y -= 6
+	fmt.Fprintf(d.page, "0.6 G 0.5 w %.2f %.2f m %.2f %.2f l S 0 G\n", Margin, d.y, PageWidth-Margin, d.y)
+	d.y -= 6
+}
+
+// Write 以指定樣式輸出一段文字，超過版面寬度時自動換行，換行符號會開始新的一行
+func (d *Document) Write(text string, style Style) {
+	if style.Size <= 0 {
+		style.Size = 11
+	}
+	width := PageWidth - 2*Margin - style.Indent
+	lineHeight := style.Size * lineSpacing
+
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, line := range wrap(paragraph, style.Size, width) {
+			d.ensure(lineHeight)
+			d.y -= lineHeight
+
+			x := Margin + style.Indent
+			if style.Center {
+				x = (PageWidth - textWidth(line, style.Size)) / 2
+			}
+			d.text(x, d.y+(lineHeight-style.Size)/2, line, style.Size, style.Gray)
+		}
+	}
+}
+
+// ensure 確保目前頁面還有指定的高度，否則換頁
+func (d *Document) ensure(height float64) {
+	if d.y-height < Margin {
+		d.NewPage()
+	}
+}
+
+// text 在指定位置輸出單行文字
+func (d *Document) text(x, y float64, line string, size, gray float64) {
+	fmt.Fprintf(d.page, "BT %.2f g /F1 %.2f Tf %.2f %.2f Td <%s> Tj ET\n", gray, size, x, y, encodeText(line))
+}
+
+// WriteTo 將文件寫入 w，並在每頁頁尾加上標題和頁碼
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &pdfWriter{}
+	out.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	// 物件編號：1 目錄、2 頁面樹、3-5 字型、6 文件資訊，之後每頁兩個物件
+	const firstPage = 7
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	out.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	out.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	out.object(3, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /%s /DescendantFonts [4 0 R] >>",
+		fontName, fontEncoding))
+	// ASCII 字元在 Adobe-CNS1 中對應 CID 1-95，寬度為半形
+	out.object(4, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /%s "+
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (CNS1) /Supplement 0 >> "+
+		"/FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>", fontName))
+	out.object(5, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 6 /FontBBox [-160 -249 1015 888] "+
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>", fontName))
+	out.object(6, fmt.Sprintf("<< /Title <FEFF%s> /Producer (debate_web) >>", encodeText(d.title)))
+
+	for i, page := range d.pages {
+		pageNum := firstPage + 2*i
+		out.object(pageNum, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", PageWidth, PageHeight, pageNum+1))
+
+		content := bytes.NewBuffer(page.Bytes())
+		footer := fmt.Sprintf("%s    %d / %d", d.title, i+1, len(d.pages))
+		fmt.Fprintf(content, "BT 0.5 g /F1 8 Tf %.2f %.2f Td <%s> Tj ET\n",
+			(PageWidth-textWidth(footer, 8))/2, Margin/2, encodeText(footer))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(content.Bytes())
+		zw.Close()
+		out.stream(pageNum+1, compressed.Bytes())
+	}
+
+	out.finish(firstPage + 2*len(d.pages))
+	return out.buf.WriteTo(w)
+}
+
+// pdfWriter 記錄每個物件的位置，最後輸出交叉引用表
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets map[int]int
+}
+
+func (p *pdfWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&p.buf, format, args...)
+}
+
+func (p *pdfWriter) object(num int, body string) {
+	p.mark(num)
+	p.printf("%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+func (p *pdfWriter) stream(num int, data []byte) {
+	p.mark(num)
+	p.printf("%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", num, len(data))
+	p.buf.Write(data)
+	p.printf("\nendstream\nendobj\n")
+}
+
+func (p *pdfWriter) mark(num int) {
+	if p.offsets == nil {
+		p.offsets = make(map[int]int)
+	}
+	p.offsets[num] = p.buf.Len()
+}
+
+// finish 輸出交叉引用表和文件結尾，size 為最大物件編號加一
+func (p *pdfWriter) finish(size int) {
+	xref := p.buf.Len()
+	p.printf("xref\n0 %d\n0000000000 65535 f \n", size)
+	for i := 1; i < size; i++ {
+		p.printf("%010d 00000 n \n", p.offsets[i])
+	}
+	p.printf("trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", size, xref)
+}
+
+// runeWidth 返回字元相對於字級的寬度，ASCII 為半形，其餘視為全形
+func runeWidth(r rune) float64 {
+	if r < 0x80 {
+		return 0.5
+	}
+	return 1
+}
+
+// textWidth 計算文字在指定字級下的寬度
+func textWidth(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		width += runeWidth(r) * size
+	}
+	return width
+}
+
+// wrap 將一行文字依照寬度切成多行，英文盡量在空白處斷行
+func wrap(text string, size, width float64) []string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	start, lastSpace := 0, -1
+	lineWidth := 0.0
+	for i := 0; i < len(runes); i++ {
+		w := runeWidth(runes[i]) * size
+		if lineWidth+w > width && i > start {
+			end := i
+			if lastSpace > start && runes[i] < 0x80 && !unicode.IsSpace(runes[i]) {
+				end = lastSpace + 1
+			}
+			lines = append(lines, strings.TrimRight(string(runes[start:end]), " "))
+			start, lastSpace = end, -1
+			lineWidth = textWidth(string(runes[start:i]), size)
+		}
+		if unicode.IsSpace(runes[i]) {
+			lastSpace = i
+		}
+		lineWidth += w
+	}
+	return append(lines, string(runes[start:]))
+}
+
+// encodeText 將文字編碼為 UCS-2 的十六進位字串，字型不支援的字元以問號代替
+func encodeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			r = ' '
+		case r > 0xFFFF || unicode.IsControl(r) || unicode.Is(unicode.Cs, r):
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
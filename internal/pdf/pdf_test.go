@@ -0,0 +1,82 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteToProducesValidXref(t *testing.T) {
+	doc := New("辯論報告")
+	for i := 0; i < 200; i++ {
+		doc.Write(fmt.Sprintf("第 %d 段：我方認為 the motion should pass because of evidence.", i), Style{Size: 12})
+	}
+	doc.Rule()
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.Bytes()
+
+	if len(doc.pages) < 2 {
+		t.Fatalf("expected content to span several pages, got %d", len(doc.pages))
+	}
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+
+	// startxref 指向交叉引用表，表中每個位置都必須是對應物件的開頭
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	lines := strings.Split(string(out[xref:]), "\n")
+	size, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for num := 1; num < size; num++ {
+		offset, _ := strconv.Atoi(strings.Fields(lines[2+num])[0])
+		want := fmt.Sprintf("%d 0 obj", num)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", num, out[offset:offset+len(want)])
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		text  string
+		width float64
+		want  []string
+	}{
+		{"", 100, []string{""}},
+		{"hello world", 100, []string{"hello world"}},
+		{"hello world", 40, []string{"hello", "world"}},
+		{"一二三四五", 30, []string{"一二三", "四五"}},
+		{"中文 mixed", 35, []string{"中文", "mixed"}},
+	}
+
+	for _, tt := range tests {
+		got := wrap(tt.text, 10, tt.width)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrap(%q, %v) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	if got := encodeText("A中"); got != "00414E2D" {
+		t.Errorf("encodeText: got %s", got)
+	}
+	// 超出 BMP 的字元和控制字元以問號代替
+	if got := encodeText("👏\x01"); got != "003F003F" {
+		t.Errorf("encodeText: got %s", got)
+	}
+}
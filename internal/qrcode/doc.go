@@ -0,0 +1,6 @@
+// Package qrcode 提供產生 QR Code 圖片的功能。
+//
+// 這個包以純 Go 實現了 QR Code 的位元組模式編碼，使用 M 級錯誤修正，
+// 自動選擇能容納資料的最小版本和懲罰分數最低的遮罩。
+// 主要用來顯示兩步驟驗證的 otpauth 網址，讓驗證器應用程式掃描。
+package qrcode
@@ -0,0 +1,212 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// 規格附錄中 HELLO WORLD 以 1-M 編碼的資料碼字和錯誤修正碼
+	data := []byte{0x20, 0x5B, 0x0B, 0x78, 0xD1, 0x72, 0xDC, 0x4D, 0x43, 0x40, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := rsRemainder(data, rsDivisor(len(want))); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	tests := []struct {
+		mask int
+		want string
+	}{
+		{0, "101010000010010"},
+		{4, "100010111111001"},
+	}
+	for _, tt := range tests {
+		c := newCode(1)
+		c.drawFormatBits(tt.mask)
+		if got := readFormatBits(c); fmt.Sprintf("%015b", got) != tt.want {
+			t.Errorf("mask %d format bits = %015b, want %s", tt.mask, got, tt.want)
+		}
+	}
+
+	c := newCode(7)
+	c.drawVersion()
+	got := 0
+	for i := 17; i >= 0; i-- {
+		got <<= 1
+		if c.modules[i/3][c.Size-11+i%3] {
+			got |= 1
+		}
+	}
+	if got != 0x07C94 {
+		t.Errorf("version 7 bits = %#x, want 0x07c94", got)
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []string{
+		"",
+		"hello",
+		"otpauth://totp/Debate:alice?secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP&issuer=Debate&algorithm=SHA1&digits=6&period=30",
+		strings.Repeat("辯論", 120),
+	}
+	for _, text := range tests {
+		c, err := Encode([]byte(text))
+		if err != nil {
+			t.Fatalf("encode %d bytes: %v", len(text), err)
+		}
+		if got := decode(t, c); got != text {
+			t.Errorf("version %d decoded %q, want %q", c.Version, got, text)
+		}
+	}
+}
+
+func TestEncodeVersion(t *testing.T) {
+	// 1-M 的位元組模式最多 14 個位元組
+	if c, _ := Encode(bytes.Repeat([]byte("a"), 14)); c.Version != 1 {
+		t.Errorf("14 bytes used version %d, want 1", c.Version)
+	}
+	if c, _ := Encode(bytes.Repeat([]byte("a"), 15)); c.Version != 2 {
+		t.Errorf("15 bytes used version %d, want 2", c.Version)
+	}
+	if _, err := Encode(make([]byte, 2332)); err != ErrTooLong {
+		t.Errorf("encode 2332 bytes error = %v, want ErrTooLong", err)
+	}
+}
+
+func TestWritePNG(t *testing.T) {
+	c, _ := Encode([]byte("hello"))
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf, 4); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	side := (c.Size + quietZone*2) * 4
+	if b := img.Bounds(); b.Dx() != side || b.Dy() != side {
+		t.Fatalf("image is %v, want %dx%d", b, side, side)
+	}
+	// 左上角是空白，接著是定位圖形的深色外框
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("quiet zone is dark")
+	}
+	if r, _, _, _ := img.At(quietZone*4, quietZone*4).RGBA(); r != 0 {
+		t.Error("finder pattern is light")
+	}
+}
+
+// readFormatBits 讀取左上角的格式資訊
+func readFormatBits(c *Code) int {
+	bits := 0
+	set := func(i, x, y int) {
+		if c.modules[y][x] {
+			bits |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		set(i, 8, i)
+	}
+	set(6, 8, 7)
+	set(7, 8, 8)
+	set(8, 7, 8)
+	for i := 9; i < 15; i++ {
+		set(i, 14-i, 8)
+	}
+	return bits
+}
+
+// decode 以與編碼無關的方式讀回資料：解開遮罩、取出碼字、檢查錯誤修正碼並解析位元組模式
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+
+	format := readFormatBits(c) ^ 0x5412
+	if format>>13 != eccFormatBits {
+		t.Fatalf("format bits %015b do not use level M", format^0x5412)
+	}
+	mask := format >> 10 & 7
+
+	ref := newCode(c.Version)
+	ref.drawFunctionPatterns()
+	var raw []byte
+	var cur, n int
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.Size; vert++ {
+			y := vert
+			if (right+1)&2 == 0 {
+				y = c.Size - 1 - vert
+			}
+			for x := right; x >= right-1; x-- {
+				if ref.reserve[y][x] {
+					continue
+				}
+				cur = cur<<1 | boolInt(c.modules[y][x] != maskBit(mask, x, y))
+				if n++; n%8 == 0 {
+					raw = append(raw, byte(cur))
+					cur = 0
+				}
+			}
+		}
+	}
+	raw = raw[:rawDataModules(c.Version)/8]
+
+	// 還原交錯排列的區塊
+	numBlocks, eccLen := eccBlocks[c.Version], eccCodewordsPerBlock[c.Version]
+	numShort := numBlocks - len(raw)%numBlocks
+	shortData := len(raw)/numBlocks - eccLen
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for j := range blocks {
+			if i < shortData || j >= numShort {
+				blocks[j] = append(blocks[j], raw[k])
+				k++
+			}
+		}
+	}
+	var data []byte
+	for j, block := range blocks {
+		ecc := raw[k+j : len(raw) : len(raw)]
+		var want []byte
+		for i := 0; i < eccLen; i++ {
+			want = append(want, ecc[i*numBlocks])
+		}
+		if got := rsRemainder(block, rsDivisor(eccLen)); !bytes.Equal(got, want) {
+			t.Fatalf("block %d error correction does not match", j)
+		}
+		data = append(data, block...)
+	}
+
+	read := func(pos, length int) int {
+		v := 0
+		for i := pos; i < pos+length; i++ {
+			v = v<<1 | int(data[i/8]>>(7-i%8)&1)
+		}
+		return v
+	}
+	if mode := read(0, 4); mode != 0x4 {
+		t.Fatalf("mode = %#x, want byte mode", mode)
+	}
+	length := read(4, countBits(c.Version))
+	var out []byte
+	for i := 0; i < length; i++ {
+		out = append(out, byte(read(4+countBits(c.Version)+i*8, 8)))
+	}
+	return string(out)
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
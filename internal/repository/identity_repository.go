@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdentityRepository interface {
+	CreateState(state *models.OIDCState) error
+	TakeState(hash string) (*models.OIDCState, error)
+	PruneStates(now time.Time) error
+	FindIdentity(issuer, subject string) (*models.UserIdentity, error)
+	ListIdentities(userID uint) ([]models.UserIdentity, error)
+	CreateIdentity(identity *models.UserIdentity) error
+}
+
+type identityRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewIdentityRepository(db *storage.PostgresDB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+func (r *identityRepository) CreateState(state *models.OIDCState) error {
+	return r.db.Create(state).Error
+}
+
+// TakeState 刪除並返回尚未過期的登入狀態，同一個狀態只能取得一次
+func (r *identityRepository) TakeState(hash string) (*models.OIDCState, error) {
+	var states []models.OIDCState
+	result := r.db.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", hash, time.Now()).
+		Delete(&states)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(states) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &states[0], nil
+}
+
+// PruneStates 刪除已過期的登入狀態
+func (r *identityRepository) PruneStates(now time.Time) error {
+	return r.db.Where("expires_at <= ?", now).Delete(&models.OIDCState{}).Error
+}
+
+func (r *identityRepository) FindIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) ListIdentities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *identityRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MessageRepository interface {
+	Create(message *models.Message) error
+	FindByID(id uint) (*models.Message, error)
+	FindByIDs(ids []uint) ([]models.Message, error)
+	FindByRoom(roomID uint) ([]models.Message, error)
+	EachByRoom(roomID uint, batchSize int, fn func([]models.Message) error) error
+	FindHeldByRoom(roomID uint) ([]models.Message, error)
+	FindBefore(roomID, messageID uint, limit int) ([]models.Message, error)
+	UpdateStatus(id uint, status string) error
+	Delete(id uint) error
+	Search(filter MessageSearchFilter) ([]MessageSearchResult, error)
+	IndexMissing(batchSize int) error
+}
+
+// MessageSearchFilter 是全文搜尋的條件，零值的欄位不參與過濾
+type MessageSearchFilter struct {
+	Query  string
+	RoomID uint
+	UserID uint
+	Role   string
+	Tag    string // 房間的主題標籤
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// MessageSearchResult 是一則符合搜尋條件的消息
+type MessageSearchResult struct {
+	MessageID uint
+	RoomID    uint
+	RoomName  string
+	UserID    uint
+	Role      string
+	Channel   string
+	Content   string
+	CreatedAt time.Time
+	Seq       int // 消息在房間內的順序，從 1 開始，與匯出紀錄的編號一致
+	Rank      float64
+}
+
+type messageRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewMessageRepository(db *storage.PostgresDB) MessageRepository {
+	return &messageRepository{db: db}
+}
+
+// Create 寫入消息並建立全文搜尋索引
+func (r *messageRepository) Create(message *models.Message) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		return indexMessage(tx, message.ID, message.Content)
+	})
+}
+
+// indexMessage 更新消息的 tsvector 欄位
+func indexMessage(tx *gorm.DB, id uint, content string) error {
+	return tx.Exec("UPDATE messages SET search_vector = to_tsvector('simple', ?) WHERE id = ?",
+		searchDocument(content), id).Error
+}
+
+func (r *messageRepository) FindByID(id uint) (*models.Message, error) {
+	var message models.Message
+	err := r.db.First(&message, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// FindByIDs 查詢多則消息，包含尚未發布的消息
+func (r *messageRepository) FindByIDs(ids []uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Where("id IN ?", ids).Order("id ASC").Find(&messages).Error
+	return messages, err
+}
+
+// FindByRoom 按發送順序查詢房間內所有已發布的消息
+func (r *messageRepository) FindByRoom(roomID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Where("room_id = ? AND status = ?", roomID, models.MessageStatusPublished).
+		Order("id ASC").Find(&messages).Error
+	return messages, err
+}
+
+// EachByRoom 按發送順序分批讀取房間內已發布的消息，避免一次載入全部消息
+func (r *messageRepository) EachByRoom(roomID uint, batchSize int, fn func([]models.Message) error) error {
+	var batch []models.Message
+	// FindInBatches 本身按主鍵排序
+	return r.db.Where("room_id = ? AND status = ?", roomID, models.MessageStatusPublished).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// FindHeldByRoom 按發送順序查詢房間內等待審核的消息
+func (r *messageRepository) FindHeldByRoom(roomID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Where("room_id = ? AND status = ?", roomID, models.MessageStatusHeld).
+		Order("id ASC").Find(&messages).Error
+	return messages, err
+}
+
+// FindBefore 查詢房間內在指定消息之前的最近幾則已發布消息，按發送順序返回
+func (r *messageRepository) FindBefore(roomID, messageID uint, limit int) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.Where("room_id = ? AND status = ? AND id < ?", roomID, models.MessageStatusPublished, messageID).
+		Order("id DESC").Limit(limit).Find(&messages).Error
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, err
+}
+
+// UpdateStatus 更新消息的審核狀態
+func (r *messageRepository) UpdateStatus(id uint, status string) error {
+	return r.db.Model(&models.Message{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// Delete 軟刪除消息，刪除後不會出現在任何紀錄和搜尋結果中
+func (r *messageRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Message{}, id).Error
+}
+
+// Search 以全文搜尋查詢消息，按相關度排序，已刪除房間中的消息不會出現在結果中
+func (r *messageRepository) Search(filter MessageSearchFilter) ([]MessageSearchResult, error) {
+	query := searchQuery(filter.Query)
+
+	db := r.db.Table("messages").
+		Select(`messages.id AS message_id, messages.room_id, rooms.name AS room_name,
+			messages.user_id, messages.role, messages.channel, messages.content, messages.created_at,
+			(SELECT COUNT(*) FROM messages AS m
+				WHERE m.room_id = messages.room_id AND m.id <= messages.id
+					AND m.status = messages.status AND m.deleted_at IS NULL) AS seq,
+			ts_rank(messages.search_vector, websearch_to_tsquery('simple', ?)) AS rank`, query).
+		Joins("JOIN rooms ON rooms.id = messages.room_id AND rooms.deleted_at IS NULL").
+		Where("messages.deleted_at IS NULL AND messages.status = ?", models.MessageStatusPublished).
+		Where("messages.search_vector @@ websearch_to_tsquery('simple', ?)", query)
+
+	if filter.RoomID != 0 {
+		db = db.Where("messages.room_id = ?", filter.RoomID)
+	}
+	if filter.UserID != 0 {
+		db = db.Where("messages.user_id = ?", filter.UserID)
+	}
+	if filter.Role != "" {
+		db = db.Where("messages.role = ?", filter.Role)
+	}
+	if filter.Tag != "" {
+		tag, err := json.Marshal([]string{filter.Tag})
+		if err != nil {
+			return nil, err
+		}
+		db = db.Where("rooms.tags @> ?::jsonb", string(tag))
+	}
+	if !filter.From.IsZero() {
+		db = db.Where("messages.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		db = db.Where("messages.created_at < ?", filter.To)
+	}
+
+	var results []MessageSearchResult
+	err := db.Order("rank DESC, messages.id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(&results).Error
+	return results, err
+}
+
+// IndexMissing 為尚未建立搜尋索引的消息補建索引，用於升級前已存在的消息
+func (r *messageRepository) IndexMissing(batchSize int) error {
+	var batch []models.Message
+	return r.db.Select("id", "content").
+		Where("search_vector IS NULL").
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			for _, msg := range batch {
+				if err := indexMessage(r.db.DB, msg.ID, msg.Content); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
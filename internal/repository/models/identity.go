@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// UserIdentity 將外部 OpenID Connect 提供者的帳號連結到本地用戶，同一個提供者帳號只能連結一個用戶
+type UserIdentity struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"index"`
+	Issuer    string `gorm:"uniqueIndex:idx_identity_subject"`
+	Subject   string `gorm:"uniqueIndex:idx_identity_subject"`
+	Email     string // 連結時提供者回報的電子郵件，只用於顯示
+}
+
+// OIDCState 是發起 OpenID Connect 登入時保存的狀態，在提供者導回後使用一次即刪除
+// UserID 不為零時表示已登入的用戶要連結帳號，而不是登入
+type OIDCState struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	StateHash string    `gorm:"uniqueIndex"`
+	Verifier  string    // PKCE 的 code verifier
+	Nonce     string    // 必須出現在 ID token 中的 nonce
+	UserID    uint      `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// TableName 避免 gorm 將 OIDC 拆成 o_id_c
+func (OIDCState) TableName() string {
+	return "oidc_states"
+}
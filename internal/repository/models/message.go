@@ -7,9 +7,52 @@ import (
 // Message 代表一個統一的消息結構，同時滿足 WebSocket 和數據庫存儲需求
 type Message struct {
 	gorm.Model
-	Type    string
-	RoomID  uint
-	UserID  uint
-	Content string
-	Role    string // "proponent", "opponent", "system"
+	Type      string
+	RoomID    uint `gorm:"index"`
+	UserID    uint
+	Content   string
+	Role      string       // "proponent", "opponent", "moderator", "spectator", "system"
+	Channel   string       // "floor", "gallery"，空值視為 floor
+	ReplyToID uint         `gorm:"index"` // 回應的消息 ID，0 表示不回應任何消息
+	Kind      ArgumentKind // 論點類型，空值表示一般發言
+	Status    string       `gorm:"index"` // 審核狀態，空值表示已發布
+	// 全文搜尋用的 tsvector，由 MessageRepository 在寫入時維護，不經由模型讀寫
+	SearchVector string      `gorm:"type:tsvector;index:idx_messages_search,type:gin;<-:false;->:false" json:"-"`
+	Data         interface{} `gorm:"-" json:",omitempty"` // 非聊天消息附帶的結構化內容，不寫入資料庫
+}
+
+// MessageTypeChat 是用戶發送的聊天消息的類型，其餘類型都由伺服器產生
+const MessageTypeChat = "chat"
+
+// 房間內的邏輯頻道
+const (
+	ChannelFloor   = "floor"   // 辯論場，所有人可讀，由辯手和系統發言
+	ChannelGallery = "gallery" // 觀眾席聊天，辯手可選擇隱藏
+)
+
+// 消息的審核狀態，只有已發布的消息會出現在紀錄和搜尋結果中
+const (
+	MessageStatusPublished = ""     // 已發布
+	MessageStatusHeld      = "held" // 被審核過濾器暫緩，等待主持人審核
+)
+
+// ArgumentKind 定義辯論場上論點的類型
+type ArgumentKind string
+
+const (
+	ArgumentClaim      ArgumentKind = "claim"      // 主張
+	ArgumentEvidence   ArgumentKind = "evidence"   // 證據
+	ArgumentRebuttal   ArgumentKind = "rebuttal"   // 反駁
+	ArgumentConcession ArgumentKind = "concession" // 讓步
+	ArgumentQuestion   ArgumentKind = "question"   // 質詢
+)
+
+// IsValid 檢查論點類型是否為已定義的類型
+func (k ArgumentKind) IsValid() bool {
+	switch k {
+	case ArgumentClaim, ArgumentEvidence, ArgumentRebuttal, ArgumentConcession, ArgumentQuestion:
+		return true
+	default:
+		return false
+	}
 }
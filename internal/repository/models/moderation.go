@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MessageReport 記錄參與者對消息的檢舉，每人對同一消息只能檢舉一次
+type MessageReport struct {
+	gorm.Model
+	MessageID  uint `gorm:"uniqueIndex:idx_message_report"`
+	ReporterID uint `gorm:"uniqueIndex:idx_message_report"`
+	RoomID     uint `gorm:"index"`
+	Reason     string
+	Status     ReportStatus
+	ResolvedBy uint // 處理檢舉的主持人
+}
+
+// ReportStatus 定義檢舉狀態的類型
+type ReportStatus string
+
+const (
+	ReportStatusOpen     ReportStatus = "open"
+	ReportStatusResolved ReportStatus = "resolved"
+)
+
+// Sanction 記錄主持人對用戶在房間內的處分，同時作為房間的管理紀錄
+type Sanction struct {
+	gorm.Model
+	RoomID      uint `gorm:"index"`
+	UserID      uint `gorm:"index"`
+	Kind        SanctionKind
+	Reason      string
+	ModeratorID uint
+	ExpiresAt   *time.Time // 到期時間，nil 表示永久有效
+	RevokedAt   *time.Time // 被提前解除的時間
+	RevokedBy   uint       // 解除處分的主持人
+}
+
+// SanctionKind 定義處分類型
+type SanctionKind string
+
+const (
+	SanctionMute SanctionKind = "mute" // 禁言，到期後自動解除
+	SanctionKick SanctionKind = "kick" // 踢出房間，只作紀錄，可以重新加入
+	SanctionBan  SanctionKind = "ban"  // 禁止再次加入房間
+)
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Question 表示觀眾提交給辯手的問題
+type Question struct {
+	gorm.Model
+	RoomID     uint `gorm:"index"`
+	UserID     uint
+	Content    string
+	Status     QuestionStatus
+	Upvotes    int
+	TargetSide string // 被提升到辯論場時指定回答的一方 (proponent/opponent)
+	MessageID  uint   // 被提升後在辯論場上對應的消息
+}
+
+// QuestionStatus 定義問題狀態的類型
+type QuestionStatus string
+
+const (
+	QuestionStatusPending   QuestionStatus = "pending"
+	QuestionStatusPromoted  QuestionStatus = "promoted"
+	QuestionStatusDismissed QuestionStatus = "dismissed"
+)
+
+// QuestionVote 記錄觀眾對問題的點讚，每人每題只能一次
+type QuestionVote struct {
+	gorm.Model
+	QuestionID uint `gorm:"uniqueIndex:idx_question_vote"`
+	UserID     uint `gorm:"uniqueIndex:idx_question_vote"`
+}
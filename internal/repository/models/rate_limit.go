@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// RateLimitCounter 是固定窗口限流的計數，供多個實例共用
+type RateLimitCounter struct {
+	Key       string `gorm:"primaryKey"`
+	Count     int
+	WindowEnd time.Time `gorm:"index"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Reaction 表示觀眾對一則已保存消息的表情回應
+type Reaction struct {
+	gorm.Model
+	MessageID uint   `gorm:"uniqueIndex:idx_reaction"`
+	UserID    uint   `gorm:"uniqueIndex:idx_reaction"`
+	Emoji     string `gorm:"uniqueIndex:idx_reaction"`
+	RoomID    uint   `gorm:"index"`
+}
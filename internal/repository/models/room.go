@@ -10,13 +10,16 @@ import (
 type Room struct {
 	gorm.Model
 	Name        string
+	Format      string // 辯論賽制名稱，決定各頻道的規則
 	Status      RoomStatus
+	OwnerID     uint // 建立房間的用戶，擔任房間主持人
 	ProponentID uint
 	OpponentID  uint
 	StartTime   time.Time
 	EndTime     time.Time
 	Messages    []Message
-	Spectators  []uint
+	Spectators  []uint   `gorm:"serializer:json"`
+	Tags        []string `gorm:"serializer:json;type:jsonb"` // 辯題的主題標籤，用於搜尋
 }
 
 // RoomStatus 定義房間狀態的類型
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// RefreshToken 記錄一個已發出的 refresh token，資料庫只保存其雜湊值
+// 同一次登入換發出的 token 屬於同一個 Family，任何一個被重複使用時整個 Family 都會被撤銷
+type RefreshToken struct {
+	ID              uint `gorm:"primarykey"`
+	CreatedAt       time.Time
+	UserID          uint      `gorm:"index"`
+	FamilyID        string    `gorm:"index"`
+	TokenHash       string    `gorm:"uniqueIndex"`
+	ExpiresAt       time.Time `gorm:"index"`
+	AccessID        string    `gorm:"index"` // 與此 refresh token 一起發出的 access token 的 ID
+	AccessExpiresAt time.Time
+	UsedAt          *time.Time // 已換發過新的 token
+	RevokedAt       *time.Time
+}
+
+// RevokedToken 記錄在到期前被撤銷的 access token，到期後即可刪除
+type RevokedToken struct {
+	ID        string    `gorm:"primarykey"`
+	UserID    uint      `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// UserToken 是寄給用戶的一次性 token，例如重設密碼的連結，資料庫只保存其雜湊值
+type UserToken struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint      `gorm:"index"`
+	Purpose   string    `gorm:"index"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	ExpiresAt time.Time `gorm:"index"`
+	UsedAt    *time.Time
+}
+
+// UserToken 的用途
+const (
+	UserTokenPasswordReset = "password_reset"
+	UserTokenVerifyEmail   = "verify_email"
+	UserTokenTwoFactor     = "two_factor" // 密碼驗證通過後、輸入兩步驟驗證碼前的登入 challenge
+)
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecoveryCode 是兩步驟驗證的備用碼，用於遺失驗證器時登入，每個只能使用一次，資料庫只保存其雜湊值
+type RecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint   `gorm:"index"`
+	CodeHash  string `gorm:"index"`
+	UsedAt    *time.Time
+}
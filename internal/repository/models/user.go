@@ -1,12 +1,67 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 // User 表示系統中的用戶
 type User struct {
-	gorm.Model        // 內嵌 gorm.Model，提供 ID、CreatedAt、UpdatedAt 和 DeletedAt 字段
-	Username   string `gorm:"uniqueIndex;not null" json:"username"` // 用戶名，必須唯一
-	Password   string `gorm:"not null" json:"-"`                    // 密碼，json 序列化時會被忽略
+	gorm.Model          // 內嵌 gorm.Model，提供 ID、CreatedAt、UpdatedAt 和 DeletedAt 字段
+	Username   string   `gorm:"uniqueIndex;not null" json:"username"` // 用戶名，必須唯一
+	Password   string   `gorm:"not null" json:"-"`                    // 密碼，json 序列化時會被忽略
+	Role       UserRole `gorm:"not null;default:user" json:"role"`    // 全站角色，決定用戶在所有房間的權限
+
+	FailedLogins int        `gorm:"not null;default:0" json:"-"` // 上次鎖定或成功登入後連續失敗的次數
+	Lockouts     int        `gorm:"not null;default:0" json:"-"` // 上次成功登入後被鎖定的次數，決定下次鎖定的時長
+	LockedUntil  *time.Time `json:"-"`                           // 帳號鎖定的到期時間
+
+	DisabledAt *time.Time `json:"disabled_at,omitempty"` // 帳號被管理員停用的時間，停用期間無法登入
+
+	Email           *string    `gorm:"uniqueIndex" json:"-"` // 小寫的電子郵件地址，未設定時為 NULL
+	EmailVerifiedAt *time.Time `json:"-"`
+
+	// 兩步驟驗證
+	TOTPSecret        string     `json:"-"` // base32 編碼的 TOTP 密鑰，空值表示未啟用
+	TOTPPendingSecret string     `json:"-"` // 設定中尚未以驗證碼確認的密鑰
+	TOTPEnabledAt     *time.Time `json:"-"`
+	TOTPLastStep      int64      `gorm:"not null;default:0" json:"-"` // 最後一次使用的驗證碼的時間步，防止同一個驗證碼被重複使用
+
+	// 個人資料
+	DisplayName    string   `gorm:"size:64" json:"display_name"`
+	Bio            string   `gorm:"size:500" json:"bio"`
+	AvatarKey      string   `json:"-"`                                      // 頭像在 blob store 中的前綴，空值表示沒有頭像
+	Language       string   `gorm:"size:16" json:"language"`                // 偏好的介面語言，例如 zh-TW、en
+	PreferredSides []string `gorm:"serializer:json" json:"preferred_sides"` // 偏好擔任的一方 (proponent/opponent)
+}
+
+// Name 返回用戶對外顯示的名稱，沒有設定顯示名稱時使用用戶名
+func (u *User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
 }
+
+// VerifiedEmail 返回已驗證的電子郵件地址，未設定或未驗證時返回空字串
+func (u *User) VerifiedEmail() string {
+	if u.Email == nil || u.EmailVerifiedAt == nil {
+		return ""
+	}
+	return *u.Email
+}
+
+// TwoFactorEnabled 返回用戶是否已啟用兩步驟驗證
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
+// UserRole 定義全站角色的類型
+type UserRole string
+
+const (
+	UserRoleAdmin     UserRole = "admin"     // 管理員，擁有所有權限
+	UserRoleModerator UserRole = "moderator" // 站務，可以管理所有房間的發言
+	UserRoleUser      UserRole = "user"      // 一般用戶
+)
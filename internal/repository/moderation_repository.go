@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ModerationRepository interface {
+	AddReport(report *models.MessageReport) (bool, error)
+	FindOpenReports(roomID uint) ([]models.MessageReport, error)
+	ResolveReports(messageID, moderatorID uint) error
+	CreateSanction(sanction *models.Sanction) error
+	FindActiveSanctions(kind models.SanctionKind) ([]models.Sanction, error)
+	HasActiveSanction(roomID, userID uint, kind models.SanctionKind) (bool, error)
+	RevokeSanctions(roomID, userID uint, kind models.SanctionKind, moderatorID uint) (bool, error)
+	FindSanctionsByRoom(roomID uint) ([]models.Sanction, error)
+}
+
+type moderationRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewModerationRepository(db *storage.PostgresDB) ModerationRepository {
+	return &moderationRepository{db: db}
+}
+
+// AddReport 新增檢舉，同一用戶重複檢舉同一消息時返回 false
+func (r *moderationRepository) AddReport(report *models.MessageReport) (bool, error) {
+	result := r.db.Where(models.MessageReport{
+		MessageID:  report.MessageID,
+		ReporterID: report.ReporterID,
+	}).Attrs(models.MessageReport{
+		RoomID: report.RoomID,
+		Reason: report.Reason,
+		Status: models.ReportStatusOpen,
+	}).FirstOrCreate(report)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindOpenReports 按時間順序查詢房間內尚未處理的檢舉
+func (r *moderationRepository) FindOpenReports(roomID uint) ([]models.MessageReport, error) {
+	var reports []models.MessageReport
+	err := r.db.Where("room_id = ? AND status = ?", roomID, models.ReportStatusOpen).
+		Order("id ASC").Find(&reports).Error
+	return reports, err
+}
+
+// ResolveReports 將消息的所有未處理檢舉標記為已處理
+func (r *moderationRepository) ResolveReports(messageID, moderatorID uint) error {
+	return r.db.Model(&models.MessageReport{}).
+		Where("message_id = ? AND status = ?", messageID, models.ReportStatusOpen).
+		Updates(map[string]interface{}{"status": models.ReportStatusResolved, "resolved_by": moderatorID}).Error
+}
+
+func (r *moderationRepository) CreateSanction(sanction *models.Sanction) error {
+	return r.db.Create(sanction).Error
+}
+
+// active 篩選尚未到期也未被解除的處分
+func (r *moderationRepository) active(kind models.SanctionKind) *gorm.DB {
+	return r.db.Model(&models.Sanction{}).
+		Where("kind = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", kind, time.Now())
+}
+
+// FindActiveSanctions 查詢所有房間中仍然有效的處分
+func (r *moderationRepository) FindActiveSanctions(kind models.SanctionKind) ([]models.Sanction, error) {
+	var sanctions []models.Sanction
+	err := r.active(kind).Find(&sanctions).Error
+	return sanctions, err
+}
+
+// HasActiveSanction 檢查用戶在房間內是否有仍然有效的處分
+func (r *moderationRepository) HasActiveSanction(roomID, userID uint, kind models.SanctionKind) (bool, error) {
+	var count int64
+	err := r.active(kind).Where("room_id = ? AND user_id = ?", roomID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// RevokeSanctions 提前解除用戶在房間內所有有效的處分，沒有可解除的處分時返回 false
+func (r *moderationRepository) RevokeSanctions(roomID, userID uint, kind models.SanctionKind, moderatorID uint) (bool, error) {
+	result := r.active(kind).
+		Where("room_id = ? AND user_id = ?", roomID, userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_by": moderatorID})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindSanctionsByRoom 按時間順序查詢房間的所有處分紀錄
+func (r *moderationRepository) FindSanctionsByRoom(roomID uint) ([]models.Sanction, error) {
+	var sanctions []models.Sanction
+	err := r.db.Where("room_id = ?", roomID).Order("id ASC").Find(&sanctions).Error
+	return sanctions, err
+}
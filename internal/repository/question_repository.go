@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+type QuestionRepository interface {
+	Create(question *models.Question) error
+	FindByID(id uint) (*models.Question, error)
+	Update(question *models.Question) error
+	FindPendingByRoom(roomID uint) ([]models.Question, error)
+	AddVote(questionID, userID uint) (bool, error)
+}
+
+type questionRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewQuestionRepository(db *storage.PostgresDB) QuestionRepository {
+	return &questionRepository{db: db}
+}
+
+func (r *questionRepository) Create(question *models.Question) error {
+	return r.db.Create(question).Error
+}
+
+func (r *questionRepository) FindByID(id uint) (*models.Question, error) {
+	var question models.Question
+	err := r.db.First(&question, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+func (r *questionRepository) Update(question *models.Question) error {
+	return r.db.Save(question).Error
+}
+
+// FindPendingByRoom 查詢房間內待處理的問題，按點讚數和提交時間排序
+func (r *questionRepository) FindPendingByRoom(roomID uint) ([]models.Question, error) {
+	var questions []models.Question
+	err := r.db.Where("room_id = ? AND status = ?", roomID, models.QuestionStatusPending).
+		Order("upvotes DESC, id ASC").
+		Find(&questions).Error
+	return questions, err
+}
+
+// AddVote 記錄一次點讚並更新問題的點讚數，重複點讚時返回 false
+func (r *questionRepository) AddVote(questionID, userID uint) (bool, error) {
+	added := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		vote := models.QuestionVote{QuestionID: questionID, UserID: userID}
+		result := tx.Where(vote).FirstOrCreate(&vote)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		added = true
+		return tx.Model(&models.Question{}).Where("id = ?", questionID).
+			UpdateColumn("upvotes", gorm.Expr("upvotes + 1")).Error
+	})
+	return added, err
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"time"
+)
+
+// RateLimitRepository 以資料庫保存固定窗口的限流計數，讓多個實例共用同一份計數
+type RateLimitRepository interface {
+	Hit(key string, window time.Duration) (count int, reset time.Time, err error)
+	Prune(now time.Time) error
+}
+
+type rateLimitRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewRateLimitRepository(db *storage.PostgresDB) RateLimitRepository {
+	return &rateLimitRepository{db: db}
+}
+
+// Hit 將 key 在目前窗口的計數加一，窗口已結束時重新開始計數
+func (r *rateLimitRepository) Hit(key string, window time.Duration) (int, time.Time, error) {
+	now := time.Now()
+	var counter models.RateLimitCounter
+	err := r.db.Raw(`INSERT INTO rate_limit_counters (key, count, window_end) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limit_counters.window_end <= ? THEN 1 ELSE rate_limit_counters.count + 1 END,
+			window_end = CASE WHEN rate_limit_counters.window_end <= ? THEN EXCLUDED.window_end ELSE rate_limit_counters.window_end END
+		RETURNING count, window_end`, key, now.Add(window), now, now).
+		Scan(&counter).Error
+	return counter.Count, counter.WindowEnd, err
+}
+
+// Prune 刪除窗口已結束的計數
+func (r *rateLimitRepository) Prune(now time.Time) error {
+	return r.db.Where("window_end <= ?", now).Delete(&models.RateLimitCounter{}).Error
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+)
+
+// ReactionCount 是按消息和表情分組的回應數
+type ReactionCount struct {
+	MessageID uint
+	Emoji     string
+	Count     int
+}
+
+// SideReactionCount 是按消息發送方角色和表情分組的回應數
+type SideReactionCount struct {
+	Role  string
+	Emoji string
+	Count int
+}
+
+type ReactionRepository interface {
+	Add(reaction *models.Reaction) (bool, error)
+	Remove(messageID, userID uint, emoji string) (bool, error)
+	CountByMessages(messageIDs []uint) ([]ReactionCount, error)
+	CountByRole(roomID uint) ([]SideReactionCount, error)
+}
+
+type reactionRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewReactionRepository(db *storage.PostgresDB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+// Add 新增回應，同一用戶對同一消息重複使用同一表情時返回 false
+func (r *reactionRepository) Add(reaction *models.Reaction) (bool, error) {
+	result := r.db.Where(models.Reaction{
+		MessageID: reaction.MessageID,
+		UserID:    reaction.UserID,
+		Emoji:     reaction.Emoji,
+	}).Attrs(models.Reaction{RoomID: reaction.RoomID}).FirstOrCreate(reaction)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Remove 撤回回應，回應不存在時返回 false
+func (r *reactionRepository) Remove(messageID, userID uint, emoji string) (bool, error) {
+	result := r.db.Unscoped().
+		Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&models.Reaction{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountByMessages 統計多則消息各表情的回應數
+func (r *reactionRepository) CountByMessages(messageIDs []uint) ([]ReactionCount, error) {
+	var counts []ReactionCount
+	err := r.db.Model(&models.Reaction{}).
+		Select("message_id, emoji, COUNT(*) AS count").
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Scan(&counts).Error
+	return counts, err
+}
+
+// CountByRole 統計房間內各發言角色收到的各表情回應數，已刪除或未發布的消息不計
+func (r *reactionRepository) CountByRole(roomID uint) ([]SideReactionCount, error) {
+	var counts []SideReactionCount
+	err := r.db.Model(&models.Reaction{}).
+		Select("messages.role AS role, reactions.emoji AS emoji, COUNT(*) AS count").
+		Joins("JOIN messages ON messages.id = reactions.message_id").
+		Where("reactions.room_id = ? AND reactions.deleted_at IS NULL", roomID).
+		Where("messages.deleted_at IS NULL AND messages.status = ?", models.MessageStatusPublished).
+		Group("messages.role, reactions.emoji").
+		Scan(&counts).Error
+	return counts, err
+}
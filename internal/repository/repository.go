@@ -3,13 +3,29 @@ package repository
 import "debate_web/internal/storage"
 
 type Repositories struct {
-	User UserRepository
-	Room RoomRepository
+	User       UserRepository
+	Room       RoomRepository
+	Message    MessageRepository
+	Question   QuestionRepository
+	Reaction   ReactionRepository
+	Moderation ModerationRepository
+	RateLimit  RateLimitRepository
+	Token      TokenRepository
+	TwoFactor  TwoFactorRepository
+	Identity   IdentityRepository
 }
 
 func NewRepositories(db *storage.PostgresDB) *Repositories {
 	return &Repositories{
-		User: NewUserRepository(db),
-		Room: NewRoomRepository(db),
+		User:       NewUserRepository(db),
+		Room:       NewRoomRepository(db),
+		Message:    NewMessageRepository(db),
+		Question:   NewQuestionRepository(db),
+		Reaction:   NewReactionRepository(db),
+		Moderation: NewModerationRepository(db),
+		RateLimit:  NewRateLimitRepository(db),
+		Token:      NewTokenRepository(db),
+		TwoFactor:  NewTwoFactorRepository(db),
+		Identity:   NewIdentityRepository(db),
 	}
 }
@@ -11,6 +11,7 @@ type RoomRepository interface {
 	Update(room *models.Room) error
 	Delete(id uint) error
 	FindAll() ([]models.Room, error) // 簡單的列表查詢
+	FindByDebater(userID uint) ([]models.Room, error)
 }
 
 type roomRepository struct {
@@ -48,3 +49,11 @@ func (r *roomRepository) FindAll() ([]models.Room, error) {
 	err := r.db.Order("created_at DESC").Find(&rooms).Error
 	return rooms, err
 }
+
+// FindByDebater 查詢用戶擔任正方或反方的所有房間
+func (r *roomRepository) FindByDebater(userID uint) ([]models.Room, error) {
+	var rooms []models.Room
+	err := r.db.Where("proponent_id = ? OR opponent_id = ?", userID, userID).
+		Order("created_at ASC").Find(&rooms).Error
+	return rooms, err
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Postgres 預設的分詞器會把連續的漢字視為一個詞，無法搜尋句子中的詞語。
+// 因此在寫入和查詢前先在每個漢字前後加上空白，讓每個漢字成為獨立的詞，
+// 查詢時再把連續的漢字包成片語，要求它們在內容中相鄰出現。
+
+// searchDocument 將消息內容轉換為建立 tsvector 用的文字
+func searchDocument(content string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(content) {
+		if unicode.Is(unicode.Han, r) {
+			b.WriteRune(' ')
+			b.WriteRune(r)
+			b.WriteRune(' ')
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// searchQuery 將用戶輸入轉換為 websearch_to_tsquery 的查詢，引號外連續的漢字成為片語
+func searchQuery(query string) string {
+	var b strings.Builder
+	inQuote, inHan := false, false
+	var prev rune
+	for _, r := range strings.ToLower(query) {
+		isHan := unicode.Is(unicode.Han, r)
+		switch {
+		case isHan && inQuote:
+			// 用戶自己的引號內已經是片語，只需分開每個漢字
+			b.WriteRune(' ')
+			b.WriteRune(r)
+		case isHan && !inHan:
+			// 排除符號必須緊貼在片語前
+			if prev != '-' {
+				b.WriteRune(' ')
+			}
+			b.WriteRune('"')
+			b.WriteRune(r)
+		case isHan:
+			b.WriteRune(' ')
+			b.WriteRune(r)
+		default:
+			if inHan && !inQuote {
+				b.WriteString(`" `)
+			}
+			if r == '"' {
+				inQuote = !inQuote
+			}
+			b.WriteRune(r)
+		}
+		inHan = isHan
+		prev = r
+	}
+	if inHan && !inQuote {
+		b.WriteRune('"')
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,28 @@
+package repository
+
+import "testing"
+
+func TestSearchDocument(t *testing.T) {
+	got := searchDocument("支持Death Penalty")
+	want := " 支  持 death penalty"
+	if got != want {
+		t.Errorf("searchDocument = %q, want %q", got, want)
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"死刑", `"死 刑"`},
+		{"死刑 deterrence", `"死 刑"  deterrence`},
+		{`"死刑 存廢"`, `" 死 刑  存 廢"`},
+		{"cost -死刑", `cost -"死 刑"`},
+	}
+	for _, tt := range tests {
+		if got := searchQuery(tt.query); got != tt.want {
+			t.Errorf("searchQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
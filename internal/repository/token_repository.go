@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	FindRefreshToken(hash string) (*models.RefreshToken, error)
+	FindRefreshTokenByAccessID(accessID string) (*models.RefreshToken, error)
+	UseRefreshToken(id uint) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeUser(userID uint) error
+	RevokeUserExcept(userID uint, familyID string) error
+	RevokeAccessToken(token *models.RevokedToken) error
+	IsRevoked(accessID string, userID uint) (bool, error)
+	CreateUserToken(token *models.UserToken) error
+	FindUserToken(purpose, hash string) (*models.UserToken, error)
+	UseUserToken(id uint) (bool, error)
+	InvalidateUserTokens(userID uint, purpose string) error
+	PruneExpired(now time.Time) error
+}
+
+type tokenRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewTokenRepository(db *storage.PostgresDB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *tokenRepository) FindRefreshToken(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *tokenRepository) FindRefreshTokenByAccessID(accessID string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("access_id = ?", accessID).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseRefreshToken 將 refresh token 標記為已使用，token 已被使用或撤銷時返回 false
+// 同一個 token 被同時提交兩次時只有一次會成功
+func (r *tokenRepository) UseRefreshToken(id uint) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeFamily 撤銷同一次登入換發出的所有 refresh token，以及其中尚未到期的 access token
+func (r *tokenRepository) RevokeFamily(familyID string) error {
+	return r.revoke("family_id = ?", familyID)
+}
+
+// RevokeUser 撤銷用戶在所有裝置上的 refresh token 和尚未到期的 access token
+func (r *tokenRepository) RevokeUser(userID uint) error {
+	return r.revoke("user_id = ?", userID)
+}
+
+// RevokeUserExcept 撤銷用戶在其他裝置上的憑證，保留 familyID 這一次登入
+func (r *tokenRepository) RevokeUserExcept(userID uint, familyID string) error {
+	return r.revoke("user_id = ? AND family_id <> ?", userID, familyID)
+}
+
+// revoke 撤銷符合條件的所有 refresh token 及對應的 access token
+func (r *tokenRepository) revoke(query string, args ...interface{}) error {
+	now := time.Now()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tokens []models.RefreshToken
+		if err := tx.Where(query, args...).Where("access_expires_at > ?", now).Find(&tokens).Error; err != nil {
+			return err
+		}
+		for _, token := range tokens {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+				ID:        token.AccessID,
+				UserID:    token.UserID,
+				ExpiresAt: token.AccessExpiresAt,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.RefreshToken{}).Where(query, args...).Where("revoked_at IS NULL").
+			Update("revoked_at", now).Error
+	})
+}
+
+func (r *tokenRepository) RevokeAccessToken(token *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+// IsRevoked 檢查 access token 是否已被撤銷，用戶被停用或刪除時其所有 token 都視為已撤銷
+func (r *tokenRepository) IsRevoked(accessID string, userID uint) (bool, error) {
+	var revoked bool
+	err := r.db.Raw(`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE id = ?)
+		OR NOT EXISTS (SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL AND disabled_at IS NULL)`,
+		accessID, userID).Scan(&revoked).Error
+	return revoked, err
+}
+
+func (r *tokenRepository) CreateUserToken(token *models.UserToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *tokenRepository) FindUserToken(purpose, hash string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.Where("purpose = ? AND token_hash = ?", purpose, hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// UseUserToken 將一次性 token 標記為已使用，token 已被使用或已過期時返回 false
+func (r *tokenRepository) UseUserToken(id uint) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, now).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// InvalidateUserTokens 讓用戶所有尚未使用的同類 token 失效
+func (r *tokenRepository) InvalidateUserTokens(userID uint, purpose string) error {
+	return r.db.Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
+
+// PruneExpired 刪除已過期的 refresh token、一次性 token 和撤銷紀錄
+func (r *tokenRepository) PruneExpired(now time.Time) error {
+	if err := r.db.Where("expires_at <= ?", now).Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+	if err := r.db.Where("expires_at <= ?", now).Delete(&models.UserToken{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error
+}
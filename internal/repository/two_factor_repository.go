@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TwoFactorRepository interface {
+	SetPendingSecret(userID uint, secret string) error
+	Enable(userID uint, secret string, step int64, at time.Time, codeHashes []string) error
+	Disable(userID uint) error
+	UseStep(userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	UseRecoveryCode(userID uint, codeHash string) (bool, error)
+	CountRecoveryCodes(userID uint) (int64, error)
+}
+
+type twoFactorRepository struct {
+	db *storage.PostgresDB
+}
+
+func NewTwoFactorRepository(db *storage.PostgresDB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// SetPendingSecret 保存設定中的密鑰，啟用前不影響登入
+func (r *twoFactorRepository) SetPendingSecret(userID uint, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("totp_pending_secret", secret).Error
+}
+
+// Enable 以確認過的密鑰啟用兩步驟驗證，並換上新的備用碼
+// step 是確認時使用的時間步，之後不能再用同一個驗證碼登入
+func (r *twoFactorRepository) Enable(userID uint, secret string, step int64, at time.Time, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":         secret,
+			"totp_pending_secret": "",
+			"totp_enabled_at":     at,
+			"totp_last_step":      step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// Disable 停用兩步驟驗證並刪除所有備用碼
+func (r *twoFactorRepository) Disable(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":         "",
+			"totp_pending_secret": "",
+			"totp_enabled_at":     nil,
+			"totp_last_step":      0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// UseStep 記錄用戶使用了 step 時間步的驗證碼，該時間步或更晚的驗證碼已經用過時返回 false
+func (r *twoFactorRepository) UseStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", userID, step).Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplaceRecoveryCodes 刪除用戶現有的備用碼並保存新的備用碼
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 將備用碼標記為已使用，備用碼不存在或已使用時返回 false
+func (r *twoFactorRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes 返回用戶尚未使用的備用碼數量
+func (r *twoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
@@ -3,13 +3,38 @@ package repository
 import (
 	"debate_web/internal/repository/models"
 	"debate_web/internal/storage"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type UserRepository interface {
 	Create(user *models.User) error
 	FindByID(id uint) (*models.User, error)
+	FindByIDs(ids []uint) ([]models.User, error)
 	FindByUsername(username string) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
+	UpdateRole(id uint, role models.UserRole) error
+	UpdatePassword(id uint, hash string) error
+	UpdateProfile(user *models.User) error
+	UpdateEmail(id uint, email *string) error
+	MarkEmailVerified(id uint, email string, at time.Time) (bool, error)
+	SetDisabled(id uint, disabledAt *time.Time) error
+	Search(filter UserSearchFilter) ([]models.User, int64, error)
+	IncrementFailedLogins(id uint) (int, error)
+	Lock(id uint, until time.Time) error
+	ResetLoginFailures(id uint) error
+}
+
+// UserSearchFilter 是管理員查詢用戶的條件，零值的欄位不參與過濾
+type UserSearchFilter struct {
+	Query    string // 用戶名包含的字串，不分大小寫
+	Role     models.UserRole
+	Disabled *bool
+	Limit    int
+	Offset   int
 }
 
 type userRepository struct {
@@ -33,6 +58,16 @@ func (r *userRepository) FindByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// FindByIDs 批量查詢用戶，不存在的 ID 會被忽略
+func (r *userRepository) FindByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
 func (r *userRepository) FindByUsername(username string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("username = ?", username).First(&user).Error
@@ -42,6 +77,107 @@ func (r *userRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
+func (r *userRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
+
+func (r *userRepository) UpdateRole(id uint, role models.UserRole) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *userRepository) UpdatePassword(id uint, hash string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", hash).Error
+}
+
+// UpdateProfile 只更新個人資料欄位，不影響帳號狀態
+func (r *userRepository) UpdateProfile(user *models.User) error {
+	return r.db.Model(user).
+		Select("display_name", "bio", "avatar_key", "language", "preferred_sides").
+		Updates(user).Error
+}
+
+// UpdateEmail 設定新的電子郵件地址，新地址需要重新驗證
+func (r *userRepository) UpdateEmail(id uint, email *string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email":             email,
+		"email_verified_at": nil,
+	}).Error
+}
+
+// MarkEmailVerified 在用戶的電子郵件仍是 email 時將其標記為已驗證，地址已被更換時返回 false
+func (r *userRepository) MarkEmailVerified(id uint, email string, at time.Time) (bool, error) {
+	result := r.db.Model(&models.User{}).Where("id = ? AND email = ?", id, email).Update("email_verified_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetDisabled 停用或重新啟用帳號，disabledAt 為 nil 表示啟用
+func (r *userRepository) SetDisabled(id uint, disabledAt *time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("disabled_at", disabledAt).Error
+}
+
+// Search 按 ID 順序查詢符合條件的用戶，並返回符合條件的總數
+func (r *userRepository) Search(filter UserSearchFilter) ([]models.User, int64, error) {
+	db := r.db.Model(&models.User{})
+	if filter.Query != "" {
+		db = db.Where("username ILIKE ?", "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Role != "" {
+		db = db.Where("role = ?", filter.Role)
+	}
+	if filter.Disabled != nil {
+		if *filter.Disabled {
+			db = db.Where("disabled_at IS NOT NULL")
+		} else {
+			db = db.Where("disabled_at IS NULL")
+		}
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := db.Order("id ASC").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error
+	return users, total, err
+}
+
+// escapeLike 跳脫 LIKE 模式中的特殊字元
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// IncrementFailedLogins 將用戶連續登入失敗的次數加一並返回新的次數
+func (r *userRepository) IncrementFailedLogins(id uint) (int, error) {
+	var user models.User
+	err := r.db.Raw("UPDATE users SET failed_logins = failed_logins + 1 WHERE id = ? RETURNING failed_logins", id).
+		Scan(&user).Error
+	return user.FailedLogins, err
+}
+
+// Lock 鎖定帳號直到指定時間，並重新開始計算失敗次數
+func (r *userRepository) Lock(id uint, until time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"lockouts":      gorm.Expr("lockouts + 1"),
+		"locked_until":  until,
+	}).Error
+}
+
+// ResetLoginFailures 在成功登入後清除失敗和鎖定紀錄
+func (r *userRepository) ResetLoginFailures(id uint) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"failed_logins": 0,
+		"lockouts":      0,
+		"locked_until":  nil,
+	}).Error
+}
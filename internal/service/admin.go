@@ -0,0 +1,283 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 用戶列表的分頁限制
+const (
+	defaultUserListLimit = 50
+	maxUserListLimit     = 200
+)
+
+// AdminService 處理管理員對用戶帳號和房間的管理
+type AdminService struct {
+	userRepo     repository.UserRepository
+	roomRepo     repository.RoomRepository
+	tokenService *TokenService
+	policy       *PasswordPolicy
+	twoFactor    *TwoFactorService
+	wsService    *WebSocketService
+}
+
+func NewAdminService(userRepo repository.UserRepository, roomRepo repository.RoomRepository, tokens *TokenService, policy *PasswordPolicy, twoFactor *TwoFactorService, ws *WebSocketService) *AdminService {
+	return &AdminService{
+		userRepo:     userRepo,
+		roomRepo:     roomRepo,
+		tokenService: tokens,
+		policy:       policy,
+		twoFactor:    twoFactor,
+		wsService:    ws,
+	}
+}
+
+// UserListParams 是管理員查詢用戶的參數
+type UserListParams struct {
+	Query    string
+	Role     string
+	Disabled *bool
+	Limit    int
+	Offset   int
+}
+
+// UserSummary 是管理員看到的用戶資料
+type UserSummary struct {
+	ID          uint            `json:"id"`
+	Username    string          `json:"username"`
+	Email       *string         `json:"email"`
+	Verified    bool            `json:"email_verified"`
+	Role        models.UserRole `json:"role"`
+	TwoFactor   bool            `json:"two_factor_enabled"`
+	CreatedAt   time.Time       `json:"created_at"`
+	DisabledAt  *time.Time      `json:"disabled_at"`
+	LockedUntil *time.Time      `json:"locked_until"`
+}
+
+// UserList 是一頁用戶列表
+type UserList struct {
+	Users []UserSummary `json:"users"`
+	Total int64         `json:"total"`
+}
+
+// ListUsers 按條件查詢用戶
+func (s *AdminService) ListUsers(params UserListParams) (*UserList, error) {
+	role := models.UserRole(params.Role)
+	if role != "" && !IsValidUserRole(role) {
+		return nil, errors.New("無效的角色")
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultUserListLimit
+	}
+	if params.Limit > maxUserListLimit {
+		params.Limit = maxUserListLimit
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+
+	users, total, err := s.userRepo.Search(repository.UserSearchFilter{
+		Query:    strings.TrimSpace(params.Query),
+		Role:     role,
+		Disabled: params.Disabled,
+		Limit:    params.Limit,
+		Offset:   params.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	list := &UserList{Users: make([]UserSummary, 0, len(users)), Total: total}
+	for _, user := range users {
+		list.Users = append(list.Users, UserSummary{
+			ID:          user.ID,
+			Username:    user.Username,
+			Email:       user.Email,
+			Verified:    user.EmailVerifiedAt != nil,
+			Role:        user.Role,
+			TwoFactor:   user.TwoFactorEnabled(),
+			CreatedAt:   user.CreatedAt,
+			DisabledAt:  user.DisabledAt,
+			LockedUntil: user.LockedUntil,
+		})
+	}
+	return list, nil
+}
+
+// DisableUser 停用帳號，撤銷其所有憑證並立即斷開其所有 WebSocket 連接
+func (s *AdminService) DisableUser(adminID, userID uint) error {
+	if adminID == userID {
+		return errors.New("不能對自己執行此操作")
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.userRepo.SetDisabled(userID, &now); err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeAll(userID); err != nil {
+		return err
+	}
+	s.wsService.DisconnectUserEverywhere(userID, "account_disabled", "帳號已被停用")
+
+	log.Printf("admin %d disabled user %d", adminID, userID)
+	return nil
+}
+
+// EnableUser 重新啟用帳號，用戶需要重新登入
+func (s *AdminService) EnableUser(adminID, userID uint) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return errors.New("用戶不存在")
+	}
+	if err := s.userRepo.SetDisabled(userID, nil); err != nil {
+		return err
+	}
+
+	log.Printf("admin %d enabled user %d", adminID, userID)
+	return nil
+}
+
+// ResetPassword 設定用戶的新密碼，解除登入鎖定並登出其所有裝置
+func (s *AdminService) ResetPassword(adminID, userID uint, password string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if err := s.policy.Validate(user.Username, password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(userID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.userRepo.ResetLoginFailures(userID); err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeAll(userID); err != nil {
+		return err
+	}
+
+	log.Printf("admin %d reset the password of user %d", adminID, userID)
+	return nil
+}
+
+// ResetTwoFactor 為遺失驗證器的用戶停用兩步驟驗證並登出其所有裝置
+func (s *AdminService) ResetTwoFactor(adminID, userID uint) error {
+	if adminID == userID {
+		return errors.New("不能對自己執行此操作")
+	}
+	if err := s.twoFactor.Reset(userID); err != nil {
+		return err
+	}
+
+	log.Printf("admin %d reset the two-factor authentication of user %d", adminID, userID)
+	return nil
+}
+
+// ChangeRole 變更用戶的全站角色，並登出其所有裝置，讓新角色立即生效
+func (s *AdminService) ChangeRole(adminID, userID uint, role models.UserRole) error {
+	if !IsValidUserRole(role) {
+		return errors.New("無效的角色")
+	}
+	if adminID == userID {
+		return errors.New("不能對自己執行此操作")
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if user.Role == role {
+		return nil
+	}
+
+	if err := s.userRepo.UpdateRole(userID, role); err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeAll(userID); err != nil {
+		return err
+	}
+
+	log.Printf("admin %d changed the role of user %d from %s to %s", adminID, userID, user.Role, role)
+	return nil
+}
+
+// DisconnectUser 斷開用戶在所有房間的 WebSocket 連接，用戶之後仍可重新連接
+func (s *AdminService) DisconnectUser(adminID, userID uint) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return errors.New("用戶不存在")
+	}
+	s.wsService.DisconnectUserEverywhere(userID, "disconnected", "連接已被管理員中斷")
+
+	log.Printf("admin %d disconnected user %d", adminID, userID)
+	return nil
+}
+
+// FinishRoom 強制結束房間的辯論
+func (s *AdminService) FinishRoom(adminID, roomID uint) error {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return errors.New("房間不存在")
+	}
+	if room.Status == models.RoomStatusFinished {
+		return errors.New("辯論已經結束")
+	}
+
+	room.Status = models.RoomStatusFinished
+	room.EndTime = time.Now()
+	if err := s.roomRepo.Update(room); err != nil {
+		return err
+	}
+	s.wsService.BroadcastSystemMessage(roomID, "辯論已被管理員結束")
+
+	log.Printf("admin %d finished room %d", adminID, roomID)
+	return nil
+}
+
+// DeleteRoom 刪除房間，並斷開房間內的所有連接
+func (s *AdminService) DeleteRoom(adminID, roomID uint) error {
+	if _, err := s.roomRepo.FindByID(roomID); err != nil {
+		return errors.New("房間不存在")
+	}
+	if err := s.roomRepo.Delete(roomID); err != nil {
+		return err
+	}
+	s.wsService.DisconnectRoom(roomID, &models.Message{
+		Type:    "room_deleted",
+		RoomID:  roomID,
+		Content: "房間已被管理員刪除",
+	})
+
+	log.Printf("admin %d deleted room %d", adminID, roomID)
+	return nil
+}
+
+// DisconnectRoom 斷開房間內的所有 WebSocket 連接，參與者之後仍可重新連接
+func (s *AdminService) DisconnectRoom(adminID, roomID uint) error {
+	if _, err := s.roomRepo.FindByID(roomID); err != nil {
+		return errors.New("房間不存在")
+	}
+	s.wsService.DisconnectRoom(roomID, &models.Message{
+		Type:    "disconnected",
+		RoomID:  roomID,
+		Content: "連接已被管理員中斷",
+	})
+
+	log.Printf("admin %d disconnected room %d", adminID, roomID)
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestDisableUserRevokesAccess(t *testing.T) {
+	ws, rooms, _ := newTestWebSocketService()
+	users := &memoryUserRepository{}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	admin := &models.User{Username: "admin", Role: models.UserRoleAdmin}
+	alice := &models.User{Username: "alice", Password: string(hash), Role: models.UserRoleUser}
+	users.Create(admin)
+	users.Create(alice)
+	tokens := NewTokenService(newMemoryTokenRepository(), users, config.AuthConfig{AccessTTL: time.Minute, RefreshTTL: time.Hour}, testJWTKeys)
+	admins := NewAdminService(users, rooms, tokens, NewPasswordPolicy(config.PasswordConfig{}), nil, ws)
+	userService := NewUserService(users, config.LockoutConfig{})
+
+	pair, _ := tokens.Issue(alice)
+	first := newTestClient(1, alice.ID, 8)
+	second := newTestClient(2, alice.ID, 8)
+	ws.addClient(first)
+	ws.addClient(second)
+	defer ws.removeClient(first)
+	defer ws.removeClient(second)
+
+	if err := admins.DisableUser(admin.ID, admin.ID); err == nil || err.Error() != "不能對自己執行此操作" {
+		t.Errorf("disable self: err = %v", err)
+	}
+	if err := admins.DisableUser(admin.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, client := range []*Client{first, second} {
+		if msg := nextNonSystem(client); msg == nil || msg.Type != "account_disabled" {
+			t.Errorf("room %d: frame = %+v, want account_disabled", client.RoomID, msg)
+		}
+		if msg := nextNonSystem(client); msg != nil {
+			t.Errorf("room %d: connection still open, got %+v", client.RoomID, msg)
+		}
+	}
+
+	if _, err := tokens.Refresh(pair.RefreshToken); err == nil {
+		t.Error("refresh succeeded for a disabled user")
+	}
+	if _, err := userService.Authenticate("alice", "secret"); err == nil || err.Error() != "帳號已被停用" {
+		t.Errorf("login while disabled: err = %v", err)
+	}
+
+	if err := admins.EnableUser(admin.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userService.Authenticate("alice", "secret"); err != nil {
+		t.Errorf("login after enabling: %v", err)
+	}
+}
+
+func TestChangeRoleAndListUsers(t *testing.T) {
+	ws, rooms, _ := newTestWebSocketService()
+	users := &memoryUserRepository{}
+	admin := &models.User{Username: "admin", Role: models.UserRoleAdmin}
+	users.Create(admin)
+	users.Create(&models.User{Username: "Alice", Role: models.UserRoleUser})
+	users.Create(&models.User{Username: "bob", Role: models.UserRoleUser})
+	tokens := NewTokenService(newMemoryTokenRepository(), users, config.AuthConfig{AccessTTL: time.Minute, RefreshTTL: time.Hour}, testJWTKeys)
+	admins := NewAdminService(users, rooms, tokens, NewPasswordPolicy(config.PasswordConfig{}), nil, ws)
+
+	if err := admins.ChangeRole(admin.ID, 2, "superuser"); err == nil || err.Error() != "無效的角色" {
+		t.Errorf("invalid role: err = %v", err)
+	}
+	if err := admins.ChangeRole(admin.ID, 2, models.UserRoleModerator); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := admins.ListUsers(UserListParams{Query: "ali"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Total != 1 || list.Users[0].Username != "Alice" || list.Users[0].Role != models.UserRoleModerator {
+		t.Errorf("search ali = %+v", list)
+	}
+
+	list, _ = admins.ListUsers(UserListParams{Role: "user"})
+	if list.Total != 1 || list.Users[0].Username != "bob" {
+		t.Errorf("role filter = %+v", list)
+	}
+}
+
+func TestDeleteRoomDisconnectsClients(t *testing.T) {
+	ws, rooms, _ := newTestWebSocketService()
+	rooms.rooms[1] = &models.Room{Model: gormModel(1), Status: models.RoomStatusOngoing}
+	admins := NewAdminService(&memoryUserRepository{}, rooms, nil, nil, nil, ws)
+
+	client := newTestClient(1, 5, 8)
+	ws.addClient(client)
+	defer ws.removeClient(client)
+
+	if err := admins.FinishRoom(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if room := rooms.rooms[1]; room.Status != models.RoomStatusFinished || time.Since(room.EndTime) > time.Minute {
+		t.Errorf("status = %s, end time = %s, want finished now", room.Status, room.EndTime)
+	}
+	if err := admins.FinishRoom(1, 1); err == nil {
+		t.Error("finished an already finished room")
+	}
+
+	if err := admins.DeleteRoom(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if msg := nextNonSystem(client); msg == nil || msg.Type != "room_deleted" {
+		t.Errorf("frame = %+v, want room_deleted", msg)
+	}
+	if msg := nextNonSystem(client); msg != nil {
+		t.Errorf("connection still open, got %+v", msg)
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"time"
+)
+
+// ArgumentService 整理辯論場上消息之間的回應關係
+type ArgumentService struct {
+	messageRepo  repository.MessageRepository
+	roomRepo     repository.RoomRepository
+	reactionRepo repository.ReactionRepository
+}
+
+func NewArgumentService(messageRepo repository.MessageRepository, roomRepo repository.RoomRepository, reactionRepo repository.ReactionRepository) *ArgumentService {
+	return &ArgumentService{
+		messageRepo:  messageRepo,
+		roomRepo:     roomRepo,
+		reactionRepo: reactionRepo,
+	}
+}
+
+// ArgumentNode 是論點樹中的一則消息及其所有回應
+type ArgumentNode struct {
+	ID        uint                `json:"id"`
+	UserID    uint                `json:"user_id"`
+	Role      string              `json:"role"`
+	Kind      models.ArgumentKind `json:"kind"`
+	Content   string              `json:"content"`
+	ReplyToID uint                `json:"reply_to_id"`
+	CreatedAt time.Time           `json:"created_at"`
+	Replies   []*ArgumentNode     `json:"replies"`
+}
+
+// Tree 返回房間辯論場上的論點樹，根節點是沒有回應其他論點的發言，按發言順序排列
+func (s *ArgumentService) Tree(roomID uint) ([]*ArgumentNode, error) {
+	if _, err := s.roomRepo.FindByID(roomID); err != nil {
+		return nil, errors.New("房間不存在")
+	}
+
+	messages, err := s.messageRepo.FindByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildArgumentTree(messages), nil
+}
+
+// isArgument 判斷消息是否屬於辯論場上的論點
+func isArgument(msg *models.Message) bool {
+	if msg.Channel != "" && msg.Channel != models.ChannelFloor {
+		return false
+	}
+	return msg.Role == "proponent" || msg.Role == "opponent" || msg.Role == "moderator"
+}
+
+// buildArgumentTree 依照 ReplyToID 將按順序排列的消息組成樹
+// 回應的目標不是論點時，該消息作為根節點
+func buildArgumentTree(messages []models.Message) []*ArgumentNode {
+	nodes := make(map[uint]*ArgumentNode)
+	roots := []*ArgumentNode{}
+
+	for i := range messages {
+		msg := &messages[i]
+		if !isArgument(msg) {
+			continue
+		}
+
+		node := &ArgumentNode{
+			ID:        msg.ID,
+			UserID:    msg.UserID,
+			Role:      msg.Role,
+			Kind:      msg.Kind,
+			Content:   msg.Content,
+			ReplyToID: msg.ReplyToID,
+			CreatedAt: msg.CreatedAt,
+			Replies:   []*ArgumentNode{},
+		}
+		nodes[msg.ID] = node
+
+		if parent, ok := nodes[msg.ReplyToID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return roots
+}
+
+// validateArgument 檢查消息的論點類型和回應目標，回應目標必須是同一房間內已保存的消息
+func validateArgument(messageRepo repository.MessageRepository, msg *models.Message) error {
+	if msg.Kind != "" && !msg.Kind.IsValid() {
+		return errors.New("無效的論點類型")
+	}
+	if msg.Kind != "" && msg.Channel != models.ChannelFloor {
+		return errors.New("只有辯論場上的發言可以標記論點類型")
+	}
+
+	if msg.ReplyToID == 0 {
+		return nil
+	}
+	target, err := messageRepo.FindByID(msg.ReplyToID)
+	if err != nil || target.RoomID != msg.RoomID || target.Status != models.MessageStatusPublished {
+		return errors.New("回應的消息不存在於此房間")
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"bufio"
+	"debate_web/internal/repository/models"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxNodeLabelLength 論點圖節點標籤的最大字數
+const maxNodeLabelLength = 40
+
+// ArgumentGraph 是辯論論點的有向圖，邊由回應方指向被回應的論點
+type ArgumentGraph struct {
+	RoomID uint             `json:"room_id"`
+	Topic  string           `json:"topic"`
+	Nodes  []ArgumentVertex `json:"nodes"`
+	Edges  []ArgumentEdge   `json:"edges"`
+}
+
+// ArgumentVertex 是論點圖中的一個論點
+type ArgumentVertex struct {
+	ID        uint                `json:"id"`
+	Side      string              `json:"side"`
+	Kind      models.ArgumentKind `json:"kind"`
+	UserID    uint                `json:"user_id"`
+	Content   string              `json:"content"`
+	Reactions map[string]int      `json:"reactions"`
+}
+
+// ArgumentEdge 是兩個論點之間的關係
+type ArgumentEdge struct {
+	From     uint   `json:"from"`
+	To       uint   `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// 論點之間的關係，由回應方的論點類型決定
+const (
+	RelationSupports  = "supports"
+	RelationRebuts    = "rebuts"
+	RelationConcedes  = "concedes"
+	RelationQuestions = "questions"
+	RelationReplies   = "replies"
+)
+
+// argumentRelation 根據回應方的論點類型判斷與被回應論點的關係
+func argumentRelation(kind models.ArgumentKind) string {
+	switch kind {
+	case models.ArgumentClaim, models.ArgumentEvidence:
+		return RelationSupports
+	case models.ArgumentRebuttal:
+		return RelationRebuts
+	case models.ArgumentConcession:
+		return RelationConcedes
+	case models.ArgumentQuestion:
+		return RelationQuestions
+	default:
+		return RelationReplies
+	}
+}
+
+// Graph 將房間的論點樹轉換為附帶回應數的論點圖
+func (s *ArgumentService) Graph(roomID uint) (*ArgumentGraph, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return nil, errors.New("房間不存在")
+	}
+
+	messages, err := s.messageRepo.FindByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &ArgumentGraph{
+		RoomID: room.ID,
+		Topic:  room.Name,
+		Nodes:  []ArgumentVertex{},
+		Edges:  []ArgumentEdge{},
+	}
+
+	var walk func(nodes []*ArgumentNode)
+	walk = func(nodes []*ArgumentNode) {
+		for _, node := range nodes {
+			graph.Nodes = append(graph.Nodes, ArgumentVertex{
+				ID:        node.ID,
+				Side:      node.Role,
+				Kind:      node.Kind,
+				UserID:    node.UserID,
+				Content:   node.Content,
+				Reactions: map[string]int{},
+			})
+			for _, reply := range node.Replies {
+				graph.Edges = append(graph.Edges, ArgumentEdge{
+					From:     reply.ID,
+					To:       node.ID,
+					Relation: argumentRelation(reply.Kind),
+				})
+			}
+			walk(node.Replies)
+		}
+	}
+	walk(buildArgumentTree(messages))
+
+	if len(graph.Nodes) == 0 {
+		return graph, nil
+	}
+
+	ids := make([]uint, len(graph.Nodes))
+	index := make(map[uint]int, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		ids[i] = node.ID
+		index[node.ID] = i
+	}
+	counts, err := s.reactionRepo.CountByMessages(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		graph.Nodes[index[count.MessageID]].Reactions[count.Emoji] = count.Count
+	}
+
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	return graph, nil
+}
+
+// dotSides 論點圖中各方的分組名稱和顏色
+var dotSides = []struct {
+	side, label, color string
+}{
+	{"proponent", "正方", "royalblue"},
+	{"opponent", "反方", "firebrick"},
+	{"moderator", "主持人", "gray40"},
+}
+
+// dotEdgeStyles 各種關係在 DOT 中的樣式
+var dotEdgeStyles = map[string]string{
+	RelationSupports:  `color="forestgreen"`,
+	RelationRebuts:    `color="red", style="bold"`,
+	RelationConcedes:  `color="orange", style="dashed"`,
+	RelationQuestions: `color="purple", style="dotted"`,
+	RelationReplies:   `color="gray50"`,
+}
+
+// WriteDOT 以 Graphviz DOT 格式輸出論點圖，各方的論點分別放在獨立的子圖中
+func (g *ArgumentGraph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "digraph argument_map {\n")
+	fmt.Fprintf(bw, "  label=%s;\n", dotQuote(g.Topic))
+	fmt.Fprintf(bw, "  rankdir=BT;\n")
+	fmt.Fprintf(bw, "  node [shape=box, style=\"rounded,filled\", fillcolor=white];\n")
+
+	for _, side := range dotSides {
+		fmt.Fprintf(bw, "\n  subgraph cluster_%s {\n", side.side)
+		fmt.Fprintf(bw, "    label=%s;\n    color=%s;\n", dotQuote(side.label), side.color)
+		for _, node := range g.Nodes {
+			if node.Side == side.side {
+				fmt.Fprintf(bw, "    m%d [label=%s, color=%s];\n", node.ID, dotQuote(node.label()), side.color)
+			}
+		}
+		fmt.Fprintf(bw, "  }\n")
+	}
+
+	fmt.Fprintf(bw, "\n")
+	for _, edge := range g.Edges {
+		fmt.Fprintf(bw, "  m%d -> m%d [label=%s, %s];\n",
+			edge.From, edge.To, dotQuote(edge.Relation), dotEdgeStyles[edge.Relation])
+	}
+	fmt.Fprintf(bw, "}\n")
+
+	return bw.Flush()
+}
+
+// label 返回節點在 DOT 中顯示的文字：論點類型、截斷後的內容以及回應數
+func (v ArgumentVertex) label() string {
+	var b strings.Builder
+
+	kind := string(v.Kind)
+	if kind == "" {
+		kind = "statement"
+	}
+	fmt.Fprintf(&b, "[%s] %s", kind, truncateRunes(v.Content, maxNodeLabelLength))
+
+	if len(v.Reactions) > 0 {
+		emojis := make([]string, 0, len(v.Reactions))
+		for emoji := range v.Reactions {
+			emojis = append(emojis, emoji)
+		}
+		sort.Strings(emojis)
+
+		b.WriteString("\n")
+		for i, emoji := range emojis {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "%s%d", emoji, v.Reactions[emoji])
+		}
+	}
+
+	return b.String()
+}
+
+// dotQuote 將文字轉為 DOT 的雙引號字串
+func dotQuote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// truncateRunes 將文字截斷到指定字數，超過時以省略號結尾
+func truncateRunes(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
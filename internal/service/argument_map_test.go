@@ -0,0 +1,168 @@
+package service
+
+import (
+	"bytes"
+	"debate_web/internal/repository/models"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDotQuote(t *testing.T) {
+	tests := map[string]string{
+		`plain`:             `"plain"`,
+		`say "no"`:          `"say \"no\""`,
+		`C:\path`:           `"C:\\path"`,
+		"line1\r\nline2":    `"line1\nline2"`,
+		`\"` + "\n":         `"\\\"\n"`,
+		"死刑\n\"存廢\"":        `"死刑\n\"存廢\""`,
+		`label="x"]; m1 ->`: `"label=\"x\"]; m1 ->"`,
+	}
+	for in, want := range tests {
+		if got := dotQuote(in); got != want {
+			t.Errorf("dotQuote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestArgumentRelation(t *testing.T) {
+	tests := map[models.ArgumentKind]string{
+		models.ArgumentClaim:      RelationSupports,
+		models.ArgumentEvidence:   RelationSupports,
+		models.ArgumentRebuttal:   RelationRebuts,
+		models.ArgumentConcession: RelationConcedes,
+		models.ArgumentQuestion:   RelationQuestions,
+		"":                        RelationReplies,
+	}
+	for kind, want := range tests {
+		if got := argumentRelation(kind); got != want {
+			t.Errorf("argumentRelation(%q) = %s, want %s", kind, got, want)
+		}
+	}
+}
+
+// newTestArgumentGraph 建立一個小型的論點樹並返回其論點圖
+func newTestArgumentGraph(t *testing.T) *ArgumentGraph {
+	t.Helper()
+	_, rooms, messages := newTestWebSocketService()
+	room := &models.Room{Name: `死刑 "存廢"`, ProponentID: 1, OpponentID: 2}
+	rooms.Create(room)
+
+	post := func(userID uint, role string, kind models.ArgumentKind, replyTo uint, content string) {
+		messages.Create(&models.Message{
+			RoomID: room.ID, UserID: userID, Role: role, Kind: kind, ReplyToID: replyTo,
+			Content: content, Channel: models.ChannelFloor,
+		})
+	}
+	post(1, "proponent", models.ArgumentClaim, 0, "死刑有嚇阻作用")        // 1
+	post(2, "opponent", models.ArgumentRebuttal, 1, `沒有 "證據"`)      // 2
+	post(1, "proponent", models.ArgumentEvidence, 2, "研究顯示\n兇殺率下降") // 3
+	post(9, "moderator", models.ArgumentQuestion, 1, "請正方說明")       // 4
+	post(2, "opponent", models.ArgumentConcession, 3, "數據屬實")       // 5
+	post(3, "spectator", "", 1, "觀眾留言不在論點圖中")                       // 6
+	messages.messages[5].Channel = models.ChannelGallery
+
+	reactions := &memoryReactionRepository{messages: messages}
+	reactions.Add(&models.Reaction{MessageID: 1, UserID: 3, Emoji: "👏", RoomID: room.ID})
+	reactions.Add(&models.Reaction{MessageID: 1, UserID: 4, Emoji: "👏", RoomID: room.ID})
+	reactions.Add(&models.Reaction{MessageID: 1, UserID: 3, Emoji: "🔥", RoomID: room.ID})
+	reactions.Add(&models.Reaction{MessageID: 2, UserID: 4, Emoji: "🤔", RoomID: room.ID})
+
+	graph, err := NewArgumentService(messages, rooms, reactions).Graph(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func TestArgumentGraphJSON(t *testing.T) {
+	data, err := json.Marshal(newTestArgumentGraph(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Topic string `json:"topic"`
+		Nodes []struct {
+			ID        uint           `json:"id"`
+			Side      string         `json:"side"`
+			Kind      string         `json:"kind"`
+			Reactions map[string]int `json:"reactions"`
+		} `json:"nodes"`
+		Edges []ArgumentEdge `json:"edges"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.Topic != `死刑 "存廢"` || len(decoded.Nodes) != 5 {
+		t.Fatalf("graph = %s", data)
+	}
+	wantSides := []string{"proponent", "opponent", "proponent", "moderator", "opponent"}
+	for i, node := range decoded.Nodes {
+		if node.ID != uint(i+1) || node.Side != wantSides[i] || node.Reactions == nil {
+			t.Errorf("node %d = %+v", i, node)
+		}
+	}
+	if r := decoded.Nodes[0].Reactions; len(r) != 2 || r["👏"] != 2 || r["🔥"] != 1 {
+		t.Errorf("reactions of node 1 = %v", r)
+	}
+
+	wantEdges := map[ArgumentEdge]bool{
+		{From: 2, To: 1, Relation: RelationRebuts}:    true,
+		{From: 4, To: 1, Relation: RelationQuestions}: true,
+		{From: 3, To: 2, Relation: RelationSupports}:  true,
+		{From: 5, To: 3, Relation: RelationConcedes}:  true,
+	}
+	if len(decoded.Edges) != len(wantEdges) {
+		t.Fatalf("edges = %+v", decoded.Edges)
+	}
+	for _, edge := range decoded.Edges {
+		if !wantEdges[edge] {
+			t.Errorf("unexpected edge %+v", edge)
+		}
+	}
+}
+
+func TestArgumentGraphDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestArgumentGraph(t).WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		`digraph argument_map {`,
+		`  label="死刑 \"存廢\"";`,
+		`  rankdir=BT;`,
+		`  node [shape=box, style="rounded,filled", fillcolor=white];`,
+		``,
+		`  subgraph cluster_proponent {`,
+		`    label="正方";`,
+		`    color=royalblue;`,
+		`    m1 [label="[claim] 死刑有嚇阻作用\n👏2 🔥1", color=royalblue];`,
+		`    m3 [label="[evidence] 研究顯示\n兇殺率下降", color=royalblue];`,
+		`  }`,
+		``,
+		`  subgraph cluster_opponent {`,
+		`    label="反方";`,
+		`    color=firebrick;`,
+		`    m2 [label="[rebuttal] 沒有 \"證據\"\n🤔1", color=firebrick];`,
+		`    m5 [label="[concession] 數據屬實", color=firebrick];`,
+		`  }`,
+		``,
+		`  subgraph cluster_moderator {`,
+		`    label="主持人";`,
+		`    color=gray40;`,
+		`    m4 [label="[question] 請正方說明", color=gray40];`,
+		`  }`,
+		``,
+		`  m2 -> m1 [label="rebuts", color="red", style="bold"];`,
+		`  m4 -> m1 [label="questions", color="purple", style="dotted"];`,
+		`  m3 -> m2 [label="supports", color="forestgreen"];`,
+		`  m5 -> m3 [label="concedes", color="orange", style="dashed"];`,
+		`}`,
+		``,
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("WriteDOT =\n%s\nwant\n%s", got, want)
+	}
+}
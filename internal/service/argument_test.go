@@ -0,0 +1,54 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"testing"
+)
+
+func TestBuildArgumentTree(t *testing.T) {
+	messages := []models.Message{
+		{Model: gormModel(1), Role: "proponent", Kind: models.ArgumentClaim},
+		{Model: gormModel(2), Role: "system"},
+		{Model: gormModel(3), Role: "opponent", Kind: models.ArgumentRebuttal, ReplyToID: 1},
+		{Model: gormModel(4), Role: "spectator", Channel: models.ChannelGallery, ReplyToID: 1},
+		{Model: gormModel(5), Role: "proponent", Kind: models.ArgumentEvidence, ReplyToID: 3},
+		{Model: gormModel(6), Role: "opponent", Kind: models.ArgumentClaim, ReplyToID: 2},
+	}
+
+	roots := buildArgumentTree(messages)
+	if len(roots) != 2 || roots[0].ID != 1 || roots[1].ID != 6 {
+		t.Fatalf("expected roots [1 6], got %+v", roots)
+	}
+	if len(roots[0].Replies) != 1 || roots[0].Replies[0].ID != 3 {
+		t.Fatalf("expected message 3 to reply to 1, got %+v", roots[0].Replies)
+	}
+	if replies := roots[0].Replies[0].Replies; len(replies) != 1 || replies[0].ID != 5 {
+		t.Fatalf("expected message 5 to reply to 3, got %+v", replies)
+	}
+}
+
+func TestValidateArgument(t *testing.T) {
+	_, _, messages := newTestWebSocketService()
+	messages.Create(&models.Message{RoomID: 1, Role: "proponent"})
+	messages.Create(&models.Message{RoomID: 2, Role: "proponent"})
+
+	tests := []struct {
+		name    string
+		msg     models.Message
+		wantErr bool
+	}{
+		{"plain message", models.Message{RoomID: 1, Channel: models.ChannelFloor}, false},
+		{"valid rebuttal", models.Message{RoomID: 1, Channel: models.ChannelFloor, Kind: models.ArgumentRebuttal, ReplyToID: 1}, false},
+		{"unknown kind", models.Message{RoomID: 1, Channel: models.ChannelFloor, Kind: "insult"}, true},
+		{"kind in gallery", models.Message{RoomID: 1, Channel: models.ChannelGallery, Kind: models.ArgumentClaim}, true},
+		{"reply to other room", models.Message{RoomID: 1, Channel: models.ChannelFloor, ReplyToID: 2}, true},
+		{"reply to missing message", models.Message{RoomID: 1, Channel: models.ChannelFloor, ReplyToID: 99}, true},
+	}
+
+	for _, tt := range tests {
+		err := validateArgument(messages, &tt.msg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/mail"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"log"
+	netmail "net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 電子郵件地址的限制
+const (
+	maxEmailLength        = 254
+	defaultEmailVerifyTTL = 24 * time.Hour
+	mailTemplateVerify    = "verify_email"
+	mailTemplatePassword  = "password_reset"
+)
+
+// EmailService 管理用戶的電子郵件地址及其驗證
+type EmailService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.TokenRepository
+	mailer    mail.Mailer
+	cfg       config.EmailConfig
+}
+
+func NewEmailService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, mailer mail.Mailer, cfg config.EmailConfig) *EmailService {
+	if cfg.VerifyTTL <= 0 {
+		cfg.VerifyTTL = defaultEmailVerifyTTL
+	}
+	return &EmailService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mailer:    mailer,
+		cfg:       cfg,
+	}
+}
+
+// PrepareEmail 驗證並正規化註冊時填寫的電子郵件地址，空字串表示不設定
+func (s *EmailService) PrepareEmail(email string) (*string, error) {
+	if strings.TrimSpace(email) == "" {
+		return nil, nil
+	}
+	normalized, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAvailable(normalized, 0); err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// SetEmail 設定用戶的電子郵件地址並寄出驗證郵件，空字串表示移除
+// 更換地址後需要重新驗證，之前寄出的驗證連結隨即失效
+func (s *EmailService) SetEmail(userID uint, email string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+
+	var address *string
+	if strings.TrimSpace(email) != "" {
+		normalized, err := normalizeEmail(email)
+		if err != nil {
+			return err
+		}
+		if user.Email != nil && *user.Email == normalized {
+			return nil
+		}
+		if err := s.checkAvailable(normalized, userID); err != nil {
+			return err
+		}
+		address = &normalized
+	}
+
+	if err := s.tokenRepo.InvalidateUserTokens(userID, models.UserTokenVerifyEmail); err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateEmail(userID, address); err != nil {
+		return err
+	}
+	if address == nil {
+		return nil
+	}
+
+	user.Email, user.EmailVerifiedAt = address, nil
+	return s.sendVerification(user)
+}
+
+// SendVerification 重新寄出驗證郵件
+func (s *EmailService) SendVerification(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if user.Email == nil {
+		return errors.New("沒有需要驗證的電子郵件")
+	}
+	if user.EmailVerifiedAt != nil {
+		return errors.New("電子郵件已經驗證過了")
+	}
+	if err := s.tokenRepo.InvalidateUserTokens(userID, models.UserTokenVerifyEmail); err != nil {
+		return err
+	}
+	return s.sendVerification(user)
+}
+
+// Verify 以驗證郵件中的 token 完成驗證，token 只能使用一次
+func (s *EmailService) Verify(token string) error {
+	verifyToken, err := s.tokenRepo.FindUserToken(models.UserTokenVerifyEmail, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("無效或已過期的驗證連結")
+		}
+		return err
+	}
+	user, err := s.userRepo.FindByID(verifyToken.UserID)
+	if err != nil || user.Email == nil {
+		return errors.New("無效或已過期的驗證連結")
+	}
+
+	used, err := s.tokenRepo.UseUserToken(verifyToken.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("無效或已過期的驗證連結")
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(user.ID, *user.Email, time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return errors.New("無效或已過期的驗證連結")
+	}
+	return nil
+}
+
+// CheckVerified 確認用戶已驗證電子郵件
+func (s *EmailService) CheckVerified(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if user.VerifiedEmail() == "" {
+		return errors.New("請先驗證電子郵件")
+	}
+	return nil
+}
+
+// sendVerification 建立驗證 token 並寄到用戶目前的電子郵件地址
+func (s *EmailService) sendVerification(user *models.User) error {
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	err = s.tokenRepo.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenVerifyEmail,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.VerifyTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMail(s.mailer, user, *user.Email, mailTemplateVerify, mailData{
+		Name:    user.Name(),
+		Link:    linkWithToken(s.cfg.VerifyURL, token),
+		Minutes: int(s.cfg.VerifyTTL.Minutes()),
+	})
+	return nil
+}
+
+// checkAvailable 確認電子郵件地址沒有被其他用戶使用
+func (s *EmailService) checkAvailable(email string, userID uint) error {
+	owner, err := s.userRepo.FindByEmail(email)
+	switch {
+	case err == nil && owner.ID != userID:
+		return errors.New("電子郵件已被使用")
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return nil
+}
+
+// normalizeEmail 驗證電子郵件地址的格式並轉為小寫
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) > maxEmailLength {
+		return "", errors.New("無效的電子郵件地址")
+	}
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", errors.New("無效的電子郵件地址")
+	}
+	return email, nil
+}
+
+// mailData 是郵件範本使用的資料
+type mailData struct {
+	Name    string
+	Link    string
+	Minutes int
+}
+
+// sendMail 以用戶偏好的語言套用範本，並在背景寄出，回應時間不受寄送影響
+func sendMail(mailer mail.Mailer, user *models.User, to, template string, data mailData) {
+	msg, err := mail.Render(to, template, user.Language, data)
+	if err != nil {
+		log.Printf("render %s mail for user %d error: %v", template, user.ID, err)
+		return
+	}
+	go func() {
+		if err := mailer.Send(msg); err != nil {
+			log.Printf("send %s mail to user %d error: %v", template, user.ID, err)
+		}
+	}()
+}
+
+// linkWithToken 返回在 token 參數中附加 token 的網址，沒有設定網址時只返回 token
+func linkWithToken(base, token string) string {
+	link, err := url.Parse(base)
+	if err != nil || base == "" {
+		return token
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestEmailService(t *testing.T) (*EmailService, *memoryUserRepository, *models.User, captureMailer) {
+	t.Helper()
+	users := &memoryUserRepository{}
+	user := &models.User{Username: "alice", Role: models.UserRoleUser}
+	users.Create(user)
+	mailer := make(captureMailer, 4)
+	s := NewEmailService(users, newMemoryTokenRepository(), mailer, config.EmailConfig{VerifyTTL: time.Hour})
+	return s, users, user, mailer
+}
+
+var verifyTokenPattern = regexp.MustCompile(`[A-Za-z0-9_-]{43}`)
+
+func TestVerifyEmail(t *testing.T) {
+	s, users, user, mailer := newTestEmailService(t)
+	users.users[0].Language = "en"
+
+	if err := s.SetEmail(user.ID, " Alice@Example.COM "); err != nil {
+		t.Fatalf("set email: %v", err)
+	}
+	msg := mailer.nextMail(t)
+	if msg.To != "alice@example.com" || msg.Subject != "Verify your email address" {
+		t.Fatalf("unexpected verification mail %+v", msg)
+	}
+	if err := s.CheckVerified(user.ID); err == nil {
+		t.Fatal("unverified email passed the check")
+	}
+
+	token := verifyTokenPattern.FindString(msg.Body)
+	if err := s.Verify(token); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := s.CheckVerified(user.ID); err != nil {
+		t.Errorf("verified email failed the check: %v", err)
+	}
+	if err := s.Verify(token); err == nil {
+		t.Error("verification token was accepted twice")
+	}
+}
+
+func TestChangingEmailInvalidatesVerification(t *testing.T) {
+	s, users, user, mailer := newTestEmailService(t)
+	other := &models.User{Username: "bob"}
+	users.Create(other)
+
+	s.SetEmail(user.ID, "alice@example.com")
+	first := mailer.nextMail(t)
+	if !strings.Contains(first.Body, "您好") {
+		t.Errorf("expected the zh-TW template by default, got %q", first.Body)
+	}
+	s.SetEmail(user.ID, "alice@example.org")
+	mailer.nextMail(t)
+
+	if err := s.Verify(verifyTokenPattern.FindString(first.Body)); err == nil {
+		t.Error("token for the old address verified the new one")
+	}
+	if err := s.SetEmail(other.ID, "ALICE@example.org"); err == nil || err.Error() != "電子郵件已被使用" {
+		t.Errorf("expected duplicate email to be rejected, got %v", err)
+	}
+	if err := s.SetEmail(other.ID, "not an email"); err == nil || err.Error() != "無效的電子郵件地址" {
+		t.Errorf("expected invalid email to be rejected, got %v", err)
+	}
+}
+
+func TestJoinRoomRequiresVerifiedEmail(t *testing.T) {
+	s, users, user, mailer := newTestEmailService(t)
+	ws, rooms, _ := newTestWebSocketService()
+	rooms.rooms[1] = &models.Room{Model: gormModel(1), Status: models.RoomStatusWaiting}
+	roomService := NewRoomService(rooms, &memoryModerationRepository{}, ws)
+	roomService.RequireVerifiedEmail(s)
+
+	if err := roomService.JoinRoom(1, user.ID, "proponent"); err == nil || err.Error() != "請先驗證電子郵件" {
+		t.Fatalf("expected join to require a verified email, got %v", err)
+	}
+
+	s.SetEmail(user.ID, "alice@example.com")
+	s.Verify(verifyTokenPattern.FindString(mailer.nextMail(t).Body))
+	if err := roomService.JoinRoom(1, users.users[0].ID, "proponent"); err != nil {
+		t.Fatalf("join after verification: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"time"
+)
+
+// ChannelPolicy 定義單一頻道的發言、保存和節流規則
+type ChannelPolicy struct {
+	Enabled   bool          // 是否開放此頻道
+	Writers   []string      // 可以在此頻道發言的角色
+	Persist   bool          // 是否將消息寫入資料庫
+	SlowMode  time.Duration // 同一連接兩次發言的最短間隔，0 表示不限制
+	MaxLength int           // 單則消息的最大字數，0 表示不限制
+}
+
+// CanWrite 檢查角色是否可以在此頻道發言
+func (p ChannelPolicy) CanWrite(role string) bool {
+	for _, writer := range p.Writers {
+		if writer == role {
+			return true
+		}
+	}
+	return false
+}
+
+// DebateFormat 描述一種辯論賽制下辯論場和觀眾席的規則
+type DebateFormat struct {
+	Name    string
+	Floor   ChannelPolicy
+	Gallery ChannelPolicy
+}
+
+// Policy 返回指定頻道的規則，未知頻道返回未開放的規則
+func (f *DebateFormat) Policy(channel string) ChannelPolicy {
+	switch channel {
+	case models.ChannelFloor:
+		return f.Floor
+	case models.ChannelGallery:
+		return f.Gallery
+	default:
+		return ChannelPolicy{}
+	}
+}
+
+// DefaultFormat 是房間未指定或指定了未知賽制時使用的賽制名稱
+const DefaultFormat = "standard"
+
+var floorPolicy = ChannelPolicy{
+	Enabled: true,
+	Writers: []string{"proponent", "opponent", "moderator"},
+	Persist: true,
+}
+
+// debateFormats 內建的辯論賽制
+var debateFormats = map[string]*DebateFormat{
+	// 標準賽制：觀眾席開放並保存，限制發言頻率
+	"standard": {
+		Name:  "standard",
+		Floor: floorPolicy,
+		Gallery: ChannelPolicy{
+			Enabled:   true,
+			Writers:   []string{"spectator"},
+			Persist:   true,
+			SlowMode:  3 * time.Second,
+			MaxLength: 200,
+		},
+	},
+	// 比賽賽制：關閉觀眾席，避免場外干擾
+	"tournament": {
+		Name:    "tournament",
+		Floor:   floorPolicy,
+		Gallery: ChannelPolicy{},
+	},
+	// 休閒賽制：觀眾席不保存也不節流
+	"casual": {
+		Name:  "casual",
+		Floor: floorPolicy,
+		Gallery: ChannelPolicy{
+			Enabled:   true,
+			Writers:   []string{"spectator"},
+			MaxLength: 500,
+		},
+	},
+}
+
+// GetDebateFormat 根據名稱查找賽制，找不到時返回預設賽制
+func GetDebateFormat(name string) *DebateFormat {
+	if format, ok := debateFormats[name]; ok {
+		return format
+	}
+	return debateFormats[DefaultFormat]
+}
+
+// IsValidDebateFormat 檢查賽制名稱是否存在
+func IsValidDebateFormat(name string) bool {
+	_, ok := debateFormats[name]
+	return ok
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"debate_web/internal/config"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// minHMACSecretLength HS256 密鑰的最短長度，與簽名的輸出長度相同
+const minHMACSecretLength = 32
+
+// TokenClaims 是 access token 的聲明
+type TokenClaims struct {
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+	jwt.StandardClaims
+}
+
+// JWTKeySet 以設定的金鑰簽發和驗證 JWT
+// 新的 token 一律以簽名金鑰簽發，驗證時依 token 標頭的 kid 選擇金鑰，讓輪替期間舊的 token 仍然有效
+type JWTKeySet struct {
+	issuer  string
+	signing *jwtKey
+	keys    map[string]*jwtKey
+	order   []string // 設定檔中的金鑰順序，JWKS 依此順序輸出
+}
+
+// jwtKey 是單一金鑰，只用於驗證的金鑰沒有 signKey
+type jwtKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewJWTKeySet 載入設定中的所有金鑰，簽名金鑰必須存在且包含私鑰
+// 設定檔不附帶任何密鑰，未設定時啟動失敗，而不是以公開的預設值簽發 token
+func NewJWTKeySet(cfg config.JWTConfig) (*JWTKeySet, error) {
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("no jwt keys are configured")
+	}
+	set := &JWTKeySet{issuer: cfg.Issuer, keys: make(map[string]*jwtKey)}
+	for _, keyCfg := range cfg.Keys {
+		if keyCfg.ID == "" {
+			return nil, errors.New("jwt key id is required")
+		}
+		if set.keys[keyCfg.ID] != nil {
+			return nil, fmt.Errorf("duplicate jwt key id %q", keyCfg.ID)
+		}
+		key, err := loadJWTKey(keyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", keyCfg.ID, err)
+		}
+		set.keys[key.id] = key
+		set.order = append(set.order, key.id)
+	}
+
+	set.signing = set.keys[cfg.SigningKey]
+	if set.signing == nil {
+		return nil, fmt.Errorf("jwt signing key %q is not configured", cfg.SigningKey)
+	}
+	if set.signing.signKey == nil {
+		return nil, fmt.Errorf("jwt signing key %q has no private key", cfg.SigningKey)
+	}
+	return set, nil
+}
+
+func loadJWTKey(cfg config.JWTKeyConfig) (*jwtKey, error) {
+	key := &jwtKey{id: cfg.ID}
+	switch cfg.Algorithm {
+	case "HS256":
+		secret := cfg.Secret
+		if cfg.SecretEnv != "" && os.Getenv(cfg.SecretEnv) != "" {
+			secret = os.Getenv(cfg.SecretEnv)
+		}
+		if secret == "" && cfg.SecretEnv != "" {
+			return nil, fmt.Errorf("HS256 secret is not configured, set %s or secret", cfg.SecretEnv)
+		}
+		if secret == "" {
+			return nil, errors.New("HS256 secret is not configured")
+		}
+		if len(secret) < minHMACSecretLength {
+			return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minHMACSecretLength)
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(secret)
+		key.verifyKey = []byte(secret)
+
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if cfg.PrivateKey != "" {
+			pem, err := os.ReadFile(cfg.PrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey, key.verifyKey = private, &private.PublicKey
+		}
+		if cfg.PublicKey != "" {
+			pem, err := os.ReadFile(cfg.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			if key.verifyKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if cfg.PrivateKey != "" {
+			pem, err := os.ReadFile(cfg.PrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			private, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.signKey, key.verifyKey = private, private.(crypto.Signer).Public()
+		}
+		if cfg.PublicKey != "" {
+			pem, err := os.ReadFile(cfg.PublicKey)
+			if err != nil {
+				return nil, err
+			}
+			if key.verifyKey, err = jwt.ParseEdPublicKeyFromPEM(pem); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+
+	if key.verifyKey == nil {
+		return nil, errors.New("private_key or public_key is required")
+	}
+	return key, nil
+}
+
+// Sign 以簽名金鑰簽發 token，設定了 Issuer 時會寫入 iss
+func (k *JWTKeySet) Sign(claims *TokenClaims) (string, error) {
+	claims.Issuer = k.issuer
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signing.signKey)
+}
+
+// Parse 驗證 token 的簽名、有效期和簽發者，token 的演算法必須與 kid 對應的金鑰一致
+func (k *JWTKeySet) Parse(tokenString string) (*TokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key := k.keys[kid]
+		if key == nil {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %q", token.Method.Alg())
+		}
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*TokenClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if k.issuer != "" && !claims.VerifyIssuer(k.issuer, true) {
+		return nil, errors.New("invalid token issuer")
+	}
+	return claims, nil
+}
+
+// JWK 是 JSON Web Key 中本服務會用到的欄位
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+// JWKS 是公開的金鑰集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 返回所有非對稱金鑰的公鑰，供其他服務驗證本服務簽發的 token
+// HS256 的密鑰不能公開，不會出現在其中
+func (k *JWTKeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range k.order {
+		key := k.keys[id]
+		switch public := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				N:         base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     key.id,
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return jwks
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"debate_web/internal/config"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// writeKeyPair 產生金鑰並將私鑰和公鑰寫成 PEM 檔案
+func writeKeyPair(t *testing.T, algorithm string) (privatePath, publicPath string) {
+	t.Helper()
+	var private, public interface{}
+	switch algorithm {
+	case "RS256":
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, public = key, &key.PublicKey
+	case "EdDSA":
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		private, public = key, pub
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath = filepath.Join(dir, "private.pem")
+	publicPath = filepath.Join(dir, "public.pem")
+	os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644)
+	return privatePath, publicPath
+}
+
+func testClaims(userID uint) *TokenClaims {
+	return &TokenClaims{
+		UserID: userID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        "id",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+	}
+}
+
+func TestJWTKeySetAlgorithms(t *testing.T) {
+	for _, algorithm := range []string{"RS256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			private, _ := writeKeyPair(t, algorithm)
+			keys, err := NewJWTKeySet(config.JWTConfig{
+				Issuer:     "debate_web",
+				SigningKey: "k1",
+				Keys:       []config.JWTKeyConfig{{ID: "k1", Algorithm: algorithm, PrivateKey: private}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := keys.Sign(testClaims(7))
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims, err := keys.Parse(token)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if claims.UserID != 7 || claims.Issuer != "debate_web" {
+				t.Errorf("claims = %+v", claims)
+			}
+
+			jwks := keys.JWKS()
+			if len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "k1" || jwks.Keys[0].Algorithm != algorithm {
+				t.Errorf("jwks = %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestJWTKeySetRotation(t *testing.T) {
+	private, public := writeKeyPair(t, "EdDSA")
+	old := config.JWTKeyConfig{ID: "old", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}
+	before, err := NewJWTKeySet(config.JWTConfig{SigningKey: "old", Keys: []config.JWTKeyConfig{old}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _ := before.Sign(testClaims(1))
+
+	// 新金鑰開始簽發，舊金鑰只保留用於驗證
+	after, err := NewJWTKeySet(config.JWTConfig{
+		SigningKey: "new",
+		Keys: []config.JWTKeyConfig{
+			old,
+			{ID: "new", Algorithm: "EdDSA", PrivateKey: private, PublicKey: public},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := after.Parse(oldToken); err != nil {
+		t.Errorf("token signed with the old key: %v", err)
+	}
+	newToken, _ := after.Sign(testClaims(1))
+	if _, err := before.Parse(newToken); err == nil {
+		t.Error("token with an unknown kid was accepted")
+	}
+	if jwks := after.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].KeyID != "new" {
+		t.Errorf("jwks = %+v, want only the public key", jwks)
+	}
+}
+
+func TestJWTKeySetRejectsAlgorithmMismatch(t *testing.T) {
+	keys, _ := NewJWTKeySet(config.JWTConfig{
+		SigningKey: "k1",
+		Keys:       []config.JWTKeyConfig{{ID: "k1", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}},
+	})
+
+	// 以 none 演算法偽造的 token
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, testClaims(1))
+	token.Header["kid"] = "k1"
+	forged, _ := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := keys.Parse(forged); err == nil {
+		t.Error("token signed with none was accepted")
+	}
+}
+
+func TestJWTKeySetConfigErrors(t *testing.T) {
+	_, public := writeKeyPair(t, "RS256")
+	cases := map[string]config.JWTConfig{
+		"no keys":        {SigningKey: "k"},
+		"empty secret":   {SigningKey: "k", Keys: []config.JWTKeyConfig{{ID: "k", Algorithm: "HS256", SecretEnv: "DEBATE_WEB_TEST_UNSET_SECRET"}}},
+		"short secret":   {SigningKey: "k", Keys: []config.JWTKeyConfig{{ID: "k", Algorithm: "HS256", Secret: "short"}}},
+		"missing signer": {SigningKey: "x", Keys: []config.JWTKeyConfig{{ID: "k", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}},
+		"verify only":    {SigningKey: "k", Keys: []config.JWTKeyConfig{{ID: "k", Algorithm: "RS256", PublicKey: public}}},
+		"unknown alg":    {SigningKey: "k", Keys: []config.JWTKeyConfig{{ID: "k", Algorithm: "ES256"}}},
+		"duplicate kid":  {SigningKey: "k", Keys: []config.JWTKeyConfig{{ID: "k", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}, {ID: "k", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}}},
+	}
+	for name, cfg := range cases {
+		if _, err := NewJWTKeySet(cfg); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
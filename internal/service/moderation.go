@@ -0,0 +1,291 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ModerationAction 是審核過濾器對消息的處理結果，數值越大越嚴格
+type ModerationAction int
+
+const (
+	ModerationAllow  ModerationAction = iota // 照常發送
+	ModerationMask                           // 以修改後的內容發送
+	ModerationHold                           // 暫緩發送，等待主持人審核
+	ModerationReject                         // 拒絕發送並通知發送者
+)
+
+// ModerationVerdict 是單一過濾器或整個審核流程的結果
+type ModerationVerdict struct {
+	Action  ModerationAction
+	Content string // Mask 或 Hold 時替換後的內容，Hold 時空值表示不修改
+	Reason  string // Hold 或 Reject 的原因，會顯示給發送者
+}
+
+// ModerationFilter 檢查一則即將發送的消息
+// 過濾器不應修改 msg，需要遮蔽內容時以 Mask 返回新的內容
+type ModerationFilter interface {
+	Moderate(client *Client, msg *models.Message) ModerationVerdict
+}
+
+// ModerationFilterFunc 讓普通函數可以作為過濾器使用
+type ModerationFilterFunc func(client *Client, msg *models.Message) ModerationVerdict
+
+func (f ModerationFilterFunc) Moderate(client *Client, msg *models.Message) ModerationVerdict {
+	return f(client, msg)
+}
+
+// ModerationPipeline 依序執行審核過濾器
+// 遮蔽的內容會交給後續的過濾器繼續檢查，遇到拒絕時立即結束，暫緩則保留到最後
+type ModerationPipeline struct {
+	filters []ModerationFilter
+}
+
+// NewModerationPipeline 創建審核流程，沒有過濾器時所有消息都照常發送
+func NewModerationPipeline(filters ...ModerationFilter) *ModerationPipeline {
+	return &ModerationPipeline{filters: filters}
+}
+
+// Use 在流程最後加入過濾器，必須在開始接受連接之前呼叫
+func (p *ModerationPipeline) Use(filter ModerationFilter) {
+	p.filters = append(p.filters, filter)
+}
+
+// Run 以所有過濾器檢查消息，返回最嚴格的結果，Content 為最終要發送的內容
+func (p *ModerationPipeline) Run(client *Client, msg *models.Message) ModerationVerdict {
+	result := ModerationVerdict{Action: ModerationAllow, Content: msg.Content}
+	checked := *msg
+	for _, filter := range p.filters {
+		verdict := filter.Moderate(client, &checked)
+		switch verdict.Action {
+		case ModerationReject:
+			verdict.Content = result.Content
+			return verdict
+		case ModerationHold:
+			if verdict.Content != "" {
+				checked.Content = verdict.Content
+				result.Content = verdict.Content
+			}
+			if result.Action < ModerationHold {
+				result.Action, result.Reason = ModerationHold, verdict.Reason
+			}
+		case ModerationMask:
+			checked.Content = verdict.Content
+			result.Content = verdict.Content
+			if result.Action < ModerationMask {
+				result.Action = ModerationMask
+			}
+		}
+	}
+	return result
+}
+
+// NewConfiguredModeration 根據設定建立包含內建過濾器的審核流程
+func NewConfiguredModeration(cfg config.ModerationConfig) *ModerationPipeline {
+	return NewModerationPipeline(
+		NewLengthFilter(cfg.MaxLength),
+		NewRepeatFilter(cfg.MaxRepeat),
+		NewWordFilter(cfg.Words.Mask, cfg.Words.Hold, cfg.Words.Reject),
+		NewURLFilter(cfg.URLs.Allow, cfg.URLs.Deny),
+	)
+}
+
+// lengthFilter 限制各頻道的消息長度，賽制本身也有上限時取較小者
+type lengthFilter struct {
+	limits map[string]int
+}
+
+func NewLengthFilter(limits map[string]int) ModerationFilter {
+	return &lengthFilter{limits: limits}
+}
+
+func (f *lengthFilter) Moderate(client *Client, msg *models.Message) ModerationVerdict {
+	limit := f.limits[msg.Channel]
+	if client.Format != nil {
+		if formatLimit := client.Format.Policy(msg.Channel).MaxLength; formatLimit > 0 && (limit <= 0 || formatLimit < limit) {
+			limit = formatLimit
+		}
+	}
+	if limit > 0 && utf8.RuneCountInString(msg.Content) > limit {
+		return ModerationVerdict{Action: ModerationReject, Reason: fmt.Sprintf("消息長度不能超過 %d 字", limit)}
+	}
+	return ModerationVerdict{}
+}
+
+// repeatFilter 拒絕同一字元連續出現過多次的洗版消息，空白不計算在內
+type repeatFilter struct {
+	max int
+}
+
+func NewRepeatFilter(max int) ModerationFilter {
+	return &repeatFilter{max: max}
+}
+
+func (f *repeatFilter) Moderate(client *Client, msg *models.Message) ModerationVerdict {
+	if f.max <= 0 {
+		return ModerationVerdict{}
+	}
+	var last rune
+	run := 0
+	for _, r := range msg.Content {
+		if r == last && !unicode.IsSpace(r) {
+			run++
+		} else {
+			last, run = r, 1
+		}
+		if run > f.max {
+			return ModerationVerdict{Action: ModerationReject, Reason: "請勿重複輸入相同字元洗版"}
+		}
+	}
+	return ModerationVerdict{}
+}
+
+// wordFilter 依敏感詞列表遮蔽、暫緩或拒絕消息
+// 英文詞不分大小寫且只比對完整單字，中文詞直接比對字串
+type wordFilter struct {
+	mask, hold, reject [][]rune
+}
+
+func NewWordFilter(mask, hold, reject []string) ModerationFilter {
+	return &wordFilter{
+		mask:   normalizeWords(mask),
+		hold:   normalizeWords(hold),
+		reject: normalizeWords(reject),
+	}
+}
+
+func normalizeWords(words []string) [][]rune {
+	var normalized [][]rune
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			normalized = append(normalized, []rune(word))
+		}
+	}
+	return normalized
+}
+
+func (f *wordFilter) Moderate(client *Client, msg *models.Message) ModerationVerdict {
+	runes := []rune(msg.Content)
+	lower := []rune(strings.ToLower(msg.Content))
+	if len(lower) != len(runes) {
+		lower = runes
+	}
+
+	for _, word := range f.reject {
+		if len(findWord(lower, word)) > 0 {
+			return ModerationVerdict{Action: ModerationReject, Reason: "消息包含不允許的用語"}
+		}
+	}
+
+	// 先遮蔽再判斷是否暫緩，審核通過後發布的是遮蔽後的內容
+	verdict := ModerationVerdict{}
+	for _, word := range f.mask {
+		for _, start := range findWord(lower, word) {
+			for i := start; i < start+len(word); i++ {
+				runes[i] = '*'
+			}
+			verdict = ModerationVerdict{Action: ModerationMask, Content: string(runes)}
+		}
+	}
+	for _, word := range f.hold {
+		if len(findWord(lower, word)) > 0 {
+			verdict.Action, verdict.Reason = ModerationHold, "消息包含需要審核的用語"
+			break
+		}
+	}
+	return verdict
+}
+
+// findWord 返回 word 在 text 中所有出現位置，英文字母和數字組成的詞必須是完整單字
+func findWord(text, word []rune) []int {
+	whole := isASCIIWord(word)
+	var found []int
+	for i := 0; i+len(word) <= len(text); i++ {
+		if string(text[i:i+len(word)]) != string(word) {
+			continue
+		}
+		if whole && ((i > 0 && isWordRune(text[i-1])) || (i+len(word) < len(text) && isWordRune(text[i+len(word)]))) {
+			continue
+		}
+		found = append(found, i)
+	}
+	return found
+}
+
+func isASCIIWord(word []rune) bool {
+	for _, r := range word {
+		if r >= utf8.RuneSelf || !isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// urlPattern 比對消息中的連結，包含省略協定的 www. 開頭網址
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"'，。）)]+`)
+
+// urlFilter 依網域名單處理消息中的連結
+type urlFilter struct {
+	allow, deny []string
+}
+
+func NewURLFilter(allow, deny []string) ModerationFilter {
+	return &urlFilter{allow: normalizeDomains(allow), deny: normalizeDomains(deny)}
+}
+
+func normalizeDomains(domains []string) []string {
+	var normalized []string
+	for _, domain := range domains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+func (f *urlFilter) Moderate(client *Client, msg *models.Message) ModerationVerdict {
+	if len(f.allow) == 0 && len(f.deny) == 0 {
+		return ModerationVerdict{}
+	}
+
+	verdict := ModerationVerdict{}
+	for _, link := range urlPattern.FindAllString(msg.Content, -1) {
+		if !strings.Contains(link, "://") {
+			link = "http://" + link
+		}
+		u, err := url.Parse(link)
+		if err != nil || u.Hostname() == "" {
+			return ModerationVerdict{Action: ModerationReject, Reason: "消息包含無效的連結"}
+		}
+		host := strings.ToLower(u.Hostname())
+		if matchDomain(host, f.deny) {
+			return ModerationVerdict{Action: ModerationReject, Reason: "消息包含不允許的連結"}
+		}
+		if len(f.allow) > 0 && !matchDomain(host, f.allow) {
+			verdict = ModerationVerdict{Action: ModerationHold, Reason: "消息包含需要審核的連結"}
+		}
+	}
+	return verdict
+}
+
+// matchDomain 檢查 host 是否為名單中的網域或其子網域
+func matchDomain(host string, domains []string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
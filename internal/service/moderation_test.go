@@ -0,0 +1,96 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"testing"
+)
+
+func TestModerationFilters(t *testing.T) {
+	client := &Client{Format: GetDebateFormat(DefaultFormat)}
+	pipeline := NewModerationPipeline(
+		NewLengthFilter(map[string]int{models.ChannelGallery: 10}),
+		NewRepeatFilter(4),
+		NewWordFilter([]string{"笨蛋", "Idiot"}, []string{"作弊"}, []string{"去死"}),
+		NewURLFilter([]string{"example.org"}, []string{"spam.com"}),
+	)
+
+	tests := []struct {
+		name    string
+		channel string
+		content string
+		action  ModerationAction
+		result  string
+	}{
+		{"allow", models.ChannelFloor, "我方認為應該廢除死刑", ModerationAllow, "我方認為應該廢除死刑"},
+		{"mask chinese", models.ChannelFloor, "你這個笨蛋", ModerationMask, "你這個**"},
+		{"mask english case insensitive", models.ChannelFloor, "what an IDIOT.", ModerationMask, "what an *****."},
+		{"english whole word only", models.ChannelFloor, "idiots and idiotic", ModerationAllow, "idiots and idiotic"},
+		{"hold word", models.ChannelFloor, "對方作弊", ModerationHold, "對方作弊"},
+		{"reject word", models.ChannelFloor, "你去死", ModerationReject, "你去死"},
+		{"repeat", models.ChannelFloor, "好好好好好", ModerationReject, "好好好好好"},
+		{"repeated spaces allowed", models.ChannelFloor, "a      b", ModerationAllow, "a      b"},
+		{"gallery length", models.ChannelGallery, "一二三四五六七八九十一", ModerationReject, "一二三四五六七八九十一"},
+		{"allowed link", models.ChannelFloor, "見 https://docs.example.org/a", ModerationAllow, "見 https://docs.example.org/a"},
+		{"unlisted link held", models.ChannelFloor, "見 www.other.net/x", ModerationHold, "見 www.other.net/x"},
+		{"denied link", models.ChannelFloor, "見 http://SPAM.com", ModerationReject, "見 http://SPAM.com"},
+		{"mask then hold", models.ChannelFloor, "笨蛋作弊", ModerationHold, "**作弊"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &models.Message{Channel: tt.channel, Content: tt.content}
+			verdict := pipeline.Run(client, msg)
+			if verdict.Action != tt.action {
+				t.Fatalf("action = %d, want %d (reason %q)", verdict.Action, tt.action, verdict.Reason)
+			}
+			if verdict.Content != tt.result {
+				t.Errorf("content = %q, want %q", verdict.Content, tt.result)
+			}
+			if msg.Content != tt.content {
+				t.Errorf("pipeline modified the original message")
+			}
+		})
+	}
+}
+
+func TestHeldMessageIsNotBroadcast(t *testing.T) {
+	s, _, messages := newTestWebSocketService()
+	s.UseModeration(NewModerationPipeline(NewWordFilter(nil, []string{"作弊"}, nil)))
+
+	sender := newTestClient(1, 1, 8)
+	sender.Role = "proponent"
+	other := newTestClient(1, 2, 8)
+	other.Role = "opponent"
+	s.addClient(sender)
+	s.addClient(other)
+	defer s.removeClient(sender)
+	defer s.removeClient(other)
+
+	s.handleMessage(sender, &models.Message{Content: "對方作弊"})
+	s.handleMessage(sender, &models.Message{Content: "下一點"})
+
+	if got := nextNonSystem(sender).Type; got != "message_held" {
+		t.Fatalf("sender got %q, want message_held", got)
+	}
+	if got := nextNonSystem(other).Content; got != "下一點" {
+		t.Fatalf("other client got %q, want only the published message", got)
+	}
+
+	published, _ := messages.FindByRoom(1)
+	if len(published) != 1 {
+		t.Fatalf("published messages = %d, want 1", len(published))
+	}
+	held, _ := messages.FindByID(1)
+	if held.Status != models.MessageStatusHeld {
+		t.Errorf("held message status = %q", held.Status)
+	}
+}
+
+// nextNonSystem 讀取客戶端下一則非系統通知的消息
+func nextNonSystem(client *Client) *models.Message {
+	for msg := range client.SendChan {
+		if msg.Type != "system" {
+			return msg
+		}
+	}
+	return nil
+}
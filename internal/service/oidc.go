@@ -0,0 +1,357 @@
+package service
+
+import (
+	"context"
+	"debate_web/internal/config"
+	"debate_web/internal/oidc"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOIDCStateTTL      = 10 * time.Minute
+	defaultOIDCUsernameClaim = "preferred_username"
+	oidcPruneInterval        = time.Hour
+
+	// 自動建立的用戶名長度，與註冊時的限制相同
+	minUsernameLength = 3
+	maxUsernameLength = 32
+	// maxUsernameAttempts 是用戶名已被使用時加上數字後綴嘗試的次數
+	maxUsernameAttempts = 100
+)
+
+// OIDCService 處理以外部 OpenID Connect 提供者登入，以及提供者帳號和本地用戶的連結
+// 登入使用授權碼流程和 PKCE，state、nonce 和 code verifier 保存在資料庫中，由提供者導回後使用一次即刪除
+type OIDCService struct {
+	provider *oidc.Provider // 未啟用時為 nil
+	repo     repository.IdentityRepository
+	userRepo repository.UserRepository
+	users    *UserService
+	cfg      config.OIDCConfig
+}
+
+// NewOIDCService 創建 OIDC 登入服務，啟用時開始定期清除過期的登入狀態
+// 提供者的 discovery 文件在第一次登入時才取得，提供者暫時無法連線不影響服務啟動
+func NewOIDCService(repo repository.IdentityRepository, userRepo repository.UserRepository, users *UserService, cfg config.OIDCConfig) *OIDCService {
+	if cfg.StateTTL <= 0 {
+		cfg.StateTTL = defaultOIDCStateTTL
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = defaultOIDCUsernameClaim
+	}
+	s := &OIDCService{repo: repo, userRepo: userRepo, users: users, cfg: cfg}
+	if cfg.Enabled {
+		s.provider = oidc.NewProvider(oidc.Config{
+			Issuer:       cfg.Issuer,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		})
+		go s.run(oidcPruneInterval)
+	}
+	return s
+}
+
+// OIDCAuthorization 是發起登入後返回給前端的資料
+// 前端應保存 State，提供者導回時確認網址中的 state 與其相同，再將授權碼送回本服務
+type OIDCAuthorization struct {
+	URL       string `json:"authorization_url"`
+	State     string `json:"state"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// OIDCProvider 是前端顯示登入按鈕需要的資料
+type OIDCProvider struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`
+}
+
+// Provider 返回是否啟用 OIDC 登入及提供者的名稱
+func (s *OIDCService) Provider() OIDCProvider {
+	if s.provider == nil {
+		return OIDCProvider{}
+	}
+	return OIDCProvider{Enabled: true, Name: s.cfg.Name}
+}
+
+// BeginLogin 發起登入，返回提供者的授權網址
+func (s *OIDCService) BeginLogin(ctx context.Context) (*OIDCAuthorization, error) {
+	return s.begin(ctx, 0)
+}
+
+// BeginLink 為已登入的用戶發起連結，完成後提供者帳號可以用來登入該用戶
+func (s *OIDCService) BeginLink(ctx context.Context, userID uint) (*OIDCAuthorization, error) {
+	return s.begin(ctx, userID)
+}
+
+// CompleteLogin 以提供者導回的授權碼完成登入，返回對應的本地用戶
+// 提供者帳號尚未連結時，依設定以已驗證的電子郵件連結到現有用戶，或自動建立新用戶
+func (s *OIDCService) CompleteLogin(ctx context.Context, code, state string) (*models.User, error) {
+	claims, err := s.complete(ctx, code, state, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolve(claims)
+	if err != nil {
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.New("帳號已被停用")
+	}
+	return user, nil
+}
+
+// CompleteLink 以提供者導回的授權碼將提供者帳號連結到目前的用戶
+func (s *OIDCService) CompleteLink(ctx context.Context, userID uint, code, state string) (*models.UserIdentity, error) {
+	claims, err := s.complete(ctx, code, state, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.repo.FindIdentity(claims.Issuer, claims.Subject)
+	switch {
+	case err == nil && identity.UserID == userID:
+		return identity, nil
+	case err == nil:
+		return nil, errors.New("此提供者帳號已連結到其他用戶")
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+	return s.link(userID, claims)
+}
+
+// ListIdentities 返回用戶已連結的提供者帳號
+func (s *OIDCService) ListIdentities(userID uint) ([]models.UserIdentity, error) {
+	return s.repo.ListIdentities(userID)
+}
+
+// begin 保存登入狀態並產生授權網址，userID 不為零時表示連結帳號
+func (s *OIDCService) begin(ctx context.Context, userID uint) (*OIDCAuthorization, error) {
+	if s.provider == nil {
+		return nil, errors.New("未啟用 OIDC 登入")
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	// 32 個位元組的 base64url 編碼是 43 個字元，符合 PKCE 對 code verifier 的長度要求
+	verifier, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	url, err := s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("oidc discovery error: %v", err)
+		return nil, errors.New("無法連線到身分提供者")
+	}
+	err = s.repo.CreateState(&models.OIDCState{
+		StateHash: hashToken(state),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.cfg.StateTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCAuthorization{URL: url, State: state, ExpiresIn: int(s.cfg.StateTTL.Seconds())}, nil
+}
+
+// complete 取出登入狀態並以授權碼換取驗證過的 ID token 聲明
+// 登入和連結的狀態不能互換，避免連結流程的授權碼被用來登入，或反之
+func (s *OIDCService) complete(ctx context.Context, code, state string, userID uint) (*oidc.Claims, error) {
+	if s.provider == nil {
+		return nil, errors.New("未啟用 OIDC 登入")
+	}
+
+	saved, err := s.repo.TakeState(hashToken(state))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("無效或已過期的登入狀態")
+		}
+		return nil, err
+	}
+	if saved.UserID != userID {
+		return nil, errors.New("無效或已過期的登入狀態")
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, saved.Verifier, saved.Nonce)
+	if err != nil {
+		log.Printf("oidc exchange error: %v", err)
+		return nil, errors.New("OIDC 登入失敗")
+	}
+	return claims, nil
+}
+
+// resolve 找出提供者帳號對應的本地用戶，必要時連結或建立用戶
+func (s *OIDCService) resolve(claims *oidc.Claims) (*models.User, error) {
+	identity, err := s.repo.FindIdentity(claims.Issuer, claims.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, errors.New("用戶不存在")
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// 雙方都驗證過同一個地址時，才視為同一個人
+	if s.cfg.LinkVerifiedEmail && claims.EmailVerified {
+		if email, err := normalizeEmail(claims.Email); err == nil {
+			user, err := s.userRepo.FindByEmail(email)
+			switch {
+			case err == nil && user.VerifiedEmail() == email:
+				if _, err := s.link(user.ID, claims); err != nil {
+					return nil, err
+				}
+				return user, nil
+			case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+				return nil, err
+			}
+		}
+	}
+
+	if !s.cfg.AutoProvision {
+		return nil, errors.New("此提供者帳號尚未連結，請先以密碼登入後連結")
+	}
+	return s.provision(claims)
+}
+
+// link 建立提供者帳號和用戶的連結
+func (s *OIDCService) link(userID uint, claims *oidc.Claims) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{
+		UserID:  userID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	}
+	if err := s.repo.CreateIdentity(identity); err != nil {
+		return nil, err
+	}
+	log.Printf("user %d linked oidc subject %s", userID, claims.Subject)
+	return identity, nil
+}
+
+// provision 為提供者帳號建立新的用戶並連結
+// 用戶沒有可用的密碼，之後可以透過已驗證的電子郵件重設密碼
+func (s *OIDCService) provision(claims *oidc.Claims) (*models.User, error) {
+	password, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{Password: string(hash), Role: models.UserRoleUser}
+	if name := strings.TrimSpace(claims.Name); utf8.RuneCountInString(name) <= maxDisplayNameLength && strings.IndexFunc(name, unicode.IsControl) < 0 {
+		user.DisplayName = name
+	}
+	if email, err := normalizeEmail(claims.Email); err == nil {
+		if _, err := s.userRepo.FindByEmail(email); errors.Is(err, gorm.ErrRecordNotFound) {
+			user.Email = &email
+			if claims.EmailVerified {
+				now := time.Now()
+				user.EmailVerifiedAt = &now
+			}
+		}
+	}
+
+	base := usernameBase(s.usernameClaim(claims))
+	for i := 1; i <= maxUsernameAttempts; i++ {
+		user.Username = usernameCandidate(base, i)
+		err = s.users.CreateUser(user)
+		if err == nil {
+			break
+		}
+		if err.Error() != "用戶名已被使用" {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("user %d (%s) provisioned from oidc subject %s", user.ID, user.Username, claims.Subject)
+	if _, err := s.link(user.ID, claims); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// usernameClaim 返回設定的用戶名聲明，沒有時依序改用 preferred_username 和電子郵件的用戶部分
+func (s *OIDCService) usernameClaim(claims *oidc.Claims) string {
+	if value, ok := claims.Raw[s.cfg.UsernameClaim].(string); ok && value != "" {
+		return value
+	}
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername
+	}
+	if at := strings.Index(claims.Email, "@"); at > 0 {
+		return claims.Email[:at]
+	}
+	return ""
+}
+
+// usernameBase 將聲明轉為可用的用戶名，只保留字母、數字和 . _ -
+func usernameBase(value string) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '.', r == '_', r == '-':
+			return r
+		case unicode.IsSpace(r):
+			return '_'
+		}
+		return -1
+	}, strings.TrimSpace(value))
+	if utf8.RuneCountInString(base) < minUsernameLength {
+		return "user"
+	}
+	return base
+}
+
+// usernameCandidate 返回第 n 次嘗試的用戶名，從第二次開始加上數字後綴並保持在長度限制內
+func usernameCandidate(base string, n int) string {
+	suffix := ""
+	if n > 1 {
+		suffix = fmt.Sprint(n)
+	}
+	runes := []rune(base)
+	if limit := maxUsernameLength - len(suffix); len(runes) > limit {
+		runes = runes[:limit]
+	}
+	return string(runes) + suffix
+}
+
+// run 定期清除過期的登入狀態
+func (s *OIDCService) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := s.repo.PruneStates(now); err != nil {
+			log.Printf("prune oidc states error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"debate_web/internal/config"
+	"debate_web/internal/oidc/oidctest"
+	"debate_web/internal/repository/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryIdentityRepository 是只保存在記憶體中的 IdentityRepository
+type memoryIdentityRepository struct {
+	mu         sync.Mutex
+	states     []*models.OIDCState
+	identities []*models.UserIdentity
+}
+
+func (r *memoryIdentityRepository) CreateState(state *models.OIDCState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+	return nil
+}
+
+func (r *memoryIdentityRepository) TakeState(hash string) (*models.OIDCState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, state := range r.states {
+		if state.StateHash == hash && time.Now().Before(state.ExpiresAt) {
+			r.states = append(r.states[:i], r.states[i+1:]...)
+			return state, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryIdentityRepository) PruneStates(now time.Time) error {
+	return nil
+}
+
+func (r *memoryIdentityRepository) FindIdentity(issuer, subject string) (*models.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryIdentityRepository) ListIdentities(userID uint) ([]models.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var identities []models.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			identities = append(identities, *identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *memoryIdentityRepository) CreateIdentity(identity *models.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+const testOIDCRedirect = "http://localhost/oidc/callback"
+
+func newTestOIDCService(t *testing.T, cfg config.OIDCConfig) (*OIDCService, *oidctest.Server, *memoryUserRepository) {
+	t.Helper()
+	server := oidctest.NewServer("debate", "secret", testOIDCRedirect)
+	t.Cleanup(server.Close)
+
+	cfg.Enabled = true
+	cfg.Issuer, cfg.ClientID, cfg.ClientSecret, cfg.RedirectURL = server.Issuer, "debate", "secret", testOIDCRedirect
+	users := &memoryUserRepository{}
+	s := NewOIDCService(&memoryIdentityRepository{}, users, NewUserService(users, config.LockoutConfig{}), cfg)
+	return s, server, users
+}
+
+// oidcAuthorize 發起流程並在測試提供者上同意授權，返回授權碼和 state
+func oidcAuthorize(t *testing.T, server *oidctest.Server, begin func(context.Context) (*OIDCAuthorization, error)) (string, string) {
+	t.Helper()
+	authorization, err := begin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state, err := server.Authorize(authorization.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != authorization.State {
+		t.Fatalf("provider returned state %q, want %q", state, authorization.State)
+	}
+	return code, state
+}
+
+func TestOIDCProvisionsUser(t *testing.T) {
+	s, server, users := newTestOIDCService(t, config.OIDCConfig{AutoProvision: true})
+	users.Create(&models.User{Username: "test.user"})
+
+	code, state := oidcAuthorize(t, server, s.BeginLogin)
+	user, err := s.CompleteLogin(context.Background(), code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "test.user2" || user.DisplayName != "Test User" || user.VerifiedEmail() != "user@example.edu" || user.Role != models.UserRoleUser {
+		t.Errorf("provisioned user = %+v", user)
+	}
+
+	// 同一個 state 不能再次使用
+	if _, err := s.CompleteLogin(context.Background(), code, state); err == nil || err.Error() != "無效或已過期的登入狀態" {
+		t.Errorf("reused state: err = %v", err)
+	}
+
+	// 之後的登入找到同一個用戶
+	code, state = oidcAuthorize(t, server, s.BeginLogin)
+	again, err := s.CompleteLogin(context.Background(), code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != user.ID || len(users.users) != 2 {
+		t.Errorf("second login returned user %d, %d users exist", again.ID, len(users.users))
+	}
+}
+
+func TestOIDCLinksVerifiedEmail(t *testing.T) {
+	s, server, users := newTestOIDCService(t, config.OIDCConfig{})
+	email, verifiedAt := "user@example.edu", time.Now()
+	users.Create(&models.User{Username: "alice", Email: &email})
+
+	// 本地的地址未驗證時不連結，也不自動建立用戶
+	code, state := oidcAuthorize(t, server, s.BeginLogin)
+	if _, err := s.CompleteLogin(context.Background(), code, state); err == nil || !strings.HasPrefix(err.Error(), "此提供者帳號尚未連結") {
+		t.Fatalf("unlinked login: err = %v", err)
+	}
+
+	s.cfg.LinkVerifiedEmail = true
+	code, state = oidcAuthorize(t, server, s.BeginLogin)
+	if _, err := s.CompleteLogin(context.Background(), code, state); err == nil {
+		t.Fatal("linked an unverified local email")
+	}
+
+	users.users[0].EmailVerifiedAt = &verifiedAt
+	code, state = oidcAuthorize(t, server, s.BeginLogin)
+	user, err := s.CompleteLogin(context.Background(), code, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alice" {
+		t.Errorf("logged in as %q, want alice", user.Username)
+	}
+}
+
+func TestOIDCLinkAccount(t *testing.T) {
+	s, server, users := newTestOIDCService(t, config.OIDCConfig{})
+	alice := &models.User{Username: "alice"}
+	bob := &models.User{Username: "bob"}
+	users.Create(alice)
+	users.Create(bob)
+
+	beginLink := func(userID uint) func(context.Context) (*OIDCAuthorization, error) {
+		return func(ctx context.Context) (*OIDCAuthorization, error) { return s.BeginLink(ctx, userID) }
+	}
+
+	// 連結流程的授權碼不能用來登入
+	code, state := oidcAuthorize(t, server, beginLink(alice.ID))
+	if _, err := s.CompleteLogin(context.Background(), code, state); err == nil || err.Error() != "無效或已過期的登入狀態" {
+		t.Fatalf("login with link state: err = %v", err)
+	}
+
+	// 也不能由其他用戶完成
+	code, state = oidcAuthorize(t, server, beginLink(alice.ID))
+	if _, err := s.CompleteLink(context.Background(), bob.ID, code, state); err == nil {
+		t.Fatal("another user completed the link")
+	}
+
+	code, state = oidcAuthorize(t, server, beginLink(alice.ID))
+	if _, err := s.CompleteLink(context.Background(), alice.ID, code, state); err != nil {
+		t.Fatal(err)
+	}
+	code, state = oidcAuthorize(t, server, s.BeginLogin)
+	user, err := s.CompleteLogin(context.Background(), code, state)
+	if err != nil || user.ID != alice.ID {
+		t.Fatalf("login after linking: user = %v, err = %v", user, err)
+	}
+
+	code, state = oidcAuthorize(t, server, beginLink(bob.ID))
+	if _, err := s.CompleteLink(context.Background(), bob.ID, code, state); err == nil || err.Error() != "此提供者帳號已連結到其他用戶" {
+		t.Errorf("link identity of another user: err = %v", err)
+	}
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	s := NewOIDCService(&memoryIdentityRepository{}, &memoryUserRepository{}, nil, config.OIDCConfig{})
+	if s.Provider().Enabled {
+		t.Error("provider is enabled")
+	}
+	if _, err := s.BeginLogin(context.Background()); err == nil || err.Error() != "未啟用 OIDC 登入" {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestUsernameCandidate(t *testing.T) {
+	tests := []struct {
+		claim string
+		n     int
+		want  string
+	}{
+		{"test.user", 1, "test.user"},
+		{"test.user", 3, "test.user3"},
+		{"王 小明", 1, "王_小明"},
+		{"a!", 1, "user"},
+		{strings.Repeat("x", 40), 12, strings.Repeat("x", 30) + "12"},
+	}
+	for _, tt := range tests {
+		if got := usernameCandidate(usernameBase(tt.claim), tt.n); got != tt.want {
+			t.Errorf("usernameCandidate(%q, %d) = %q, want %q", tt.claim, tt.n, got, tt.want)
+		}
+	}
+}
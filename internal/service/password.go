@@ -0,0 +1,253 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/mail"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 密碼規則的預設值
+const (
+	defaultPasswordMinLength = 8
+	bcryptMaxPasswordLength  = 72 // bcrypt 只使用前 72 個位元組
+	defaultPasswordResetTTL  = 30 * time.Minute
+)
+
+// PasswordPolicy 檢查新密碼是否符合設定的規則
+type PasswordPolicy struct {
+	cfg config.PasswordConfig
+}
+
+func NewPasswordPolicy(cfg config.PasswordConfig) *PasswordPolicy {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = defaultPasswordMinLength
+	}
+	if cfg.MaxLength <= 0 || cfg.MaxLength > bcryptMaxPasswordLength {
+		cfg.MaxLength = bcryptMaxPasswordLength
+	}
+	return &PasswordPolicy{cfg: cfg}
+}
+
+// Validate 返回密碼第一個不符合的規則
+func (p *PasswordPolicy) Validate(username, password string) error {
+	if len([]rune(password)) < p.cfg.MinLength {
+		return policyError("密碼至少需要 %d 個字元", p.cfg.MinLength)
+	}
+	if len(password) > p.cfg.MaxLength {
+		return policyError("密碼不能超過 %d 個位元組", p.cfg.MaxLength)
+	}
+
+	var letter, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			letter = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.cfg.RequireLetter && !letter {
+		return policyError("密碼需要包含字母")
+	}
+	if p.cfg.RequireDigit && !digit {
+		return policyError("密碼需要包含數字")
+	}
+	if p.cfg.RequireSymbol && !symbol {
+		return policyError("密碼需要包含符號")
+	}
+	if p.cfg.RejectUsername && username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return policyError("密碼不能包含用戶名")
+	}
+	return nil
+}
+
+// PasswordPolicyError 表示新密碼不符合密碼規則
+type PasswordPolicyError struct {
+	Reason string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return e.Reason
+}
+
+func policyError(format string, args ...interface{}) error {
+	return &PasswordPolicyError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// PasswordService 處理修改密碼和忘記密碼的流程
+type PasswordService struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.TokenRepository
+	tokens    *TokenService
+	mailer    mail.Mailer
+	policy    *PasswordPolicy
+	cfg       config.PasswordConfig
+}
+
+func NewPasswordService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, tokens *TokenService, mailer mail.Mailer, policy *PasswordPolicy, cfg config.PasswordConfig) *PasswordService {
+	if cfg.ResetTTL <= 0 {
+		cfg.ResetTTL = defaultPasswordResetTTL
+	}
+	return &PasswordService{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		tokens:    tokens,
+		mailer:    mailer,
+		policy:    policy,
+		cfg:       cfg,
+	}
+}
+
+// Validate 檢查新密碼是否符合密碼規則
+func (s *PasswordService) Validate(username, password string) error {
+	return s.policy.Validate(username, password)
+}
+
+// Change 驗證目前的密碼後設定新密碼，並登出其他裝置，目前的登入保持有效
+func (s *PasswordService) Change(userID uint, accessID, current, password string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)); err != nil {
+		return errors.New("目前的密碼不正確")
+	}
+	if current == password {
+		return errors.New("新密碼不能與目前的密碼相同")
+	}
+	if err := s.policy.Validate(user.Username, password); err != nil {
+		return err
+	}
+
+	if err := s.setPassword(user.ID, password); err != nil {
+		return err
+	}
+	if err := s.tokens.RevokeOthers(user.ID, accessID); err != nil {
+		return err
+	}
+
+	log.Printf("user %d changed their password", user.ID)
+	return nil
+}
+
+// RequestReset 為用戶建立重設密碼的 token 並寄到其已驗證的電子郵件，之前寄出的連結隨即失效
+// login 可以是用戶名或電子郵件地址
+// 無論用戶是否存在都不返回錯誤，避免從回應推測用戶名是否存在
+func (s *PasswordService) RequestReset(login string) error {
+	user, err := s.findUser(login)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.DisabledAt != nil {
+		return nil
+	}
+	// 未驗證的地址可能填錯，不能用來取回帳號
+	if user.VerifiedEmail() == "" {
+		log.Printf("user %d requested a password reset without a verified email", user.ID)
+		return nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	if err := s.tokenRepo.InvalidateUserTokens(user.ID, models.UserTokenPasswordReset); err != nil {
+		return err
+	}
+	err = s.tokenRepo.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenPasswordReset,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.ResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	sendMail(s.mailer, user, user.VerifiedEmail(), mailTemplatePassword, mailData{
+		Name:    user.Name(),
+		Link:    linkWithToken(s.cfg.ResetURL, token),
+		Minutes: int(s.cfg.ResetTTL.Minutes()),
+	})
+	return nil
+}
+
+// findUser 以用戶名或電子郵件地址查詢用戶
+func (s *PasswordService) findUser(login string) (*models.User, error) {
+	user, err := s.userRepo.FindByUsername(login)
+	if errors.Is(err, gorm.ErrRecordNotFound) && strings.Contains(login, "@") {
+		return s.userRepo.FindByEmail(strings.ToLower(strings.TrimSpace(login)))
+	}
+	return user, err
+}
+
+// Reset 以重設密碼的 token 設定新密碼，token 只能使用一次
+// 重設後解除登入鎖定，並登出用戶在所有裝置上的登入
+func (s *PasswordService) Reset(token, password string) error {
+	resetToken, err := s.tokenRepo.FindUserToken(models.UserTokenPasswordReset, hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("無效或已過期的重設連結")
+		}
+		return err
+	}
+	if resetToken.UsedAt != nil || !time.Now().Before(resetToken.ExpiresAt) {
+		return errors.New("無效或已過期的重設連結")
+	}
+	user, err := s.userRepo.FindByID(resetToken.UserID)
+	if err != nil {
+		return errors.New("無效或已過期的重設連結")
+	}
+
+	// 先檢查密碼規則，不符合時 token 仍可再次使用
+	if err := s.policy.Validate(user.Username, password); err != nil {
+		return err
+	}
+	used, err := s.tokenRepo.UseUserToken(resetToken.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("無效或已過期的重設連結")
+	}
+
+	if err := s.setPassword(user.ID, password); err != nil {
+		return err
+	}
+	if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+		return err
+	}
+	if err := s.tokens.RevokeAll(user.ID); err != nil {
+		return err
+	}
+
+	log.Printf("user %d reset their password", user.ID)
+	return nil
+}
+
+// setPassword 保存新密碼的雜湊值，並讓尚未使用的重設連結失效
+func (s *PasswordService) setPassword(userID uint, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(userID, string(hash)); err != nil {
+		return err
+	}
+	return s.tokenRepo.InvalidateUserTokens(userID, models.UserTokenPasswordReset)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/mail"
+	"debate_web/internal/repository/models"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// captureMailer 將寄出的郵件轉交給測試
+type captureMailer chan mail.Message
+
+func (m captureMailer) Send(msg mail.Message) error {
+	m <- msg
+	return nil
+}
+
+// nextMail 等待下一封寄出的郵件
+func (m captureMailer) nextMail(t *testing.T) mail.Message {
+	t.Helper()
+	select {
+	case msg := <-m:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no mail was sent")
+		return mail.Message{}
+	}
+}
+
+var testPasswordConfig = config.PasswordConfig{
+	MinLength:      8,
+	RequireLetter:  true,
+	RequireDigit:   true,
+	RejectUsername: true,
+	ResetTTL:       time.Minute,
+	ResetURL:       "http://localhost/reset-password",
+}
+
+func newTestPasswordService(t *testing.T, password string) (*PasswordService, *TokenService, *memoryUserRepository, *models.User, captureMailer) {
+	t.Helper()
+	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	users := &memoryUserRepository{}
+	email, verifiedAt := "alice@example.com", time.Now()
+	user := &models.User{Username: "alice", Password: string(hash), Role: models.UserRoleUser, Email: &email, EmailVerifiedAt: &verifiedAt}
+	users.Create(user)
+
+	repo := newMemoryTokenRepository()
+	tokens := NewTokenService(repo, users, config.AuthConfig{AccessTTL: 15 * time.Minute, RefreshTTL: time.Hour}, testJWTKeys)
+	mailer := make(captureMailer, 4)
+	s := NewPasswordService(users, repo, tokens, mailer, NewPasswordPolicy(testPasswordConfig), testPasswordConfig)
+	return s, tokens, users, user, mailer
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy := NewPasswordPolicy(testPasswordConfig)
+	cases := map[string]bool{
+		"short1":      false,
+		"onlyletters": false,
+		"1234567890":  false,
+		"xxalice123":  false,
+		"debate2024":  true,
+		"長長的中文密碼也可以1": true,
+	}
+	for password, ok := range cases {
+		err := policy.Validate("alice", password)
+		if ok && err != nil {
+			t.Errorf("%q rejected: %v", password, err)
+		}
+		var policyErr *PasswordPolicyError
+		if !ok && !errors.As(err, &policyErr) {
+			t.Errorf("%q: expected a policy error, got %v", password, err)
+		}
+	}
+	if err := NewPasswordPolicy(config.PasswordConfig{MaxLength: 100}).Validate("", string(make([]byte, 73))); err == nil {
+		t.Error("passwords longer than bcrypt accepts must be rejected")
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	s, tokens, users, user, _ := newTestPasswordService(t, "original1")
+	current, _ := tokens.Issue(user)
+	other, _ := tokens.Issue(user)
+
+	if err := s.Change(user.ID, accessID(t, current.AccessToken), "wrong", "changed12"); err == nil || err.Error() != "目前的密碼不正確" {
+		t.Fatalf("expected wrong current password to be rejected, got %v", err)
+	}
+	if err := s.Change(user.ID, accessID(t, current.AccessToken), "original1", "changed12"); err != nil {
+		t.Fatalf("change password: %v", err)
+	}
+
+	stored, _ := users.FindByID(user.ID)
+	if bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("changed12")) != nil {
+		t.Error("password was not updated")
+	}
+	if revoked, _ := tokens.IsRevoked(accessID(t, current.AccessToken), user.ID); revoked {
+		t.Error("current session was revoked")
+	}
+	if revoked, _ := tokens.IsRevoked(accessID(t, other.AccessToken), user.ID); !revoked {
+		t.Error("other session is still valid")
+	}
+	if _, err := tokens.Refresh(other.RefreshToken); err == nil {
+		t.Error("other session can still refresh")
+	}
+}
+
+func TestResetPasswordTokenIsSingleUse(t *testing.T) {
+	s, tokens, users, user, mailer := newTestPasswordService(t, "original1")
+	session, _ := tokens.Issue(user)
+	users.Lock(user.ID, time.Now().Add(time.Hour))
+
+	if err := s.RequestReset("nobody"); err != nil {
+		t.Fatalf("unknown users must not be reported: %v", err)
+	}
+	if err := s.RequestReset("alice"); err != nil {
+		t.Fatal(err)
+	}
+	msg := mailer.nextMail(t)
+	if msg.To != "alice@example.com" {
+		t.Errorf("reset mail sent to %q", msg.To)
+	}
+	link, err := url.Parse(regexp.MustCompile(`http://\S+`).FindString(msg.Body))
+	if err != nil {
+		t.Fatalf("mail has no reset link: %q", msg.Body)
+	}
+	token := link.Query().Get("token")
+
+	if err := s.Reset(token, "weak"); err == nil {
+		t.Fatal("weak password accepted")
+	}
+	if err := s.Reset(token, "newpassword9"); err != nil {
+		t.Fatalf("reset with a token that failed the policy check: %v", err)
+	}
+	if err := s.Reset(token, "another99"); err == nil || err.Error() != "無效或已過期的重設連結" {
+		t.Fatalf("expected the token to be single use, got %v", err)
+	}
+
+	stored, _ := users.FindByID(user.ID)
+	if stored.LockedUntil != nil {
+		t.Error("reset did not clear the lockout")
+	}
+	if revoked, _ := tokens.IsRevoked(accessID(t, session.AccessToken), user.ID); !revoked {
+		t.Error("reset did not revoke existing sessions")
+	}
+}
+
+func TestResetPasswordInvalidatesOlderTokens(t *testing.T) {
+	s, _, _, _, mailer := newTestPasswordService(t, "original1")
+	s.cfg.ResetURL = ""
+
+	s.RequestReset("alice")
+	first := regexp.MustCompile(`[A-Za-z0-9_-]{43}`).FindString(mailer.nextMail(t).Body)
+	s.RequestReset("ALICE@example.com")
+	second := regexp.MustCompile(`[A-Za-z0-9_-]{43}`).FindString(mailer.nextMail(t).Body)
+
+	if err := s.Reset(first, "newpassword9"); err == nil {
+		t.Error("older reset token is still valid")
+	}
+	if err := s.Reset(second, "newpassword9"); err != nil {
+		t.Errorf("latest reset token: %v", err)
+	}
+}
+
+func TestResetPasswordRequiresVerifiedEmail(t *testing.T) {
+	s, _, users, user, mailer := newTestPasswordService(t, "original1")
+	users.UpdateEmail(user.ID, user.Email)
+
+	if err := s.RequestReset("alice"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-mailer:
+		t.Fatalf("reset mail sent to an unverified address: %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+)
+
+// Permission 是可以授予全站角色的操作
+type Permission string
+
+const (
+	PermCreateRoom   Permission = "room:create"   // 創建房間，創建者成為該房間的主持人
+	PermManageRooms  Permission = "room:manage"   // 強制結束或刪除任何房間
+	PermModerateRoom Permission = "room:moderate" // 在任何房間審核消息和處分參與者
+	PermManageUsers  Permission = "user:manage"   // 管理用戶帳號和角色
+)
+
+// rolePermissions 是各角色擁有的權限
+// 房間主持人對自己的房間有審核和處分的權限，不需要全站角色
+var rolePermissions = map[models.UserRole][]Permission{
+	models.UserRoleAdmin: {
+		PermCreateRoom, PermManageRooms, PermModerateRoom, PermManageUsers,
+	},
+	models.UserRoleModerator: {
+		PermCreateRoom, PermModerateRoom,
+	},
+	models.UserRoleUser: {
+		PermCreateRoom,
+	},
+}
+
+// roleRanks 是全站角色的等級，擁有全站角色的用戶只能由等級更高的用戶處分
+var roleRanks = map[models.UserRole]int{
+	models.UserRoleUser:      0,
+	models.UserRoleModerator: 1,
+	models.UserRoleAdmin:     2,
+}
+
+// IsValidUserRole 檢查角色是否存在
+func IsValidUserRole(role models.UserRole) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission 檢查角色是否擁有權限，未知的角色沒有任何權限
+func HasPermission(role models.UserRole, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// canModerateRoom 檢查用戶是否可以管理房間的發言：房間主持人，或擁有全站審核權限的用戶
+// 角色以資料庫為準，讓角色變更立即生效
+func canModerateRoom(users repository.UserRepository, room *models.Room, userID uint) bool {
+	if room.OwnerID == userID {
+		return true
+	}
+	user, err := users.FindByID(userID)
+	return err == nil && HasPermission(user.Role, PermModerateRoom)
+}
+
+// canSanction 檢查用戶是否可以處分目標用戶：一般用戶可以由房間的管理者處分，
+// 審核員和管理員則只能由等級更高的用戶處分，避免審核員之間互相踢出或禁言
+func canSanction(users repository.UserRepository, actorID, targetID uint) bool {
+	target, err := users.FindByID(targetID)
+	if err != nil || roleRanks[target.Role] == roleRanks[models.UserRoleUser] {
+		return true
+	}
+	actor, err := users.FindByID(actorID)
+	return err == nil && roleRanks[actor.Role] > roleRanks[target.Role]
+}
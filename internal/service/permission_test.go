@@ -0,0 +1,81 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"testing"
+)
+
+func TestPermissionMatrix(t *testing.T) {
+	cases := []struct {
+		role models.UserRole
+		perm Permission
+		want bool
+	}{
+		{models.UserRoleUser, PermCreateRoom, true},
+		{models.UserRoleUser, PermModerateRoom, false},
+		{models.UserRoleModerator, PermModerateRoom, true},
+		{models.UserRoleModerator, PermManageUsers, false},
+		{models.UserRoleAdmin, PermManageUsers, true},
+		{models.UserRoleAdmin, PermManageRooms, true},
+		{"", PermCreateRoom, false},
+	}
+	for _, c := range cases {
+		if got := HasPermission(c.role, c.perm); got != c.want {
+			t.Errorf("HasPermission(%q, %q) = %v, want %v", c.role, c.perm, got, c.want)
+		}
+	}
+}
+
+func TestGlobalModeratorCanModerateAnyRoom(t *testing.T) {
+	ws, rooms, _ := newTestWebSocketService()
+	moderation := &memoryModerationRepository{}
+	users := &memoryUserRepository{}
+	users.Create(&models.User{Username: "owner", Role: models.UserRoleUser})
+	users.Create(&models.User{Username: "staff", Role: models.UserRoleModerator})
+	users.Create(&models.User{Username: "someone", Role: models.UserRoleUser})
+	sanctions := NewSanctionService(moderation, rooms, users, NewRoomService(rooms, moderation, ws), ws)
+
+	rooms.rooms[1] = &models.Room{Model: gormModel(1), OwnerID: 1, Spectators: []uint{4}}
+
+	if err := sanctions.Ban(1, 3, SanctionRequest{UserID: 4}); err == nil || err.Error() != "只有主持人可以管理參與者" {
+		t.Errorf("ban by a regular user: err = %v", err)
+	}
+	if err := sanctions.Ban(1, 2, SanctionRequest{UserID: 4}); err != nil {
+		t.Errorf("ban by a global moderator: %v", err)
+	}
+	if err := sanctions.Ban(1, 2, SanctionRequest{UserID: 1}); err == nil {
+		t.Error("global moderator banned the room owner")
+	}
+}
+
+func TestSanctionRequiresHigherRank(t *testing.T) {
+	ws, rooms, _ := newTestWebSocketService()
+	moderation := &memoryModerationRepository{}
+	users := &memoryUserRepository{}
+	users.Create(&models.User{Username: "owner", Role: models.UserRoleUser})       // 1
+	users.Create(&models.User{Username: "staff", Role: models.UserRoleModerator})  // 2
+	users.Create(&models.User{Username: "staff2", Role: models.UserRoleModerator}) // 3
+	users.Create(&models.User{Username: "admin", Role: models.UserRoleAdmin})      // 4
+	users.Create(&models.User{Username: "someone", Role: models.UserRoleUser})     // 5
+	sanctions := NewSanctionService(moderation, rooms, users, NewRoomService(rooms, moderation, ws), ws)
+
+	rooms.rooms[1] = &models.Room{Model: gormModel(1), OwnerID: 1, Spectators: []uint{2, 3, 4, 5}}
+
+	cases := []struct {
+		actor, target uint
+		allowed       bool
+	}{
+		{1, 5, true},  // 房間主持人處分一般用戶
+		{1, 2, false}, // 房間主持人不能處分審核員
+		{2, 5, true},
+		{2, 3, false}, // 審核員之間不能互相處分
+		{2, 4, false},
+		{4, 2, true}, // 管理員可以處分審核員
+	}
+	for _, c := range cases {
+		err := sanctions.Mute(1, c.actor, SanctionRequest{UserID: c.target})
+		if (err == nil) != c.allowed {
+			t.Errorf("user %d mutes user %d: err = %v, allowed = %v", c.actor, c.target, err, c.allowed)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"bytes"
+	"debate_web/internal/config"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 個人資料欄位的限制
+const (
+	maxDisplayNameLength = 64
+	maxBioLength         = 500
+
+	defaultAvatarMaxBytes     = 2 << 20
+	defaultAvatarMaxDimension = 4096
+	defaultAvatarSize         = 256
+)
+
+// SupportedLanguages 是可以設定的介面語言
+var SupportedLanguages = []string{"zh-TW", "en"}
+
+// avatarTypes 是允許上傳的頭像格式
+var avatarTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+}
+
+// ProfileService 管理用戶的個人資料和頭像
+type ProfileService struct {
+	userRepo repository.UserRepository
+	blobs    storage.BlobStore
+	cfg      config.AvatarConfig
+}
+
+func NewProfileService(userRepo repository.UserRepository, blobs storage.BlobStore, cfg config.AvatarConfig) *ProfileService {
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultAvatarMaxBytes
+	}
+	if cfg.MaxDimension <= 0 {
+		cfg.MaxDimension = defaultAvatarMaxDimension
+	}
+	if len(cfg.Sizes) == 0 {
+		cfg.Sizes = []int{defaultAvatarSize}
+	}
+	sizes := append([]int(nil), cfg.Sizes...)
+	sort.Ints(sizes)
+	cfg.Sizes = sizes
+
+	return &ProfileService{userRepo: userRepo, blobs: blobs, cfg: cfg}
+}
+
+// PublicProfile 是任何人都可以看到的個人資料
+type PublicProfile struct {
+	ID          uint            `json:"id"`
+	Username    string          `json:"username"`
+	DisplayName string          `json:"display_name"`
+	Bio         string          `json:"bio"`
+	AvatarURL   string          `json:"avatar_url"`
+	Role        models.UserRole `json:"role"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Profile 是用戶自己看到的個人資料，包含電子郵件和偏好設定
+type Profile struct {
+	PublicProfile
+	Email          string   `json:"email"`
+	EmailVerified  bool     `json:"email_verified"`
+	Language       string   `json:"language"`
+	PreferredSides []string `json:"preferred_sides"`
+}
+
+// ProfileUpdate 是修改個人資料的請求，nil 的欄位保持不變
+type ProfileUpdate struct {
+	DisplayName    *string   `json:"display_name"`
+	Bio            *string   `json:"bio"`
+	Language       *string   `json:"language"`
+	PreferredSides *[]string `json:"preferred_sides"`
+}
+
+// GetProfile 返回用戶自己的完整個人資料
+func (s *ProfileService) GetProfile(userID uint) (*Profile, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	return s.profile(user), nil
+}
+
+// GetPublicProfile 返回用戶公開的個人資料
+func (s *ProfileService) GetPublicProfile(userID uint) (*PublicProfile, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	return s.publicProfile(user), nil
+}
+
+// UpdateProfile 驗證並更新個人資料
+func (s *ProfileService) UpdateProfile(userID uint, update ProfileUpdate) (*Profile, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return nil, fmt.Errorf("顯示名稱不能超過 %d 個字", maxDisplayNameLength)
+		}
+		if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return nil, errors.New("顯示名稱包含無效的字元")
+		}
+		user.DisplayName = name
+	}
+	if update.Bio != nil {
+		bio := strings.TrimSpace(*update.Bio)
+		if utf8.RuneCountInString(bio) > maxBioLength {
+			return nil, fmt.Errorf("個人簡介不能超過 %d 個字", maxBioLength)
+		}
+		user.Bio = bio
+	}
+	if update.Language != nil {
+		if *update.Language != "" && !isSupportedLanguage(*update.Language) {
+			return nil, errors.New("不支援的語言")
+		}
+		user.Language = *update.Language
+	}
+	if update.PreferredSides != nil {
+		sides, err := normalizeSides(*update.PreferredSides)
+		if err != nil {
+			return nil, err
+		}
+		user.PreferredSides = sides
+	}
+
+	if err := s.userRepo.UpdateProfile(user); err != nil {
+		return nil, err
+	}
+	return s.profile(user), nil
+}
+
+// MaxAvatarBytes 返回頭像檔案的大小上限
+func (s *ProfileService) MaxAvatarBytes() int64 {
+	return s.cfg.MaxBytes
+}
+
+// UploadAvatar 驗證上傳的圖片，裁成正方形並產生各尺寸的縮圖
+// 只保存重新編碼的 PNG 縮圖，原始檔案中的中繼資料不會被保留
+func (s *ProfileService) UploadAvatar(userID uint, r io.Reader) (*Profile, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.cfg.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.cfg.MaxBytes {
+		return nil, errors.New("頭像檔案過大")
+	}
+	if !avatarTypes[http.DetectContentType(data)] {
+		return nil, errors.New("不支援的圖片格式")
+	}
+
+	// 先讀取尺寸，避免解碼過大的圖片耗盡記憶體
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("無效的圖片")
+	}
+	if imgCfg.Width > s.cfg.MaxDimension || imgCfg.Height > s.cfg.MaxDimension {
+		return nil, errors.New("圖片尺寸過大")
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("無效的圖片")
+	}
+
+	version, err := randomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	prefix := fmt.Sprintf("avatars/%d/%s", userID, version)
+	for _, size := range s.cfg.Sizes {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, thumbnail(img, size)); err != nil {
+			return nil, err
+		}
+		if err := s.blobs.Put(avatarKey(prefix, size), &buf); err != nil {
+			return nil, err
+		}
+	}
+
+	old := user.AvatarKey
+	user.AvatarKey = prefix
+	if err := s.userRepo.UpdateProfile(user); err != nil {
+		s.deleteAvatarFiles(prefix)
+		return nil, err
+	}
+	s.deleteAvatarFiles(old)
+
+	return s.profile(user), nil
+}
+
+// DeleteAvatar 移除用戶的頭像
+func (s *ProfileService) DeleteAvatar(userID uint) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if user.AvatarKey == "" {
+		return nil
+	}
+
+	old := user.AvatarKey
+	user.AvatarKey = ""
+	if err := s.userRepo.UpdateProfile(user); err != nil {
+		return err
+	}
+	s.deleteAvatarFiles(old)
+	return nil
+}
+
+// Avatar 返回用戶的頭像縮圖，使用不小於 size 的最小尺寸，size 為 0 時使用最大的尺寸
+func (s *ProfileService) Avatar(userID uint, size int) (io.ReadCloser, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	if user.AvatarKey == "" {
+		return nil, errors.New("沒有頭像")
+	}
+
+	chosen := s.cfg.Sizes[len(s.cfg.Sizes)-1]
+	for _, candidate := range s.cfg.Sizes {
+		if size > 0 && candidate >= size {
+			chosen = candidate
+			break
+		}
+	}
+
+	file, err := s.blobs.Open(avatarKey(user.AvatarKey, chosen))
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		return nil, errors.New("沒有頭像")
+	}
+	return file, err
+}
+
+// deleteAvatarFiles 刪除舊頭像的所有縮圖，失敗時只記錄錯誤
+func (s *ProfileService) deleteAvatarFiles(prefix string) {
+	if prefix == "" {
+		return
+	}
+	for _, size := range s.cfg.Sizes {
+		if err := s.blobs.Delete(avatarKey(prefix, size)); err != nil {
+			log.Printf("delete avatar %s error: %v", prefix, err)
+		}
+	}
+}
+
+func (s *ProfileService) publicProfile(user *models.User) *PublicProfile {
+	profile := &PublicProfile{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Role:        user.Role,
+		CreatedAt:   user.CreatedAt,
+	}
+	if user.AvatarKey != "" {
+		// 頭像的版本號讓客戶端在更換頭像後不會使用快取中的舊圖片
+		version := user.AvatarKey[strings.LastIndex(user.AvatarKey, "/")+1:]
+		profile.AvatarURL = fmt.Sprintf("/api/users/%d/avatar?v=%s", user.ID, version)
+	}
+	return profile
+}
+
+func (s *ProfileService) profile(user *models.User) *Profile {
+	sides := user.PreferredSides
+	if sides == nil {
+		sides = []string{}
+	}
+	profile := &Profile{
+		PublicProfile:  *s.publicProfile(user),
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		Language:       user.Language,
+		PreferredSides: sides,
+	}
+	if user.Email != nil {
+		profile.Email = *user.Email
+	}
+	return profile
+}
+
+func avatarKey(prefix string, size int) string {
+	return fmt.Sprintf("%s/%d.png", prefix, size)
+}
+
+func isSupportedLanguage(language string) bool {
+	for _, supported := range SupportedLanguages {
+		if language == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeSides 驗證偏好的一方並去除重複
+func normalizeSides(sides []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, side := range sides {
+		if side != "proponent" && side != "opponent" {
+			return nil, errors.New("無效的辯論方")
+		}
+		if !seen[side] {
+			seen[side] = true
+			normalized = append(normalized, side)
+		}
+	}
+	return normalized, nil
+}
+
+// thumbnail 將圖片從中央裁成正方形並縮放到 size x size
+// 縮小時以區域平均取樣，每個輸出像素是其覆蓋的所有原始像素的平均值
+func thumbnail(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+
+	// 轉為預乘 alpha 的 RGBA，平均透明像素時顏色才會正確
+	src := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(src, src.Bounds(), img, crop.Min, draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	if side == 0 {
+		return dst
+	}
+	for y := 0; y < size; y++ {
+		y0, y1 := span(y, size, side)
+		for x := 0; x < size; x++ {
+			x0, x1 := span(x, size, side)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// span 返回第 i 個輸出像素對應的原始像素範圍，放大時至少包含一個像素
+func span(i, size, side int) (int, int) {
+	start := i * side / size
+	end := (i + 1) * side / size
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
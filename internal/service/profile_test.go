@@ -0,0 +1,125 @@
+package service
+
+import (
+	"bytes"
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"debate_web/internal/storage"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func newTestProfileService(t *testing.T) (*ProfileService, *memoryUserRepository, *models.User) {
+	t.Helper()
+	blobs, err := storage.NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &memoryUserRepository{}
+	user := &models.User{Username: "alice", Role: models.UserRoleUser}
+	users.Create(user)
+	return NewProfileService(users, blobs, config.AvatarConfig{MaxBytes: 1 << 20, MaxDimension: 512, Sizes: []int{64, 16}}), users, user
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUpdateProfileValidatesFields(t *testing.T) {
+	profiles, users, user := newTestProfileService(t)
+
+	name, language := "  Alice  ", "en"
+	sides := []string{"opponent", "opponent", "proponent"}
+	profile, err := profiles.UpdateProfile(user.ID, ProfileUpdate{DisplayName: &name, Language: &language, PreferredSides: &sides})
+	if err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	if profile.DisplayName != "Alice" || profile.Language != "en" || len(profile.PreferredSides) != 2 {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	stored, _ := users.FindByID(user.ID)
+	if stored.Name() != "Alice" {
+		t.Fatalf("expected stored display name, got %q", stored.Name())
+	}
+
+	invalid := []ProfileUpdate{
+		{Language: strPtr("fr")},
+		{PreferredSides: &[]string{"judge"}},
+		{DisplayName: strPtr("bad\nname")},
+		{Bio: strPtr(string(bytes.Repeat([]byte("字"), maxBioLength+1)))},
+	}
+	for _, update := range invalid {
+		if _, err := profiles.UpdateProfile(user.ID, update); err == nil {
+			t.Fatalf("expected update %+v to be rejected", update)
+		}
+	}
+}
+
+func TestUploadAvatarStoresThumbnails(t *testing.T) {
+	profiles, _, user := newTestProfileService(t)
+
+	profile, err := profiles.UploadAvatar(user.ID, bytes.NewReader(encodePNG(t, 120, 80)))
+	if err != nil {
+		t.Fatalf("upload avatar: %v", err)
+	}
+	if profile.AvatarURL == "" {
+		t.Fatal("expected an avatar url")
+	}
+
+	for size, want := range map[int]int{0: 64, 10: 16, 32: 64} {
+		file, err := profiles.Avatar(user.ID, size)
+		if err != nil {
+			t.Fatalf("open avatar size %d: %v", size, err)
+		}
+		img, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds().Dx() != want || img.Bounds().Dy() != want {
+			t.Fatalf("size %d: expected %dx%d thumbnail, got %v", size, want, want, img.Bounds())
+		}
+		if r, _, _, _ := img.At(want/2, want/2).RGBA(); r>>8 != 255 {
+			t.Fatalf("size %d: expected the thumbnail to keep its colour", size)
+		}
+	}
+
+	if err := profiles.DeleteAvatar(user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := profiles.Avatar(user.ID, 0); err == nil || err.Error() != "沒有頭像" {
+		t.Fatalf("expected no avatar after delete, got %v", err)
+	}
+}
+
+func TestUploadAvatarRejectsInvalidImages(t *testing.T) {
+	profiles, _, user := newTestProfileService(t)
+
+	cases := map[string][]byte{
+		"不支援的圖片格式": []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"),
+		"圖片尺寸過大":   encodePNG(t, 600, 10),
+		"頭像檔案過大":   bytes.Repeat([]byte{0}, 1<<20+1),
+	}
+	for want, data := range cases {
+		if _, err := profiles.UploadAvatar(user.ID, bytes.NewReader(data)); err == nil || err.Error() != want {
+			t.Fatalf("expected %q, got %v", want, err)
+		}
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
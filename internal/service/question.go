@@ -0,0 +1,265 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxQuestionLength 觀眾提問的最大字數
+const maxQuestionLength = 300
+
+// QuestionService 處理觀眾提問、點讚和主持人提升問題到辯論場
+type QuestionService struct {
+	repo      repository.QuestionRepository
+	roomRepo  repository.RoomRepository
+	userRepo  repository.UserRepository
+	wsService *WebSocketService
+}
+
+// NewQuestionService 創建問答服務並註冊其 WebSocket 命令
+func NewQuestionService(repo repository.QuestionRepository, roomRepo repository.RoomRepository, userRepo repository.UserRepository, ws *WebSocketService) *QuestionService {
+	s := &QuestionService{
+		repo:      repo,
+		roomRepo:  roomRepo,
+		userRepo:  userRepo,
+		wsService: ws,
+	}
+
+	ws.HandleFrame("question", s.handleSubmit)
+	ws.HandleFrame("question_upvote", s.handleUpvote)
+	ws.HandleFrame("question_promote", s.handlePromote)
+	ws.HandleFrame("question_dismiss", s.handleDismiss)
+	ws.HandleFrame("question_queue", func(client *Client, frame *ClientFrame) {
+		if !client.Moderator {
+			ws.SendError(client, "只有主持人可以查看問題隊列")
+			return
+		}
+		if queue, err := s.repo.FindPendingByRoom(client.RoomID); err == nil {
+			ws.SendToClient(client, queueMessage(client.RoomID, queue))
+		}
+	})
+
+	return s
+}
+
+// ListQueue 返回房間內按點讚數排序的待處理問題，只有房間主持人和擁有全站審核權限的用戶可以查看
+func (s *QuestionService) ListQueue(roomID, userID uint) ([]models.Question, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return nil, errors.New("房間不存在")
+	}
+	if !canModerateRoom(s.userRepo, room, userID) {
+		return nil, errors.New("只有主持人可以查看問題隊列")
+	}
+	return s.repo.FindPendingByRoom(roomID)
+}
+
+// handleSubmit 處理觀眾提交的問題
+// 問題會公開在觀眾席上，因此只在開放觀眾席的賽制中接受，並與觀眾席聊天經過相同的審核和慢速模式
+// 問題隊列沒有暫緩審核的流程，需要暫緩的問題直接拒絕
+func (s *QuestionService) handleSubmit(client *Client, frame *ClientFrame) {
+	if client.Role != "spectator" {
+		s.wsService.SendError(client, "只有觀眾可以提問")
+		return
+	}
+	policy := client.Format.Policy(models.ChannelGallery)
+	if !policy.Enabled {
+		s.wsService.SendError(client, "此賽制未開放觀眾提問")
+		return
+	}
+
+	content := strings.TrimSpace(frame.Content)
+	if content == "" || utf8.RuneCountInString(content) > maxQuestionLength {
+		s.wsService.SendError(client, fmt.Sprintf("問題長度必須在 1 到 %d 字之間", maxQuestionLength))
+		return
+	}
+
+	msg := &models.Message{
+		Type:    "question",
+		RoomID:  client.RoomID,
+		UserID:  client.UserID,
+		Role:    client.Role,
+		Channel: models.ChannelGallery,
+		Content: content,
+	}
+	verdict, ok := s.wsService.screen(client, msg, policy)
+	if !ok {
+		return
+	}
+	if verdict.Action == ModerationHold {
+		s.wsService.SendError(client, verdict.Reason)
+		return
+	}
+
+	question := &models.Question{
+		RoomID:  client.RoomID,
+		UserID:  client.UserID,
+		Content: msg.Content,
+		Status:  models.QuestionStatusPending,
+	}
+	if err := s.repo.Create(question); err != nil {
+		log.Printf("question create error: %v", err)
+		s.wsService.SendError(client, "提問失敗")
+		return
+	}
+
+	s.wsService.BroadcastToRoom(client.RoomID, questionMessage("question_new", question))
+	s.pushQueue(client.RoomID)
+}
+
+// handleUpvote 處理觀眾對其他人問題的點讚
+func (s *QuestionService) handleUpvote(client *Client, frame *ClientFrame) {
+	if client.Role != "spectator" {
+		s.wsService.SendError(client, "只有觀眾可以為問題點讚")
+		return
+	}
+
+	question, ok := s.pendingQuestion(client, frame.TargetID)
+	if !ok {
+		return
+	}
+	if question.UserID == client.UserID {
+		s.wsService.SendError(client, "不能為自己的問題點讚")
+		return
+	}
+
+	added, err := s.repo.AddVote(question.ID, client.UserID)
+	if err != nil {
+		log.Printf("question vote error: %v", err)
+		s.wsService.SendError(client, "點讚失敗")
+		return
+	}
+	if !added {
+		s.wsService.SendError(client, "您已經為這個問題點過讚")
+		return
+	}
+
+	if updated, err := s.repo.FindByID(question.ID); err == nil {
+		s.wsService.BroadcastToRoom(client.RoomID, questionMessage("question_update", updated))
+	}
+	s.pushQueue(client.RoomID)
+}
+
+// handlePromote 將問題以主持人身份發布到辯論場，並指定回答的一方
+func (s *QuestionService) handlePromote(client *Client, frame *ClientFrame) {
+	if !client.Moderator {
+		s.wsService.SendError(client, "只有主持人可以提升問題")
+		return
+	}
+
+	sideName, ok := sideNames[frame.Side]
+	if !ok {
+		s.wsService.SendError(client, "無效的回答方")
+		return
+	}
+
+	question, ok := s.pendingQuestion(client, frame.TargetID)
+	if !ok {
+		return
+	}
+
+	msg := &models.Message{
+		Type:    "question",
+		RoomID:  client.RoomID,
+		UserID:  client.UserID,
+		Role:    "moderator",
+		Channel: models.ChannelFloor,
+		Content: fmt.Sprintf("觀眾提問（請%s回答）：%s", sideName, question.Content),
+		Data: map[string]interface{}{
+			"question_id": question.ID,
+			"side":        frame.Side,
+		},
+	}
+	if err := s.wsService.PublishMessage(msg); err != nil {
+		log.Printf("question promote error: %v", err)
+		s.wsService.SendError(client, "提升問題失敗")
+		return
+	}
+
+	question.Status = models.QuestionStatusPromoted
+	question.TargetSide = frame.Side
+	question.MessageID = msg.ID
+	if err := s.repo.Update(question); err != nil {
+		log.Printf("question update error: %v", err)
+	}
+
+	s.wsService.BroadcastToRoom(client.RoomID, questionMessage("question_update", question))
+	s.pushQueue(client.RoomID)
+}
+
+// handleDismiss 將問題從隊列中移除
+func (s *QuestionService) handleDismiss(client *Client, frame *ClientFrame) {
+	if !client.Moderator {
+		s.wsService.SendError(client, "只有主持人可以移除問題")
+		return
+	}
+
+	question, ok := s.pendingQuestion(client, frame.TargetID)
+	if !ok {
+		return
+	}
+
+	question.Status = models.QuestionStatusDismissed
+	if err := s.repo.Update(question); err != nil {
+		log.Printf("question update error: %v", err)
+		s.wsService.SendError(client, "移除問題失敗")
+		return
+	}
+
+	s.wsService.BroadcastToRoom(client.RoomID, questionMessage("question_update", question))
+	s.pushQueue(client.RoomID)
+}
+
+// pendingQuestion 查找客戶端所在房間內待處理的問題，找不到時向客戶端發送錯誤
+func (s *QuestionService) pendingQuestion(client *Client, questionID uint) (*models.Question, bool) {
+	question, err := s.repo.FindByID(questionID)
+	if err != nil || question.RoomID != client.RoomID {
+		s.wsService.SendError(client, "問題不存在")
+		return nil, false
+	}
+	if question.Status != models.QuestionStatusPending {
+		s.wsService.SendError(client, "問題已經處理過")
+		return nil, false
+	}
+	return question, true
+}
+
+// pushQueue 將最新的問題隊列推送給房間主持人
+func (s *QuestionService) pushQueue(roomID uint) {
+	queue, err := s.repo.FindPendingByRoom(roomID)
+	if err != nil {
+		log.Printf("question queue error: %v", err)
+		return
+	}
+	s.wsService.SendToModerators(roomID, queueMessage(roomID, queue))
+}
+
+// questionMessage 建立觀眾席上的問題通知
+func questionMessage(messageType string, question *models.Question) *models.Message {
+	return &models.Message{
+		Type:    messageType,
+		RoomID:  question.RoomID,
+		Channel: models.ChannelGallery,
+		Data:    question,
+	}
+}
+
+// queueMessage 建立只發送給主持人的問題隊列
+func queueMessage(roomID uint, queue []models.Question) *models.Message {
+	return &models.Message{
+		Type:   "question_queue",
+		RoomID: roomID,
+		Data:   queue,
+	}
+}
+
+// sideNames 辯論雙方的顯示名稱
+var sideNames = map[string]string{
+	"proponent": "正方",
+	"opponent":  "反方",
+}
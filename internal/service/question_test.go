@@ -0,0 +1,303 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// memoryQuestionRepository 是只保存在記憶體中的 QuestionRepository
+type memoryQuestionRepository struct {
+	mu        sync.Mutex
+	questions []*models.Question
+	votes     map[[2]uint]bool
+}
+
+func (r *memoryQuestionRepository) Create(question *models.Question) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	question.ID = uint(len(r.questions) + 1)
+	copied := *question
+	r.questions = append(r.questions, &copied)
+	return nil
+}
+
+func (r *memoryQuestionRepository) FindByID(id uint) (*models.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.questions) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *r.questions[id-1]
+	return &copied, nil
+}
+
+func (r *memoryQuestionRepository) Update(question *models.Question) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *question
+	r.questions[question.ID-1] = &copied
+	return nil
+}
+
+func (r *memoryQuestionRepository) FindPendingByRoom(roomID uint) ([]models.Question, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var questions []models.Question
+	for _, question := range r.questions {
+		if question.RoomID == roomID && question.Status == models.QuestionStatusPending {
+			questions = append(questions, *question)
+		}
+	}
+	sort.SliceStable(questions, func(i, j int) bool { return questions[i].Upvotes > questions[j].Upvotes })
+	return questions, nil
+}
+
+func (r *memoryQuestionRepository) AddVote(questionID, userID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.votes == nil {
+		r.votes = make(map[[2]uint]bool)
+	}
+	key := [2]uint{questionID, userID}
+	if r.votes[key] {
+		return false, nil
+	}
+	r.votes[key] = true
+	r.questions[questionID-1].Upvotes++
+	return true, nil
+}
+
+// nextOfType 讀取客戶端的消息，直到收到指定類型的消息
+func nextOfType(t *testing.T, client *Client, messageType string) *models.Message {
+	t.Helper()
+	for {
+		if msg := next(t, client); msg.Type == messageType {
+			return msg
+		}
+	}
+}
+
+// newTestQuestionService 建立問答服務，並在房間中加入主持人、兩名觀眾和一名辯手
+func newTestQuestionService(t *testing.T) (*QuestionService, *WebSocketService, *memoryMessageRepository, []*Client) {
+	t.Helper()
+	ws, rooms, messages := newTestWebSocketService()
+	room := &models.Room{OwnerID: 1, ProponentID: 4}
+	rooms.Create(room)
+	s := NewQuestionService(&memoryQuestionRepository{}, rooms, ws.userRepo, ws)
+
+	owner := newTestClient(room.ID, 1, 64)
+	owner.Moderator = true
+	alice := newTestClient(room.ID, 2, 64)
+	bob := newTestClient(room.ID, 3, 64)
+	debater := newTestClient(room.ID, 4, 64)
+	debater.Role = "proponent"
+	clients := []*Client{owner, alice, bob, debater}
+	for _, client := range clients {
+		ws.addClient(client)
+		t.Cleanup(func() { ws.removeClient(client) })
+	}
+	return s, ws, messages, clients
+}
+
+func ask(ws *WebSocketService, client *Client, content string) {
+	ws.handleFrame(client, &ClientFrame{Message: models.Message{Type: "question", Content: content}})
+}
+
+func TestQuestionSubmitAndUpvote(t *testing.T) {
+	s, ws, _, clients := newTestQuestionService(t)
+	owner, alice, bob, debater := clients[0], clients[1], clients[2], clients[3]
+
+	ask(ws, debater, "辯手不能提問")
+	if msg := nextOfType(t, debater, "error"); msg.Content != "只有觀眾可以提問" {
+		t.Errorf("debater question: error = %q", msg.Content)
+	}
+	ask(ws, alice, "   ")
+	if msg := nextOfType(t, alice, "error"); msg.Content != "問題長度必須在 1 到 300 字之間" {
+		t.Errorf("empty question: error = %q", msg.Content)
+	}
+
+	ask(ws, alice, "死刑能嚇阻犯罪嗎？") // 1
+	ask(ws, bob, "誤判怎麼辦？")      // 2
+	if msg := nextOfType(t, bob, "question_new"); msg.Channel != models.ChannelGallery || msg.Data.(*models.Question).Content != "死刑能嚇阻犯罪嗎？" {
+		t.Errorf("question_new = %+v", msg)
+	}
+
+	upvote := func(client *Client, questionID uint) {
+		ws.handleFrame(client, &ClientFrame{Message: models.Message{Type: "question_upvote"}, TargetID: questionID})
+	}
+	upvote(alice, 2)
+	upvote(alice, 2)
+	if msg := nextOfType(t, alice, "error"); msg.Content != "您已經為這個問題點過讚" {
+		t.Errorf("duplicate upvote: error = %q", msg.Content)
+	}
+	upvote(bob, 2)
+	if msg := nextOfType(t, bob, "error"); msg.Content != "不能為自己的問題點讚" {
+		t.Errorf("self upvote: error = %q", msg.Content)
+	}
+	upvote(debater, 1)
+	if msg := nextOfType(t, debater, "error"); msg.Content != "只有觀眾可以為問題點讚" {
+		t.Errorf("debater upvote: error = %q", msg.Content)
+	}
+
+	// 主持人收到的隊列按點讚數排序
+	var queue []models.Question
+	for len(queue) != 2 || queue[0].Upvotes != 1 {
+		queue = nextOfType(t, owner, "question_queue").Data.([]models.Question)
+	}
+	if queue[0].ID != 2 || queue[1].ID != 1 || queue[1].Upvotes != 0 {
+		t.Errorf("queue = %+v", queue)
+	}
+	if listed, err := s.ListQueue(1, 1); err != nil || len(listed) != 2 || listed[0].ID != 2 {
+		t.Errorf("ListQueue = %+v, err = %v", listed, err)
+	}
+	if _, err := s.ListQueue(1, 2); err == nil {
+		t.Error("spectator can read the question queue")
+	}
+}
+
+func TestQuestionPromoteAndDismiss(t *testing.T) {
+	s, ws, messages, clients := newTestQuestionService(t)
+	owner, alice, bob, debater := clients[0], clients[1], clients[2], clients[3]
+	ask(ws, alice, "死刑能嚇阻犯罪嗎？") // 1
+	ask(ws, bob, "誤判怎麼辦？")      // 2
+
+	promote := func(client *Client, questionID uint, side string) {
+		ws.handleFrame(client, &ClientFrame{Message: models.Message{Type: "question_promote"}, TargetID: questionID, Side: side})
+	}
+	promote(alice, 1, "proponent")
+	if msg := nextOfType(t, alice, "error"); msg.Content != "只有主持人可以提升問題" {
+		t.Errorf("spectator promote: error = %q", msg.Content)
+	}
+	promote(owner, 1, "gallery")
+	if msg := nextOfType(t, owner, "error"); msg.Content != "無效的回答方" {
+		t.Errorf("invalid side: error = %q", msg.Content)
+	}
+
+	// 提升後的問題以主持人身份出現在辯論場上
+	promote(owner, 1, "proponent")
+	msg := nextOfType(t, debater, "question")
+	if msg.Channel != models.ChannelFloor || msg.Role != "moderator" || msg.Content != "觀眾提問（請正方回答）：死刑能嚇阻犯罪嗎？" {
+		t.Errorf("promoted message = %+v", msg)
+	}
+	saved, _ := messages.FindByRoom(1)
+	question, _ := s.repo.FindByID(1)
+	if len(saved) != 1 || question.Status != models.QuestionStatusPromoted || question.TargetSide != "proponent" || question.MessageID != saved[0].ID {
+		t.Errorf("question = %+v, saved messages = %+v", question, saved)
+	}
+	promote(owner, 1, "opponent")
+	if msg := nextOfType(t, owner, "error"); msg.Content != "問題已經處理過" {
+		t.Errorf("promote twice: error = %q", msg.Content)
+	}
+
+	ws.handleFrame(alice, &ClientFrame{Message: models.Message{Type: "question_dismiss"}, TargetID: 2})
+	if msg := nextOfType(t, alice, "error"); msg.Content != "只有主持人可以移除問題" {
+		t.Errorf("spectator dismiss: error = %q", msg.Content)
+	}
+	ws.handleFrame(owner, &ClientFrame{Message: models.Message{Type: "question_dismiss"}, TargetID: 2})
+	if queue, _ := s.ListQueue(1, 1); len(queue) != 0 {
+		t.Errorf("queue after promote and dismiss = %+v", queue)
+	}
+	if question, _ := s.repo.FindByID(2); question.Status != models.QuestionStatusDismissed {
+		t.Errorf("dismissed question = %+v", question)
+	}
+}
+
+func TestQuestionFollowsGalleryRules(t *testing.T) {
+	s, ws, _, clients := newTestQuestionService(t)
+	alice, bob := clients[1], clients[2]
+	ws.UseModeration(NewModerationPipeline(
+		NewLengthFilter(nil),
+		NewWordFilter([]string{"笨蛋"}, []string{"作弊"}, []string{"詐騙"}),
+	))
+	expectError := func(client *Client, content, want string) {
+		t.Helper()
+		ask(ws, client, content)
+		if msg := nextOfType(t, client, "error"); msg.Content != want {
+			t.Errorf("question %q: error = %q, want %q", content, msg.Content, want)
+		}
+	}
+
+	// 關閉觀眾席的賽制不接受提問
+	bob.Format = GetDebateFormat("tournament")
+	expectError(bob, "比賽中可以提問嗎？", "此賽制未開放觀眾提問")
+
+	expectError(alice, "正方作弊", "消息包含需要審核的用語")
+	expectError(alice, "這是詐騙", "消息包含不允許的用語")
+	ask(ws, alice, strings.Repeat("問", 201))
+	nextOfType(t, alice, "error")
+
+	ask(ws, alice, "笨蛋才支持死刑？")
+	if msg := nextOfType(t, alice, "question_new"); msg.Data.(*models.Question).Content != "**才支持死刑？" {
+		t.Errorf("masked question = %+v", msg.Data)
+	}
+	expectError(alice, "再問一題", "發言過於頻繁，請稍後再試")
+
+	if queue, _ := s.ListQueue(1, 1); len(queue) != 1 {
+		t.Errorf("queue = %+v, want only the masked question", queue)
+	}
+}
+
+func TestSiteModeratorModeratesRoom(t *testing.T) {
+	ws, rooms, messages := newTestWebSocketService()
+	users := ws.userRepo
+	users.Create(&models.User{Username: "owner"})                                 // 1
+	users.Create(&models.User{Username: "staff", Role: models.UserRoleModerator}) // 2
+	users.Create(&models.User{Username: "viewer"})                                // 3
+	users.Create(&models.User{Username: "debater"})                               // 4
+
+	room := &models.Room{OwnerID: 1, ProponentID: 4, Spectators: []uint{2, 3}}
+	rooms.Create(room)
+	questions := NewQuestionService(&memoryQuestionRepository{}, rooms, users, ws)
+	moderation := &memoryModerationRepository{}
+	sanction := NewSanctionService(moderation, rooms, users, NewRoomService(rooms, moderation, ws), ws)
+	review := NewReviewService(moderation, messages, rooms, users, sanction, ws)
+
+	// 不是房間主持人的審核員連接時同樣取得主持權限
+	staff := ws.newClient(nil, room.ID, 2, "spectator")
+	viewer := ws.newClient(nil, room.ID, 3, "spectator")
+	debater := ws.newClient(nil, room.ID, 4, "proponent")
+	if !staff.Moderator || viewer.Moderator || debater.Moderator {
+		t.Fatalf("moderator flags: staff %v, viewer %v, debater %v", staff.Moderator, viewer.Moderator, debater.Moderator)
+	}
+	for _, client := range []*Client{staff, viewer, debater} {
+		ws.addClient(client)
+		defer ws.removeClient(client)
+	}
+
+	ws.handleFrame(viewer, &ClientFrame{Message: models.Message{Type: "question", Content: "死刑能嚇阻犯罪嗎？"}})
+	ws.handleFrame(staff, &ClientFrame{Message: models.Message{Type: "question", Content: "誤判怎麼辦？"}})
+	if queue := nextOfType(t, staff, "question_queue").Data.([]models.Question); len(queue) != 1 {
+		t.Fatalf("first pushed queue = %+v", queue)
+	}
+	if queue, err := questions.ListQueue(room.ID, 2); err != nil || len(queue) != 2 {
+		t.Fatalf("ListQueue for moderator: queue = %+v, err = %v", queue, err)
+	}
+	if _, err := questions.ListQueue(room.ID, 3); err == nil {
+		t.Fatal("spectator can read the question queue")
+	}
+
+	ws.handleFrame(staff, &ClientFrame{Message: models.Message{Type: "question_promote"}, TargetID: 1, Side: "proponent"})
+	ws.handleFrame(staff, &ClientFrame{Message: models.Message{Type: "question_dismiss"}, TargetID: 2})
+	if queue, _ := questions.ListQueue(room.ID, 2); len(queue) != 0 {
+		t.Fatalf("queue after promote and dismiss = %+v", queue)
+	}
+	promoted, _ := questions.repo.FindByID(1)
+	dismissed, _ := questions.repo.FindByID(2)
+	if promoted.Status != models.QuestionStatusPromoted || promoted.MessageID == 0 || dismissed.Status != models.QuestionStatusDismissed {
+		t.Fatalf("promoted = %+v, dismissed = %+v", promoted, dismissed)
+	}
+
+	ws.handleMessage(debater, &models.Message{Content: "不當的發言"})
+	published, _ := messages.FindByRoom(room.ID)
+	if err := review.ReportMessage(published[len(published)-1].ID, 3, "人身攻擊"); err != nil {
+		t.Fatal(err)
+	}
+	if msg := nextOfType(t, staff, "message_reported"); msg.Content != "人身攻擊" {
+		t.Errorf("report notification = %+v", msg)
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// tokenBucket 是令牌桶，每秒補充固定數量的令牌，最多累積到上限
+// 參數在每次取用時傳入，同一個桶可以依連接的角色使用不同的限制
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow 嘗試取用一個令牌，limit.Rate 為 0 時不限制
+func (b *tokenBucket) allow(now time.Time, limit config.TokenBucketConfig) bool {
+	if limit.Rate <= 0 {
+		return true
+	}
+
+	burst := float64(max(limit.Burst, 1))
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// frameLimiter 限制客戶端發送 WebSocket 消息的速率
+// 每個連接的令牌桶存放在 Client 中，只在 readPump 中讀寫；同一用戶所有連接共用的令牌桶由 frameLimiter 保管
+type frameLimiter struct {
+	cfg   config.WebSocketRateLimitConfig
+	users map[uint]*userBucket
+	mu    sync.Mutex
+}
+
+// userBucket 是用戶所有連接共用的令牌桶，連接數歸零時移除
+type userBucket struct {
+	bucket tokenBucket
+	conns  int
+}
+
+func newFrameLimiter(cfg config.WebSocketRateLimitConfig) *frameLimiter {
+	return &frameLimiter{cfg: cfg, users: make(map[uint]*userBucket)}
+}
+
+// limits 返回角色適用的限制，觀眾以外的角色都使用辯手的限制
+func (l *frameLimiter) limits(role string) config.RoleRateLimit {
+	if role == "spectator" {
+		return l.cfg.Spectator
+	}
+	return l.cfg.Debater
+}
+
+func (l *frameLimiter) connect(userID uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.users[userID] == nil {
+		l.users[userID] = &userBucket{}
+	}
+	l.users[userID].conns++
+}
+
+func (l *frameLimiter) disconnect(userID uint) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if user := l.users[userID]; user != nil {
+		user.conns--
+		if user.conns <= 0 {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// allowUser 從用戶共用的令牌桶取用一個令牌
+func (l *frameLimiter) allowUser(userID uint, now time.Time, limit config.TokenBucketConfig) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	user := l.users[userID]
+	if user == nil {
+		return true
+	}
+	return user.bucket.allow(now, limit)
+}
+
+// UseRateLimit 啟用 WebSocket 消息的速率限制，必須在開始接受連接之前呼叫
+func (s *WebSocketService) UseRateLimit(cfg config.WebSocketRateLimitConfig) {
+	s.limiter = newFrameLimiter(cfg)
+}
+
+// allowFrame 檢查客戶端是否可以再發送一則消息，超過限制的消息會被丟棄
+// 窗口內第一次超過限制時警告客戶端，持續超過限制則斷開連接
+func (s *WebSocketService) allowFrame(client *Client) bool {
+	if s.limiter == nil {
+		return true
+	}
+
+	now := time.Now()
+	limits := s.limiter.limits(client.Role)
+	if client.bucket.allow(now, limits.Connection) && s.limiter.allowUser(client.UserID, now, limits.User) {
+		return true
+	}
+
+	if now.Sub(client.violationsSince) > s.limiter.cfg.ViolationWindow {
+		client.violationsSince = now
+		client.violations = 0
+	}
+	client.violations++
+
+	switch {
+	case s.limiter.cfg.DisconnectAfter > 0 && client.violations >= s.limiter.cfg.DisconnectAfter:
+		log.Printf("room %d: disconnecting user %d for exceeding the frame rate limit", client.RoomID, client.UserID)
+		client.closing = true
+		s.deliver(client.RoomID, outbound{
+			message: &models.Message{
+				Type:    "disconnected",
+				RoomID:  client.RoomID,
+				Content: "發送過於頻繁，連接已被中斷",
+			},
+			to:    func(c *Client) bool { return c == client },
+			evict: true,
+		})
+	case client.violations == 1:
+		s.SendToClient(client, &models.Message{
+			Type:    "rate_limited",
+			RoomID:  client.RoomID,
+			Content: "發送過於頻繁，超出的消息已被丟棄",
+		})
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	limit := config.TokenBucketConfig{Rate: 2, Burst: 3}
+	start := time.Now()
+	var b tokenBucket
+
+	for i := 0; i < 3; i++ {
+		if !b.allow(start, limit) {
+			t.Fatalf("burst token %d was refused", i)
+		}
+	}
+	if b.allow(start, limit) {
+		t.Fatal("bucket allowed more than its burst")
+	}
+	if !b.allow(start.Add(500*time.Millisecond), limit) {
+		t.Fatal("bucket did not refill at its rate")
+	}
+	if b.allow(start.Add(500*time.Millisecond), limit) {
+		t.Fatal("bucket refilled more than its rate")
+	}
+	// 長時間閒置後最多只累積到上限
+	after := start.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		b.allow(after, limit)
+	}
+	if b.allow(after, limit) {
+		t.Fatal("bucket accumulated beyond its burst")
+	}
+
+	var unlimited tokenBucket
+	for i := 0; i < 100; i++ {
+		if !unlimited.allow(start, config.TokenBucketConfig{}) {
+			t.Fatal("zero rate should not limit")
+		}
+	}
+}
+
+func TestFrameRateLimit(t *testing.T) {
+	s, _, _ := newTestWebSocketService()
+	s.UseRateLimit(config.WebSocketRateLimitConfig{
+		Debater: config.RoleRateLimit{
+			Connection: config.TokenBucketConfig{Rate: 0.001, Burst: 2},
+			User:       config.TokenBucketConfig{Rate: 0.001, Burst: 3},
+		},
+		Spectator: config.RoleRateLimit{
+			Connection: config.TokenBucketConfig{Rate: 0.001, Burst: 1},
+		},
+		ViolationWindow: time.Minute,
+		DisconnectAfter: 3,
+	})
+
+	// 同一用戶的兩個連接共用用戶的額度
+	first, second := newTestClient(1, 1, 16), newTestClient(1, 1, 16)
+	first.Role, second.Role = "proponent", "proponent"
+	for _, c := range []*Client{first, second} {
+		s.addClient(c)
+		s.limiter.connect(c.UserID)
+	}
+	allowed := 0
+	for i := 0; i < 2; i++ {
+		for _, c := range []*Client{first, second} {
+			if s.allowFrame(c) {
+				allowed++
+			}
+		}
+	}
+	if allowed != 3 {
+		t.Fatalf("allowed %d frames across connections, want the user burst of 3", allowed)
+	}
+	if got := nextNonSystem(second).Type; got != "rate_limited" {
+		t.Fatalf("first violation sent %q, want rate_limited", got)
+	}
+
+	// 觀眾的額度較少，持續超過限制後被斷開
+	spectator := newTestClient(1, 2, 16)
+	s.addClient(spectator)
+	s.limiter.connect(spectator.UserID)
+	if !s.allowFrame(spectator) {
+		t.Fatal("spectator's first frame was refused")
+	}
+	for i := 0; i < 3; i++ {
+		s.allowFrame(spectator)
+	}
+	if !spectator.closing {
+		t.Fatal("spectator was not disconnected after sustained abuse")
+	}
+	var types []string
+	for msg := range spectator.SendChan {
+		if msg.Type != "system" {
+			types = append(types, msg.Type)
+		}
+	}
+	if len(types) != 2 || types[0] != "rate_limited" || types[1] != "disconnected" {
+		t.Fatalf("spectator frames = %v, want a warning then a disconnect", types)
+	}
+}
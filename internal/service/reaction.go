@@ -0,0 +1,178 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"log"
+	"sync"
+	"time"
+)
+
+// reactionFlushInterval 回應數更新的批次推送間隔
+const reactionFlushInterval = time.Second
+
+// reactionEmojis 伺服器允許的表情及其對掌聲指標的權重
+var reactionEmojis = map[string]int{
+	"👏": 1,
+	"👍": 1,
+	"🔥": 1,
+	"🤔": 0,
+	"😂": 0,
+	"👎": -1,
+}
+
+// ReactionService 處理觀眾對消息的表情回應，並批次推送回應數的變化
+type ReactionService struct {
+	repo        repository.ReactionRepository
+	messageRepo repository.MessageRepository
+	wsService   *WebSocketService
+
+	pending    map[uint]map[uint]bool // roomID -> 回應數有變化的消息 ID
+	pendingMux sync.Mutex
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReactionService 創建回應服務、註冊其 WebSocket 命令並開始批次推送
+func NewReactionService(repo repository.ReactionRepository, messageRepo repository.MessageRepository, ws *WebSocketService) *ReactionService {
+	s := &ReactionService{
+		repo:        repo,
+		messageRepo: messageRepo,
+		wsService:   ws,
+		pending:     make(map[uint]map[uint]bool),
+		done:        make(chan struct{}),
+	}
+
+	ws.HandleFrame("reaction", func(client *Client, frame *ClientFrame) {
+		s.handleReaction(client, frame, true)
+	})
+	ws.HandleFrame("reaction_remove", func(client *Client, frame *ClientFrame) {
+		s.handleReaction(client, frame, false)
+	})
+
+	go s.run(reactionFlushInterval)
+
+	return s
+}
+
+// Close 停止批次推送，尚未推送的變化會被捨棄，可以重複調用
+func (s *ReactionService) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// Applause 計算房間內正反雙方的掌聲指標，即雙方消息收到的回應按表情權重加總
+func (s *ReactionService) Applause(roomID uint) (map[string]int, error) {
+	counts, err := s.repo.CountByRole(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	applause := map[string]int{"proponent": 0, "opponent": 0}
+	for _, count := range counts {
+		if _, ok := applause[count.Role]; ok {
+			applause[count.Role] += reactionEmojis[count.Emoji] * count.Count
+		}
+	}
+	return applause, nil
+}
+
+// handleReaction 處理觀眾新增或撤回表情回應
+// frame.TargetID 為消息 ID，frame.Content 為表情
+func (s *ReactionService) handleReaction(client *Client, frame *ClientFrame, add bool) {
+	if client.Role != "spectator" {
+		s.wsService.SendError(client, "只有觀眾可以回應消息")
+		return
+	}
+	if _, ok := reactionEmojis[frame.Content]; !ok {
+		s.wsService.SendError(client, "不支援的表情")
+		return
+	}
+
+	message, err := s.messageRepo.FindByID(frame.TargetID)
+	if err != nil || message.RoomID != client.RoomID || message.Status != models.MessageStatusPublished {
+		s.wsService.SendError(client, "消息不存在")
+		return
+	}
+
+	var changed bool
+	if add {
+		changed, err = s.repo.Add(&models.Reaction{
+			MessageID: message.ID,
+			UserID:    client.UserID,
+			Emoji:     frame.Content,
+			RoomID:    client.RoomID,
+		})
+	} else {
+		changed, err = s.repo.Remove(message.ID, client.UserID, frame.Content)
+	}
+	if err != nil {
+		log.Printf("reaction error: %v", err)
+		s.wsService.SendError(client, "回應失敗")
+		return
+	}
+
+	if changed {
+		s.markPending(client.RoomID, message.ID)
+	}
+}
+
+// markPending 記錄回應數有變化的消息，等待下一次批次推送
+func (s *ReactionService) markPending(roomID, messageID uint) {
+	s.pendingMux.Lock()
+	defer s.pendingMux.Unlock()
+
+	if s.pending[roomID] == nil {
+		s.pending[roomID] = make(map[uint]bool)
+	}
+	s.pending[roomID][messageID] = true
+}
+
+// run 定期將各房間有變化的回應數推送到觀眾席，直到 Close 被調用
+func (s *ReactionService) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// flush 推送所有有變化的消息的最新回應數，每個房間一則更新
+func (s *ReactionService) flush() {
+	s.pendingMux.Lock()
+	pending := s.pending
+	s.pending = make(map[uint]map[uint]bool)
+	s.pendingMux.Unlock()
+
+	for roomID, messages := range pending {
+		ids := make([]uint, 0, len(messages))
+		// 回應全部撤回的消息也要推送，讓客戶端清空計數
+		counts := make(map[uint]map[string]int, len(messages))
+		for id := range messages {
+			ids = append(ids, id)
+			counts[id] = map[string]int{}
+		}
+
+		rows, err := s.repo.CountByMessages(ids)
+		if err != nil {
+			log.Printf("reaction count error: %v", err)
+			continue
+		}
+		for _, row := range rows {
+			counts[row.MessageID][row.Emoji] = row.Count
+		}
+
+		s.wsService.BroadcastToRoom(roomID, &models.Message{
+			Type:    "reaction_counts",
+			RoomID:  roomID,
+			Channel: models.ChannelGallery,
+			Data:    counts,
+		})
+	}
+}
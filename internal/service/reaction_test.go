@@ -0,0 +1,253 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryReactionRepository 是只保存在記憶體中的 ReactionRepository，並記錄單次查詢的最多消息數
+type memoryReactionRepository struct {
+	mu        sync.Mutex
+	messages  *memoryMessageRepository
+	reactions []models.Reaction
+	maxQuery  int
+}
+
+func (r *memoryReactionRepository) Add(reaction *models.Reaction) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.reactions {
+		if existing.MessageID == reaction.MessageID && existing.UserID == reaction.UserID && existing.Emoji == reaction.Emoji {
+			return false, nil
+		}
+	}
+	reaction.ID = uint(len(r.reactions) + 1)
+	r.reactions = append(r.reactions, *reaction)
+	return true, nil
+}
+
+func (r *memoryReactionRepository) Remove(messageID, userID uint, emoji string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.reactions {
+		if existing.MessageID == messageID && existing.UserID == userID && existing.Emoji == emoji {
+			r.reactions = append(r.reactions[:i], r.reactions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryReactionRepository) CountByMessages(messageIDs []uint) ([]repository.ReactionCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxQuery = max(r.maxQuery, len(messageIDs))
+	wanted := make(map[uint]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		wanted[id] = true
+	}
+	var counts []repository.ReactionCount
+	index := make(map[repository.ReactionCount]int)
+	for _, reaction := range r.reactions {
+		if !wanted[reaction.MessageID] {
+			continue
+		}
+		key := repository.ReactionCount{MessageID: reaction.MessageID, Emoji: reaction.Emoji}
+		if i, ok := index[key]; ok {
+			counts[i].Count++
+			continue
+		}
+		index[key] = len(counts)
+		key.Count = 1
+		counts = append(counts, key)
+	}
+	return counts, nil
+}
+
+func (r *memoryReactionRepository) CountByRole(roomID uint) ([]repository.SideReactionCount, error) {
+	r.mu.Lock()
+	reactions := append([]models.Reaction(nil), r.reactions...)
+	r.mu.Unlock()
+
+	var counts []repository.SideReactionCount
+	index := make(map[repository.SideReactionCount]int)
+	for _, reaction := range reactions {
+		message, err := r.messages.FindByID(reaction.MessageID)
+		if reaction.RoomID != roomID || err != nil || message.Status != models.MessageStatusPublished {
+			continue
+		}
+		key := repository.SideReactionCount{Role: message.Role, Emoji: reaction.Emoji}
+		if i, ok := index[key]; ok {
+			counts[i].Count++
+			continue
+		}
+		index[key] = len(counts)
+		key.Count = 1
+		counts = append(counts, key)
+	}
+	return counts, nil
+}
+
+// newTestReactionService 建立不自動推送的回應服務，以及一個有正反方各一則消息的房間
+func newTestReactionService() (*ReactionService, *WebSocketService, *memoryReactionRepository, *models.Room) {
+	ws, rooms, messages := newTestWebSocketService()
+	room := &models.Room{ProponentID: 1, OpponentID: 2, Spectators: []uint{3, 4}}
+	rooms.Create(room)
+	messages.Create(&models.Message{RoomID: room.ID, UserID: 1, Role: "proponent", Content: "正方論點"}) // 1
+	messages.Create(&models.Message{RoomID: room.ID, UserID: 2, Role: "opponent", Content: "反方論點"})  // 2
+
+	reactions := &memoryReactionRepository{messages: messages}
+	s := &ReactionService{
+		repo:        reactions,
+		messageRepo: messages,
+		wsService:   ws,
+		pending:     make(map[uint]map[uint]bool),
+	}
+	return s, ws, reactions, room
+}
+
+func react(s *ReactionService, client *Client, messageID uint, emoji string) {
+	s.handleReaction(client, &ClientFrame{TargetID: messageID, Message: models.Message{Content: emoji}}, true)
+}
+
+func unreact(s *ReactionService, client *Client, messageID uint, emoji string) {
+	s.handleReaction(client, &ClientFrame{TargetID: messageID, Message: models.Message{Content: emoji}}, false)
+}
+
+func TestReactionRules(t *testing.T) {
+	s, ws, reactions, room := newTestReactionService()
+	spectator := newTestClient(room.ID, 3, 16)
+	debater := newTestClient(room.ID, 1, 16)
+	debater.Role = "proponent"
+	ws.addClient(spectator)
+	ws.addClient(debater)
+	defer ws.removeClient(spectator)
+	defer ws.removeClient(debater)
+
+	react(s, debater, 2, "👏")
+	if msg := nextOfType(t, debater, "error"); msg.Content != "只有觀眾可以回應消息" {
+		t.Errorf("debater reaction: error = %q", msg.Content)
+	}
+	react(s, spectator, 1, "❤️")
+	if msg := nextOfType(t, spectator, "error"); msg.Content != "不支援的表情" {
+		t.Errorf("unknown emoji: error = %q", msg.Content)
+	}
+	react(s, spectator, 99, "👏")
+	if msg := nextOfType(t, spectator, "error"); msg.Content != "消息不存在" {
+		t.Errorf("unknown message: error = %q", msg.Content)
+	}
+	if len(reactions.reactions) != 0 || len(s.pending) != 0 {
+		t.Fatalf("rejected reactions were stored: %+v, pending %v", reactions.reactions, s.pending)
+	}
+
+	// 重複新增和撤回不存在的回應都不會產生變化
+	react(s, spectator, 1, "👏")
+	react(s, spectator, 1, "👏")
+	if len(reactions.reactions) != 1 {
+		t.Fatalf("duplicate reaction stored: %+v", reactions.reactions)
+	}
+	s.flush()
+	unreact(s, spectator, 1, "🔥")
+	if len(s.pending) != 0 {
+		t.Errorf("removing a missing reaction marked the message: %v", s.pending)
+	}
+	unreact(s, spectator, 1, "👏")
+	unreact(s, spectator, 1, "👏")
+	if len(reactions.reactions) != 0 || !s.pending[room.ID][1] {
+		t.Errorf("reactions = %+v, pending = %v", reactions.reactions, s.pending)
+	}
+}
+
+func TestReactionFlushBatchesCounts(t *testing.T) {
+	s, ws, _, room := newTestReactionService()
+	alice := newTestClient(room.ID, 3, 16)
+	bob := newTestClient(room.ID, 4, 16)
+	ws.addClient(alice)
+	ws.addClient(bob)
+	defer ws.removeClient(alice)
+	defer ws.removeClient(bob)
+
+	react(s, alice, 1, "👏")
+	react(s, bob, 1, "👏")
+	react(s, bob, 1, "🤔")
+	react(s, alice, 2, "👎")
+	unreact(s, alice, 2, "👎")
+
+	// 所有變化在同一次推送中送出，撤回到零的消息也帶上空的計數
+	s.flush()
+	msg := nextOfType(t, alice, "reaction_counts")
+	counts := msg.Data.(map[uint]map[string]int)
+	if msg.Channel != models.ChannelGallery || len(counts) != 2 || counts[1]["👏"] != 2 || counts[1]["🤔"] != 1 || len(counts[2]) != 0 {
+		t.Fatalf("reaction_counts = %+v on %q", counts, msg.Channel)
+	}
+
+	s.flush()
+	react(s, bob, 2, "🔥")
+	s.flush()
+	// 沒有變化時不推送，bob 只會收到兩次更新
+	if counts := nextOfType(t, bob, "reaction_counts").Data.(map[uint]map[string]int); len(counts) != 2 {
+		t.Errorf("first batch = %+v", counts)
+	}
+	if counts := nextOfType(t, bob, "reaction_counts").Data.(map[uint]map[string]int); len(counts) != 1 || counts[2]["🔥"] != 1 {
+		t.Errorf("second batch = %+v, want only the changed message", counts)
+	}
+}
+
+func TestReactionServiceFlushesEverySecond(t *testing.T) {
+	ws, rooms, messages := newTestWebSocketService()
+	room := &models.Room{ProponentID: 1}
+	rooms.Create(room)
+	messages.Create(&models.Message{RoomID: room.ID, UserID: 1, Role: "proponent"})
+	s := NewReactionService(&memoryReactionRepository{messages: messages}, messages, ws)
+	defer s.Close()
+
+	spectator := newTestClient(room.ID, 2, 16)
+	ws.addClient(spectator)
+	defer ws.removeClient(spectator)
+
+	ws.handleFrame(spectator, &ClientFrame{Message: models.Message{Type: "reaction", Content: "👍"}, TargetID: 1})
+	deadline := time.After(reactionFlushInterval + time.Second)
+	for {
+		select {
+		case msg := <-spectator.SendChan:
+			if msg.Type != "reaction_counts" {
+				continue
+			}
+			if counts := msg.Data.(map[uint]map[string]int); counts[1]["👍"] != 1 {
+				t.Errorf("reaction_counts = %+v", counts)
+			}
+			return
+		case <-deadline:
+			t.Fatal("reaction counts were not pushed")
+		}
+	}
+}
+
+func TestApplause(t *testing.T) {
+	s, ws, _, room := newTestReactionService()
+	alice := newTestClient(room.ID, 3, 16)
+	bob := newTestClient(room.ID, 4, 16)
+	ws.addClient(alice)
+	ws.addClient(bob)
+	defer ws.removeClient(alice)
+	defer ws.removeClient(bob)
+
+	react(s, alice, 1, "👏")
+	react(s, alice, 1, "🔥")
+	react(s, bob, 1, "👏")
+	react(s, bob, 1, "😂")
+	react(s, alice, 2, "👍")
+	react(s, alice, 2, "👎")
+	react(s, bob, 2, "👎")
+
+	applause, err := s.Applause(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applause["proponent"] != 3 || applause["opponent"] != -1 {
+		t.Errorf("applause = %v, want proponent 3 and opponent -1", applause)
+	}
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 重播速度的上下限
+const (
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 16
+)
+
+// ReplayService 讓客戶端按原本的時間間隔重看已結束的辯論
+type ReplayService struct {
+	roomRepo    repository.RoomRepository
+	messageRepo repository.MessageRepository
+}
+
+func NewReplayService(roomRepo repository.RoomRepository, messageRepo repository.MessageRepository) *ReplayService {
+	return &ReplayService{
+		roomRepo:    roomRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// ReplayControl 是客戶端在重播時發送的控制消息
+// Type 為 play、pause、seek 或 speed，Position 為 seek 的目標位置（毫秒），Speed 為播放倍速
+// seek 時伺服器先發送 replay_reset，再立即補發目標位置之前的消息，客戶端應據此重建畫面
+type ReplayControl struct {
+	Type     string
+	Position int64
+	Speed    float64
+}
+
+// ReplayState 是重播狀態，每次狀態改變時發送給客戶端
+type ReplayState struct {
+	Position int64   `json:"position_ms"`
+	Duration int64   `json:"duration_ms"`
+	Playing  bool    `json:"playing"`
+	Speed    float64 `json:"speed"`
+}
+
+// LoadReplay 讀取已結束房間的所有消息，用於之後的重播
+func (s *ReplayService) LoadReplay(roomID uint) ([]models.Message, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return nil, errors.New("房間不存在")
+	}
+	if room.Status != models.RoomStatusFinished {
+		return nil, errors.New("辯論尚未結束")
+	}
+	return s.messageRepo.FindByRoom(roomID)
+}
+
+// HandleReplay 在 WebSocket 連接上重播消息，直到客戶端斷線
+// 讀取控制消息在獨立的 goroutine 中進行，所有寫入都由重播循環負責
+func (s *ReplayService) HandleReplay(conn *websocket.Conn, messages []models.Message) {
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		conn.Close()
+	}()
+
+	controls := make(chan ReplayControl)
+	go func() {
+		defer close(controls)
+
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		conn.SetPongHandler(func(string) error {
+			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			return nil
+		})
+
+		for {
+			var control ReplayControl
+			if err := conn.ReadJSON(&control); err != nil {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+					log.Printf("replay unexpected close error: %v", err)
+				}
+				return
+			}
+			select {
+			case controls <- control:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(54 * time.Second)
+	defer ticker.Stop()
+
+	send := func(message *models.Message) error {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(message)
+	}
+	ping := func() error {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteMessage(websocket.PingMessage, nil)
+	}
+
+	if err := runReplay(messages, controls, ticker.C, send, ping); err != nil {
+		return
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	conn.WriteMessage(websocket.CloseMessage, []byte{})
+}
+
+// replayClock 是重播的虛擬時鐘，記錄上次調整時的位置，播放中的位置隨實際時間按倍速前進
+type replayClock struct {
+	base    time.Duration
+	since   time.Time
+	speed   float64
+	playing bool
+}
+
+// position 返回虛擬時鐘在 now 時的位置
+func (c *replayClock) position(now time.Time) time.Duration {
+	if !c.playing {
+		return c.base
+	}
+	return c.base + time.Duration(float64(now.Sub(c.since))*c.speed)
+}
+
+// set 從 now 開始以新的位置和狀態繼續計時
+func (c *replayClock) set(now time.Time, position time.Duration, playing bool, speed float64) {
+	c.base = position
+	c.since = now
+	c.playing = playing
+	c.speed = speed
+}
+
+// runReplay 是重播循環，按消息的原始相對時間發送，並處理客戶端的控制消息
+// controls 被關閉表示客戶端已斷線，此時循環正常結束
+func runReplay(messages []models.Message, controls <-chan ReplayControl, heartbeat <-chan time.Time,
+	send func(*models.Message) error, ping func() error) error {
+	if len(messages) == 0 {
+		return send(&models.Message{Type: "replay_end"})
+	}
+
+	roomID := messages[0].RoomID
+	start := messages[0].CreatedAt
+	offsets := make([]time.Duration, len(messages))
+	for i, msg := range messages {
+		offsets[i] = msg.CreatedAt.Sub(start)
+	}
+	duration := offsets[len(offsets)-1]
+
+	clock := &replayClock{}
+	clock.set(time.Now(), 0, true, 1)
+	next := 0
+
+	sendState := func() error {
+		return send(&models.Message{
+			Type:   "replay_state",
+			RoomID: roomID,
+			Data: ReplayState{
+				Position: clock.position(time.Now()).Milliseconds(),
+				Duration: duration.Milliseconds(),
+				Playing:  clock.playing,
+				Speed:    clock.speed,
+			},
+		})
+	}
+	// seekTo 跳到指定位置：通知客戶端清空畫面，再立即補發該位置之前的所有消息
+	seekTo := func(target time.Duration) error {
+		if err := send(&models.Message{Type: "replay_reset", RoomID: roomID}); err != nil {
+			return err
+		}
+		next = 0
+		for next < len(messages) && offsets[next] < target {
+			if err := send(&messages[next]); err != nil {
+				return err
+			}
+			next++
+		}
+		return nil
+	}
+
+	if err := sendState(); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		// 重新設定計時器到下一則消息的時間，暫停或播完時不觸發
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		var fire <-chan time.Time
+		if clock.playing && next < len(messages) {
+			wait := time.Duration(float64(offsets[next]-clock.position(time.Now())) / clock.speed)
+			timer.Reset(max(wait, 0))
+			fire = timer.C
+		}
+
+		select {
+		case <-fire:
+			// 同一時間點的消息一起發送
+			now := clock.position(time.Now())
+			for next < len(messages) && offsets[next] <= now {
+				if err := send(&messages[next]); err != nil {
+					return err
+				}
+				next++
+			}
+			if next == len(messages) {
+				clock.set(time.Now(), duration, false, clock.speed)
+				if err := sendState(); err != nil {
+					return err
+				}
+				if err := send(&models.Message{Type: "replay_end", RoomID: roomID}); err != nil {
+					return err
+				}
+			}
+
+		case control, ok := <-controls:
+			if !ok {
+				return nil
+			}
+
+			now := time.Now()
+			position := clock.position(now)
+			switch control.Type {
+			case "play":
+				if next == len(messages) {
+					// 播完後再播放從頭開始
+					if err := seekTo(0); err != nil {
+						return err
+					}
+					position = 0
+				}
+				clock.set(now, position, true, clock.speed)
+			case "pause":
+				clock.set(now, position, false, clock.speed)
+			case "seek":
+				target := min(max(time.Duration(control.Position)*time.Millisecond, 0), duration)
+				if err := seekTo(target); err != nil {
+					return err
+				}
+				clock.set(now, target, clock.playing, clock.speed)
+			case "speed":
+				speed := min(max(control.Speed, minReplaySpeed), maxReplaySpeed)
+				clock.set(now, position, clock.playing, speed)
+			default:
+				continue
+			}
+			if err := sendState(); err != nil {
+				return err
+			}
+
+		case <-heartbeat:
+			if err := ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
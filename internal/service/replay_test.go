@@ -0,0 +1,77 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"testing"
+	"time"
+)
+
+func TestRunReplay(t *testing.T) {
+	start := time.Now()
+	messages := []models.Message{}
+	for i, offset := range []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond, 10 * time.Second} {
+		msg := models.Message{Model: gormModel(uint(i + 1)), RoomID: 1, Type: "text"}
+		msg.CreatedAt = start.Add(offset)
+		messages = append(messages, msg)
+	}
+
+	controls := make(chan ReplayControl)
+	sent := make(chan *models.Message, 64)
+	finished := make(chan error, 1)
+	go func() {
+		finished <- runReplay(messages, controls, nil, func(msg *models.Message) error {
+			sent <- msg
+			return nil
+		}, func() error { return nil })
+	}()
+
+	expect := func(want string, id uint) *models.Message {
+		t.Helper()
+		select {
+		case msg := <-sent:
+			if msg.Type != want || (id != 0 && msg.ID != id) {
+				t.Fatalf("expected %s #%d, got %s #%d", want, id, msg.Type, msg.ID)
+			}
+			return msg
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+		return nil
+	}
+
+	// 開始後自動播放，前三則消息按原本的間隔送出
+	expect("replay_state", 0)
+	expect("text", 1)
+	expect("text", 2)
+	expect("text", 3)
+
+	// 暫停後跳到 9 秒：先重置，再補發之前的三則消息
+	controls <- ReplayControl{Type: "pause"}
+	if state := expect("replay_state", 0).Data.(ReplayState); state.Playing {
+		t.Fatal("expected replay to be paused")
+	}
+	controls <- ReplayControl{Type: "seek", Position: 9000}
+	expect("replay_reset", 0)
+	expect("text", 1)
+	expect("text", 2)
+	expect("text", 3)
+	if state := expect("replay_state", 0).Data.(ReplayState); state.Position != 9000 || state.Duration != 10000 {
+		t.Fatalf("unexpected state after seek: %+v", state)
+	}
+
+	// 以 16 倍速播放，剩下的 1 秒約 62 毫秒後送出最後一則消息
+	controls <- ReplayControl{Type: "speed", Speed: 100}
+	if state := expect("replay_state", 0).Data.(ReplayState); state.Speed != maxReplaySpeed {
+		t.Fatalf("expected speed to be clamped to %v, got %v", maxReplaySpeed, state.Speed)
+	}
+	controls <- ReplayControl{Type: "play"}
+	expect("replay_state", 0)
+	expect("text", 4)
+	expect("replay_state", 0)
+	expect("replay_end", 0)
+
+	close(controls)
+	if err := <-finished; err != nil {
+		t.Fatal(err)
+	}
+}
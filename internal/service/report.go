@@ -0,0 +1,156 @@
+package service
+
+import (
+	"debate_web/internal/pdf"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReportService 產生已結束辯論的 PDF 報告
+type ReportService struct {
+	roomRepo          repository.RoomRepository
+	messageRepo       repository.MessageRepository
+	transcriptService *TranscriptService
+	reactionService   *ReactionService
+}
+
+func NewReportService(roomRepo repository.RoomRepository, messageRepo repository.MessageRepository, transcript *TranscriptService, reaction *ReactionService) *ReportService {
+	return &ReportService{
+		roomRepo:          roomRepo,
+		messageRepo:       messageRepo,
+		transcriptService: transcript,
+		reactionService:   reaction,
+	}
+}
+
+// 報告的文字樣式
+var (
+	reportTitle   = pdf.Style{Size: 24, Center: true}
+	reportHeading = pdf.Style{Size: 15}
+	reportSpeaker = pdf.Style{Size: 11}
+	reportBody    = pdf.Style{Size: 10.5, Indent: 16}
+	reportNote    = pdf.Style{Size: 9, Indent: 16, Gray: 0.45}
+	reportCover   = pdf.Style{Size: 12, Center: true}
+)
+
+// WriteReport 產生房間的 PDF 報告並寫入 w
+// 報告包含封面、按發言輪次整理的辯論場紀錄以及觀眾掌聲統計，只有已結束的辯論可以匯出
+func (s *ReportService) WriteReport(w io.Writer, roomID uint) error {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return errors.New("房間不存在")
+	}
+	if room.Status != models.RoomStatusFinished {
+		return errors.New("辯論尚未結束")
+	}
+
+	meta, err := s.transcriptService.Meta(roomID)
+	if err != nil {
+		return err
+	}
+
+	doc := pdf.New(meta.Topic)
+	s.writeCover(doc, meta)
+
+	doc.NewPage()
+	if err := s.writeFloor(doc, meta); err != nil {
+		return err
+	}
+
+	if err := s.writeAudience(doc, roomID); err != nil {
+		return err
+	}
+
+	_, err = doc.WriteTo(w)
+	return err
+}
+
+// writeCover 輸出封面：辯題、參與者和日期
+func (s *ReportService) writeCover(doc *pdf.Document, meta *TranscriptMeta) {
+	doc.Space(180)
+	doc.Write("辯論報告", reportCover)
+	doc.Space(16)
+	doc.Write(meta.Topic, reportTitle)
+	doc.Space(40)
+
+	for _, p := range meta.Participants {
+		if p.Role == "spectator" {
+			continue
+		}
+		doc.Write(fmt.Sprintf("%s：%s", roleNames[p.Role], p.Username), reportCover)
+	}
+
+	spectators := 0
+	for _, p := range meta.Participants {
+		if p.Role == "spectator" {
+			spectators++
+		}
+	}
+	doc.Write(fmt.Sprintf("觀眾：%d 人", spectators), reportCover)
+	doc.Space(24)
+
+	date := meta.CreatedAt
+	if meta.StartTime != nil {
+		date = *meta.StartTime
+	}
+	doc.Write(date.Format("2006 年 1 月 2 日"), reportCover)
+	doc.Write(fmt.Sprintf("賽制：%s", meta.Format), reportCover)
+}
+
+// writeFloor 輸出辯論場紀錄，同一發言者連續的消息歸為同一輪
+func (s *ReportService) writeFloor(doc *pdf.Document, meta *TranscriptMeta) error {
+	doc.Write("辯論紀錄", reportHeading)
+	doc.Rule()
+
+	usernames := make(map[uint]string, len(meta.Participants))
+	for _, p := range meta.Participants {
+		usernames[p.UserID] = p.Username
+	}
+
+	var lastSpeaker string
+	return s.messageRepo.EachByRoom(meta.RoomID, transcriptBatchSize, func(batch []models.Message) error {
+		for _, msg := range batch {
+			if msg.Channel == models.ChannelGallery {
+				continue
+			}
+
+			if msg.Role == "system" {
+				doc.Write(fmt.Sprintf("%s  %s", msg.CreatedAt.Format("15:04:05"), msg.Content), reportNote)
+				lastSpeaker = ""
+				continue
+			}
+
+			speaker := fmt.Sprintf("%s %s", roleNames[msg.Role], usernames[msg.UserID])
+			if speaker != lastSpeaker {
+				doc.Space(6)
+				doc.Write(fmt.Sprintf("%s  %s", speaker, msg.CreatedAt.Format("15:04:05")), reportSpeaker)
+				lastSpeaker = speaker
+			}
+
+			content := msg.Content
+			if msg.Kind != "" {
+				content = fmt.Sprintf("[%s] %s", msg.Kind, content)
+			}
+			doc.Write(content, reportBody)
+		}
+		return nil
+	})
+}
+
+// writeAudience 輸出觀眾對雙方的掌聲統計
+func (s *ReportService) writeAudience(doc *pdf.Document, roomID uint) error {
+	applause, err := s.reactionService.Applause(roomID)
+	if err != nil {
+		return err
+	}
+
+	doc.Space(18)
+	doc.Write("觀眾反應", reportHeading)
+	doc.Rule()
+	doc.Write(fmt.Sprintf("正方掌聲：%d", applause["proponent"]), reportBody)
+	doc.Write(fmt.Sprintf("反方掌聲：%d", applause["opponent"]), reportBody)
+	return nil
+}
@@ -0,0 +1,273 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 檢舉和審核的限制
+const (
+	maxReportReasonLength = 200
+	reviewContextSize     = 3 // 審核隊列中每則消息附帶的前文數量
+)
+
+// 審核動作
+const (
+	ReviewApprove = "approve" // 發布暫緩的消息，或保留被檢舉的消息
+	ReviewDelete  = "delete"  // 刪除消息，並通知在線客戶端移除
+	ReviewWarn    = "warn"    // 警告發送者
+	ReviewMute    = "mute"    // 禁言發送者
+)
+
+// ReviewService 處理消息檢舉和主持人的審核隊列
+type ReviewService struct {
+	repo            repository.ModerationRepository
+	messageRepo     repository.MessageRepository
+	roomRepo        repository.RoomRepository
+	userRepo        repository.UserRepository
+	sanctionService *SanctionService
+	wsService       *WebSocketService
+}
+
+// NewReviewService 創建審核服務並註冊檢舉命令
+func NewReviewService(repo repository.ModerationRepository, messageRepo repository.MessageRepository, roomRepo repository.RoomRepository, userRepo repository.UserRepository, sanction *SanctionService, ws *WebSocketService) *ReviewService {
+	s := &ReviewService{
+		repo:            repo,
+		messageRepo:     messageRepo,
+		roomRepo:        roomRepo,
+		userRepo:        userRepo,
+		sanctionService: sanction,
+		wsService:       ws,
+	}
+
+	ws.HandleFrame("report", s.handleReport)
+
+	return s
+}
+
+// ReviewItem 是審核隊列中的一則消息
+type ReviewItem struct {
+	Message models.Message         `json:"message"`
+	Held    bool                   `json:"held"` // 是否被審核過濾器暫緩
+	Reports []models.MessageReport `json:"reports"`
+	Context []models.Message       `json:"context"` // 消息之前的幾則已發布消息
+}
+
+// ReviewAction 是主持人對一則消息的審核動作
+type ReviewAction struct {
+	Action  string `json:"action"`
+	Reason  string `json:"reason"`
+	Minutes int    `json:"minutes"` // mute 的時長，0 表示預設值
+}
+
+// ReportMessage 檢舉消息，只有房間的參與者可以檢舉
+func (s *ReviewService) ReportMessage(messageID, reporterID uint, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > maxReportReasonLength {
+		return fmt.Errorf("檢舉原因長度必須在 1 到 %d 字之間", maxReportReasonLength)
+	}
+
+	message, err := s.messageRepo.FindByID(messageID)
+	if err != nil || message.Status != models.MessageStatusPublished {
+		return errors.New("消息不存在")
+	}
+	if message.UserID == reporterID {
+		return errors.New("不能檢舉自己的消息")
+	}
+
+	room, err := s.roomRepo.FindByID(message.RoomID)
+	if err != nil {
+		return errors.New("消息不存在")
+	}
+	if !isRoomParticipant(room, reporterID) {
+		return errors.New("只有房間的參與者可以檢舉消息")
+	}
+
+	report := &models.MessageReport{
+		MessageID:  message.ID,
+		ReporterID: reporterID,
+		RoomID:     message.RoomID,
+		Reason:     reason,
+	}
+	added, err := s.repo.AddReport(report)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return errors.New("您已檢舉過此消息")
+	}
+
+	s.wsService.SendToModerators(message.RoomID, &models.Message{
+		Type:    "message_reported",
+		RoomID:  message.RoomID,
+		Content: reason,
+		Data:    report,
+	})
+	return nil
+}
+
+// ReviewQueue 返回房間內等待審核和被檢舉的消息，僅限可以管理房間的用戶
+func (s *ReviewService) ReviewQueue(roomID, userID uint) ([]ReviewItem, error) {
+	if err := s.checkModerator(roomID, userID); err != nil {
+		return nil, err
+	}
+
+	held, err := s.messageRepo.FindHeldByRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+	reports, err := s.repo.FindOpenReports(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[uint]*ReviewItem)
+	for _, msg := range held {
+		items[msg.ID] = &ReviewItem{Message: msg, Held: true}
+	}
+	var reported []uint
+	for _, report := range reports {
+		if items[report.MessageID] == nil {
+			reported = append(reported, report.MessageID)
+			items[report.MessageID] = &ReviewItem{}
+		}
+	}
+	if len(reported) > 0 {
+		messages, err := s.messageRepo.FindByIDs(reported)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			items[msg.ID].Message = msg
+		}
+	}
+	for _, report := range reports {
+		items[report.MessageID].Reports = append(items[report.MessageID].Reports, report)
+	}
+
+	queue := make([]ReviewItem, 0, len(items))
+	for id, item := range items {
+		if item.Message.ID == 0 {
+			// 被檢舉的消息已被刪除
+			continue
+		}
+		item.Context, err = s.messageRepo.FindBefore(roomID, id, reviewContextSize)
+		if err != nil {
+			return nil, err
+		}
+		if item.Reports == nil {
+			item.Reports = []models.MessageReport{}
+		}
+		queue = append(queue, *item)
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		return queue[i].Message.ID < queue[j].Message.ID
+	})
+	return queue, nil
+}
+
+// Review 執行主持人對消息的審核動作，並結案該消息所有的檢舉
+// 警告和禁言不改變消息本身，暫緩的消息仍需批准或刪除
+func (s *ReviewService) Review(roomID, messageID, moderatorID uint, action ReviewAction) error {
+	if err := s.checkModerator(roomID, moderatorID); err != nil {
+		return err
+	}
+
+	message, err := s.messageRepo.FindByID(messageID)
+	if err != nil || message.RoomID != roomID {
+		return errors.New("消息不存在")
+	}
+
+	switch action.Action {
+	case ReviewApprove:
+		if message.Status == models.MessageStatusHeld {
+			if err := s.messageRepo.UpdateStatus(message.ID, models.MessageStatusPublished); err != nil {
+				return err
+			}
+			message.Status = models.MessageStatusPublished
+			s.wsService.BroadcastToRoom(roomID, message)
+		}
+
+	case ReviewDelete:
+		if err := s.messageRepo.Delete(message.ID); err != nil {
+			return err
+		}
+		s.wsService.BroadcastToRoom(roomID, &models.Message{
+			Type:   "message_deleted",
+			RoomID: roomID,
+			Data:   map[string]uint{"message_id": message.ID},
+		})
+
+	case ReviewWarn:
+		s.wsService.SendToUser(roomID, message.UserID, &models.Message{
+			Type:    "warning",
+			RoomID:  roomID,
+			Content: action.Reason,
+			Data:    map[string]uint{"message_id": message.ID},
+		})
+
+	case ReviewMute:
+		err := s.sanctionService.Mute(roomID, moderatorID, SanctionRequest{
+			UserID:  message.UserID,
+			Minutes: action.Minutes,
+			Reason:  action.Reason,
+		})
+		if err != nil {
+			return err
+		}
+
+	default:
+		return errors.New("無效的審核動作")
+	}
+
+	return s.repo.ResolveReports(message.ID, moderatorID)
+}
+
+// checkModerator 確認用戶是房間的主持人或擁有全站審核權限
+func (s *ReviewService) checkModerator(roomID, userID uint) error {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return errors.New("房間不存在")
+	}
+	if !canModerateRoom(s.userRepo, room, userID) {
+		return errors.New("只有主持人可以審核消息")
+	}
+	return nil
+}
+
+// handleReport 處理客戶端的檢舉命令
+// frame.TargetID 為消息 ID，frame.Content 為檢舉原因
+func (s *ReviewService) handleReport(client *Client, frame *ClientFrame) {
+	message, err := s.messageRepo.FindByID(frame.TargetID)
+	if err != nil || message.RoomID != client.RoomID {
+		s.wsService.SendError(client, "消息不存在")
+		return
+	}
+	if err := s.ReportMessage(message.ID, client.UserID, frame.Content); err != nil {
+		s.wsService.SendError(client, err.Error())
+		return
+	}
+	s.wsService.SendToClient(client, &models.Message{
+		Type:   "report_received",
+		RoomID: client.RoomID,
+		Data:   map[string]uint{"message_id": message.ID},
+	})
+}
+
+// isRoomParticipant 檢查用戶是否為房間的主持人、辯手或觀眾
+func isRoomParticipant(room *models.Room, userID uint) bool {
+	if userID == room.OwnerID || userID == room.ProponentID || userID == room.OpponentID {
+		return true
+	}
+	for _, spectatorID := range room.Spectators {
+		if spectatorID == userID {
+			return true
+		}
+	}
+	return false
+}
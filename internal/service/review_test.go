@@ -0,0 +1,198 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryModerationRepository 是只保存在記憶體中的 ModerationRepository
+type memoryModerationRepository struct {
+	mu        sync.Mutex
+	reports   []models.MessageReport
+	sanctions []models.Sanction
+}
+
+func (r *memoryModerationRepository) AddReport(report *models.MessageReport) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.reports {
+		if existing.MessageID == report.MessageID && existing.ReporterID == report.ReporterID {
+			return false, nil
+		}
+	}
+	report.ID = uint(len(r.reports) + 1)
+	report.Status = models.ReportStatusOpen
+	r.reports = append(r.reports, *report)
+	return true, nil
+}
+
+func (r *memoryModerationRepository) FindOpenReports(roomID uint) ([]models.MessageReport, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var reports []models.MessageReport
+	for _, report := range r.reports {
+		if report.RoomID == roomID && report.Status == models.ReportStatusOpen {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+func (r *memoryModerationRepository) ResolveReports(messageID, moderatorID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.reports {
+		if r.reports[i].MessageID == messageID {
+			r.reports[i].Status = models.ReportStatusResolved
+			r.reports[i].ResolvedBy = moderatorID
+		}
+	}
+	return nil
+}
+
+func (r *memoryModerationRepository) CreateSanction(sanction *models.Sanction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sanction.ID = uint(len(r.sanctions) + 1)
+	r.sanctions = append(r.sanctions, *sanction)
+	return nil
+}
+
+func (r *memoryModerationRepository) FindActiveSanctions(kind models.SanctionKind) ([]models.Sanction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var active []models.Sanction
+	for _, sanction := range r.sanctions {
+		if isActiveSanction(sanction, kind) {
+			active = append(active, sanction)
+		}
+	}
+	return active, nil
+}
+
+func isActiveSanction(sanction models.Sanction, kind models.SanctionKind) bool {
+	return sanction.Kind == kind && sanction.RevokedAt == nil &&
+		(sanction.ExpiresAt == nil || sanction.ExpiresAt.After(time.Now()))
+}
+
+func (r *memoryModerationRepository) HasActiveSanction(roomID, userID uint, kind models.SanctionKind) (bool, error) {
+	active, _ := r.FindActiveSanctions(kind)
+	for _, sanction := range active {
+		if sanction.RoomID == roomID && sanction.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryModerationRepository) RevokeSanctions(roomID, userID uint, kind models.SanctionKind, moderatorID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	revoked := false
+	now := time.Now()
+	for i, sanction := range r.sanctions {
+		if sanction.RoomID == roomID && sanction.UserID == userID && isActiveSanction(sanction, kind) {
+			r.sanctions[i].RevokedAt = &now
+			r.sanctions[i].RevokedBy = moderatorID
+			revoked = true
+		}
+	}
+	return revoked, nil
+}
+
+func (r *memoryModerationRepository) FindSanctionsByRoom(roomID uint) ([]models.Sanction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sanctions []models.Sanction
+	for _, sanction := range r.sanctions {
+		if sanction.RoomID == roomID {
+			sanctions = append(sanctions, sanction)
+		}
+	}
+	return sanctions, nil
+}
+
+func TestReviewQueueAndActions(t *testing.T) {
+	ws, rooms, messages := newTestWebSocketService()
+	ws.UseModeration(NewModerationPipeline(NewWordFilter(nil, []string{"作弊"}, nil)))
+	moderation := &memoryModerationRepository{}
+	users := &memoryUserRepository{}
+	sanction := NewSanctionService(moderation, rooms, users, NewRoomService(rooms, moderation, ws), ws)
+	review := NewReviewService(moderation, messages, rooms, users, sanction, ws)
+
+	room := &models.Room{Model: gormModel(1), OwnerID: 9, ProponentID: 1, OpponentID: 2, Spectators: []uint{3}}
+	rooms.rooms[room.ID] = room
+
+	proponent := newTestClient(1, 1, 32)
+	proponent.Role = "proponent"
+	spectator := newTestClient(1, 3, 32)
+	ws.addClient(proponent)
+	ws.addClient(spectator)
+	defer ws.removeClient(proponent)
+	defer ws.removeClient(spectator)
+
+	ws.handleMessage(proponent, &models.Message{Content: "第一點"})   // 1
+	ws.handleMessage(proponent, &models.Message{Content: "對方作弊"})  // 2，暫緩
+	ws.handleMessage(proponent, &models.Message{Content: "不當的發言"}) // 3
+
+	if err := review.ReportMessage(3, 3, "人身攻擊"); err != nil {
+		t.Fatalf("report: %v", err)
+	}
+	if err := review.ReportMessage(3, 3, "人身攻擊"); err == nil || err.Error() != "您已檢舉過此消息" {
+		t.Fatalf("duplicate report error = %v", err)
+	}
+	if err := review.ReportMessage(3, 7, "路人"); err == nil || err.Error() != "只有房間的參與者可以檢舉消息" {
+		t.Fatalf("outsider report error = %v", err)
+	}
+	if _, err := review.ReviewQueue(1, 3); err == nil {
+		t.Fatal("non-moderator can read the review queue")
+	}
+
+	queue, err := review.ReviewQueue(1, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 2 || queue[0].Message.ID != 2 || !queue[0].Held || queue[1].Message.ID != 3 || len(queue[1].Reports) != 1 {
+		t.Fatalf("unexpected queue: %+v", queue)
+	}
+	if len(queue[1].Context) != 1 || queue[1].Context[0].ID != 1 {
+		t.Fatalf("context = %+v, want only the published message before it", queue[1].Context)
+	}
+
+	if err := review.Review(1, 3, 9, ReviewAction{Action: ReviewDelete}); err != nil {
+		t.Fatal(err)
+	}
+	if err := review.Review(1, 2, 9, ReviewAction{Action: ReviewApprove}); err != nil {
+		t.Fatal(err)
+	}
+	if err := review.Review(1, 1, 9, ReviewAction{Action: ReviewMute, Minutes: 5}); err != nil {
+		t.Fatal(err)
+	}
+	ws.handleFrame(proponent, &ClientFrame{Message: models.Message{Content: "禁言中"}})
+
+	// 觀眾看到：第一點、不當的發言、刪除通知、批准後的消息
+	var types []string
+	for len(types) < 4 {
+		msg := nextNonSystem(spectator)
+		types = append(types, msg.Type+":"+msg.Content)
+	}
+	want := []string{"chat:第一點", "chat:不當的發言", "message_deleted:", "chat:對方作弊"}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("spectator frames = %q, want %q", types, want)
+		}
+	}
+
+	published, _ := messages.FindByRoom(1)
+	if len(published) != 2 || published[0].ID != 1 || published[1].ID != 2 {
+		t.Fatalf("published history = %+v", published)
+	}
+	if queue, _ := review.ReviewQueue(1, 9); len(queue) != 0 {
+		t.Fatalf("queue after review = %+v", queue)
+	}
+	if _, muted := ws.MutedUntil(1, 1); !muted {
+		t.Fatal("author was not muted")
+	}
+}
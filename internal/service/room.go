@@ -5,25 +5,44 @@ import (
 	"debate_web/internal/repository/models"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 type RoomService struct {
-	repo      repository.RoomRepository
-	wsService *WebSocketService
+	repo           repository.RoomRepository
+	moderationRepo repository.ModerationRepository
+	wsService      *WebSocketService
+	emails         *EmailService // 設定時，用戶需要驗證電子郵件後才能加入房間
 }
 
-func NewRoomService(repo repository.RoomRepository, ws *WebSocketService) *RoomService {
+func NewRoomService(repo repository.RoomRepository, moderationRepo repository.ModerationRepository, ws *WebSocketService) *RoomService {
 	return &RoomService{
-		repo:      repo,
-		wsService: ws,
+		repo:           repo,
+		moderationRepo: moderationRepo,
+		wsService:      ws,
 	}
 }
 
-func (s *RoomService) CreateRoom(room *models.Room) error {
+// CreateRoom 創建房間，創建者成為房間主持人
+func (s *RoomService) CreateRoom(room *models.Room, ownerID uint) error {
+	if room.Format == "" {
+		room.Format = DefaultFormat
+	}
+	if !IsValidDebateFormat(room.Format) {
+		return errors.New("無效的辯論賽制")
+	}
+
+	room.Tags = normalizeTags(room.Tags)
+	room.OwnerID = ownerID
 	room.Status = models.RoomStatusWaiting
 	return s.repo.Create(room)
 }
 
+// RequireVerifiedEmail 要求用戶驗證電子郵件後才能加入房間，必須在開始接受請求之前呼叫
+func (s *RoomService) RequireVerifiedEmail(emails *EmailService) {
+	s.emails = emails
+}
+
 func (s *RoomService) GetRoom(id uint) (*models.Room, error) {
 	room, err := s.repo.FindByID(id)
 	if err != nil {
@@ -38,6 +57,25 @@ func (s *RoomService) JoinRoom(roomID uint, userID uint, role string) error {
 		return err
 	}
 
+	banned, err := s.moderationRepo.HasActiveSanction(roomID, userID, models.SanctionBan)
+	if err != nil {
+		return err
+	}
+	if banned {
+		return errors.New("您已被禁止加入此房間")
+	}
+
+	if s.emails != nil {
+		if err := s.emails.CheckVerified(userID); err != nil {
+			return err
+		}
+	}
+
+	// 觀眾可以在辯論結束前隨時加入
+	if role == "spectator" {
+		return s.joinAsSpectator(room, userID)
+	}
+
 	if room.Status != models.RoomStatusWaiting {
 		return errors.New("房間狀態不允許加入")
 	}
@@ -68,7 +106,33 @@ func (s *RoomService) JoinRoom(roomID uint, userID uint, role string) error {
 	}
 
 	// 透過 WebSocket 發送系統消息
-	s.wsService.BroadcastSystemMessage(roomID, fmt.Sprintf("用戶 %d 以 %s 身份加入房間", userID, role))
+	s.wsService.BroadcastSystemMessage(roomID, fmt.Sprintf("%s 以 %s 身份加入房間", s.wsService.DisplayName(userID), role))
+
+	return nil
+}
+
+// joinAsSpectator 將用戶加入房間的觀眾列表
+func (s *RoomService) joinAsSpectator(room *models.Room, userID uint) error {
+	if room.Status == models.RoomStatusFinished {
+		return errors.New("房間狀態不允許加入")
+	}
+
+	if room.ProponentID == userID || room.OpponentID == userID {
+		return errors.New("辯手無法以觀眾身份加入")
+	}
+
+	for _, spectatorID := range room.Spectators {
+		if spectatorID == userID {
+			return nil
+		}
+	}
+
+	room.Spectators = append(room.Spectators, userID)
+	if err := s.repo.Update(room); err != nil {
+		return err
+	}
+
+	s.wsService.BroadcastSystemMessage(room.ID, fmt.Sprintf("%s 以 spectator 身份加入房間", s.wsService.DisplayName(userID)))
 
 	return nil
 }
@@ -80,16 +144,44 @@ func (s *RoomService) LeaveRoom(roomID, userID uint) error {
 		return errors.New("房間不存在")
 	}
 
+	// 辯手不能在辯論進行中離開，觀眾隨時可以離開
+	if room.Status == models.RoomStatusOngoing && (room.ProponentID == userID || room.OpponentID == userID) {
+		return errors.New("辯論進行中，無法離開")
+	}
+
+	return s.removeParticipant(room, userID)
+}
+
+// RemoveParticipant 將用戶強制移出房間，用於主持人踢出用戶，辯論進行中的辯手被移出時辯論結束
+func (s *RoomService) RemoveParticipant(roomID, userID uint) error {
+	room, err := s.GetRoom(roomID)
+	if err != nil {
+		return errors.New("房間不存在")
+	}
+	return s.removeParticipant(room, userID)
+}
+
+// removeParticipant 將觀眾或辯手移出房間並更新房間狀態
+func (s *RoomService) removeParticipant(room *models.Room, userID uint) error {
+	roomID := room.ID
+
+	// 觀眾離開不影響房間狀態
+	for i, spectatorID := range room.Spectators {
+		if spectatorID == userID {
+			room.Spectators = append(room.Spectators[:i], room.Spectators[i+1:]...)
+			if err := s.repo.Update(room); err != nil {
+				return err
+			}
+			s.wsService.BroadcastSystemMessage(roomID, fmt.Sprintf("觀眾 %s 離開了房間", s.wsService.DisplayName(userID)))
+			return nil
+		}
+	}
+
 	// 檢查用戶是否在房間中
 	if room.ProponentID != userID && room.OpponentID != userID {
 		return errors.New("用戶不在此房間中")
 	}
 
-	// 檢查房間狀態
-	if room.Status == models.RoomStatusOngoing {
-		return errors.New("辯論進行中，無法離開")
-	}
-
 	// 更新房間狀態
 	if room.ProponentID == userID {
 		room.ProponentID = 0
@@ -113,7 +205,7 @@ func (s *RoomService) LeaveRoom(roomID, userID uint) error {
 	}
 
 	// 發送系統消息
-	s.wsService.BroadcastSystemMessage(roomID, "用戶離開了房間")
+	s.wsService.BroadcastSystemMessage(roomID, fmt.Sprintf("%s 離開了房間", s.wsService.DisplayName(userID)))
 
 	return nil
 }
@@ -135,6 +227,8 @@ func (h *RoomService) CheckUserInRoom(roomID, userID uint) (string, error) {
 		return "proponent", nil
 	case room.OpponentID:
 		return "opponent", nil
+	case room.OwnerID:
+		return "moderator", nil
 	default:
 		for _, spectatorID := range room.Spectators {
 			if spectatorID == userID {
@@ -144,3 +238,18 @@ func (h *RoomService) CheckUserInRoom(roomID, userID uint) (string, error) {
 		return "", errors.New("用戶不在此房間中")
 	}
 }
+
+// normalizeTags 將主題標籤轉為小寫並去除空白和重複
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"log"
+	"time"
+)
+
+// 處分時長的限制
+const (
+	defaultMuteDuration = 10 * time.Minute
+	maxMuteDuration     = 24 * time.Hour
+)
+
+// SanctionService 處理主持人對房間參與者的禁言、踢出和封鎖，所有處分都會留下紀錄
+// 擁有全站審核權限的用戶可以在任何房間執行主持人的操作
+type SanctionService struct {
+	repo        repository.ModerationRepository
+	roomRepo    repository.RoomRepository
+	userRepo    repository.UserRepository
+	roomService *RoomService
+	wsService   *WebSocketService
+}
+
+// NewSanctionService 創建處分服務，並恢復尚未到期的禁言
+func NewSanctionService(repo repository.ModerationRepository, roomRepo repository.RoomRepository, userRepo repository.UserRepository, roomService *RoomService, ws *WebSocketService) *SanctionService {
+	s := &SanctionService{
+		repo:        repo,
+		roomRepo:    roomRepo,
+		userRepo:    userRepo,
+		roomService: roomService,
+		wsService:   ws,
+	}
+
+	mutes, err := repo.FindActiveSanctions(models.SanctionMute)
+	if err != nil {
+		log.Printf("load mutes error: %v", err)
+	}
+	for _, mute := range mutes {
+		if mute.ExpiresAt != nil {
+			ws.Mute(mute.RoomID, mute.UserID, *mute.ExpiresAt)
+		}
+	}
+
+	return s
+}
+
+// SanctionRequest 是主持人對用戶施加處分的請求
+type SanctionRequest struct {
+	UserID  uint   `json:"user_id"`
+	Minutes int    `json:"minutes"` // 禁言時長，0 表示預設值；封鎖時長，0 表示永久
+	Reason  string `json:"reason"`
+}
+
+// Mute 禁止用戶在房間內發言一段時間
+func (s *SanctionService) Mute(roomID, moderatorID uint, req SanctionRequest) error {
+	room, err := s.checkTarget(roomID, moderatorID, req.UserID)
+	if err != nil {
+		return err
+	}
+	if !isRoomParticipant(room, req.UserID) {
+		return errors.New("用戶不在此房間中")
+	}
+
+	duration := defaultMuteDuration
+	if req.Minutes != 0 {
+		duration = time.Duration(req.Minutes) * time.Minute
+	}
+	if duration <= 0 || duration > maxMuteDuration {
+		return errors.New("無效的禁言時長")
+	}
+	return s.mute(roomID, req.UserID, moderatorID, duration, req.Reason)
+}
+
+// mute 記錄禁言並立即生效
+func (s *SanctionService) mute(roomID, userID, moderatorID uint, duration time.Duration, reason string) error {
+	until := time.Now().Add(duration)
+	if err := s.record(roomID, userID, moderatorID, models.SanctionMute, reason, &until); err != nil {
+		return err
+	}
+
+	s.wsService.Mute(roomID, userID, until)
+	s.wsService.SendToUser(roomID, userID, &models.Message{
+		Type:    "muted",
+		RoomID:  roomID,
+		Content: reason,
+		Data:    map[string]time.Time{"until": until},
+	})
+	return nil
+}
+
+// Unmute 提前解除用戶的禁言
+func (s *SanctionService) Unmute(roomID, moderatorID, userID uint) error {
+	if _, err := s.checkTarget(roomID, moderatorID, userID); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeSanctions(roomID, userID, models.SanctionMute, moderatorID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("用戶沒有被禁言")
+	}
+	log.Printf("room %d: moderator %d unmuted user %d", roomID, moderatorID, userID)
+
+	s.wsService.Unmute(roomID, userID)
+	s.wsService.SendToUser(roomID, userID, &models.Message{Type: "unmuted", RoomID: roomID})
+	return nil
+}
+
+// Kick 將用戶移出房間並斷開其連接，用戶之後仍可重新加入
+func (s *SanctionService) Kick(roomID, moderatorID uint, req SanctionRequest) error {
+	room, err := s.checkTarget(roomID, moderatorID, req.UserID)
+	if err != nil {
+		return err
+	}
+	if !isRoomParticipant(room, req.UserID) {
+		return errors.New("用戶不在此房間中")
+	}
+
+	if err := s.record(roomID, req.UserID, moderatorID, models.SanctionKick, req.Reason, nil); err != nil {
+		return err
+	}
+	return s.remove(roomID, req.UserID, "kicked", req.Reason)
+}
+
+// Ban 禁止用戶再次加入房間，用戶在房間內時會被一併移出
+func (s *SanctionService) Ban(roomID, moderatorID uint, req SanctionRequest) error {
+	room, err := s.checkTarget(roomID, moderatorID, req.UserID)
+	if err != nil {
+		return err
+	}
+	if req.Minutes < 0 {
+		return errors.New("無效的封鎖時長")
+	}
+
+	var until *time.Time
+	if req.Minutes > 0 {
+		t := time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+		until = &t
+	}
+	if err := s.record(roomID, req.UserID, moderatorID, models.SanctionBan, req.Reason, until); err != nil {
+		return err
+	}
+
+	if !isRoomParticipant(room, req.UserID) {
+		return nil
+	}
+	return s.remove(roomID, req.UserID, "banned", req.Reason)
+}
+
+// Unban 解除用戶的封鎖
+func (s *SanctionService) Unban(roomID, moderatorID, userID uint) error {
+	if _, err := s.checkTarget(roomID, moderatorID, userID); err != nil {
+		return err
+	}
+
+	revoked, err := s.repo.RevokeSanctions(roomID, userID, models.SanctionBan, moderatorID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("用戶沒有被封鎖")
+	}
+	log.Printf("room %d: moderator %d unbanned user %d", roomID, moderatorID, userID)
+	return nil
+}
+
+// ListSanctions 返回房間的所有處分紀錄，僅限可以管理房間的用戶
+func (s *SanctionService) ListSanctions(roomID, moderatorID uint) ([]models.Sanction, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return nil, errors.New("房間不存在")
+	}
+	if !canModerateRoom(s.userRepo, room, moderatorID) {
+		return nil, errors.New("只有主持人可以管理參與者")
+	}
+	return s.repo.FindSanctionsByRoom(roomID)
+}
+
+// remove 將用戶移出房間，通知用戶後斷開其在房間內的所有連接
+func (s *SanctionService) remove(roomID, userID uint, frameType, reason string) error {
+	if err := s.roomService.RemoveParticipant(roomID, userID); err != nil {
+		return err
+	}
+	s.wsService.DisconnectUser(roomID, userID, &models.Message{
+		Type:    frameType,
+		RoomID:  roomID,
+		Content: reason,
+	})
+	return nil
+}
+
+// record 保存處分紀錄
+func (s *SanctionService) record(roomID, userID, moderatorID uint, kind models.SanctionKind, reason string, until *time.Time) error {
+	err := s.repo.CreateSanction(&models.Sanction{
+		RoomID:      roomID,
+		UserID:      userID,
+		Kind:        kind,
+		Reason:      reason,
+		ModeratorID: moderatorID,
+		ExpiresAt:   until,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case kind == models.SanctionKick:
+		log.Printf("room %d: moderator %d kicked user %d: %s", roomID, moderatorID, userID, reason)
+	case until == nil:
+		log.Printf("room %d: moderator %d applied %s to user %d permanently: %s", roomID, moderatorID, kind, userID, reason)
+	default:
+		log.Printf("room %d: moderator %d applied %s to user %d until %s: %s", roomID, moderatorID, kind, userID, until.Format(time.RFC3339), reason)
+	}
+	return nil
+}
+
+// checkTarget 確認操作者可以管理房間，且處分對象不是房間主持人或同級以上的全站管理人員
+func (s *SanctionService) checkTarget(roomID, moderatorID, userID uint) (*models.Room, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return nil, errors.New("房間不存在")
+	}
+	if !canModerateRoom(s.userRepo, room, moderatorID) {
+		return nil, errors.New("只有主持人可以管理參與者")
+	}
+	if userID == 0 {
+		return nil, errors.New("無效的用戶ID")
+	}
+	if userID == room.OwnerID {
+		return nil, errors.New("不能對主持人執行此操作")
+	}
+	if !canSanction(s.userRepo, moderatorID, userID) {
+		return nil, errors.New("不能對同級或更高級的管理人員執行此操作")
+	}
+	return room, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"debate_web/internal/repository/models"
+	"testing"
+)
+
+func TestKickBanAndMute(t *testing.T) {
+	ws, rooms, _ := newTestWebSocketService()
+	moderation := &memoryModerationRepository{}
+	roomService := NewRoomService(rooms, moderation, ws)
+	sanctions := NewSanctionService(moderation, rooms, &memoryUserRepository{}, roomService, ws)
+
+	room := &models.Room{Model: gormModel(1), OwnerID: 9, Status: models.RoomStatusOngoing,
+		ProponentID: 1, OpponentID: 2, Spectators: []uint{3, 4}}
+	rooms.rooms[room.ID] = room
+
+	spectator := newTestClient(1, 3, 32)
+	muted := newTestClient(1, 4, 32)
+	ws.addClient(spectator)
+	ws.addClient(muted)
+	defer ws.removeClient(muted)
+
+	if err := sanctions.Kick(1, 3, SanctionRequest{UserID: 4}); err == nil || err.Error() != "只有主持人可以管理參與者" {
+		t.Fatalf("kick by non-moderator error = %v", err)
+	}
+	if err := sanctions.Ban(1, 9, SanctionRequest{UserID: 9}); err == nil {
+		t.Fatal("moderator banned themselves")
+	}
+
+	// 封鎖觀眾：收到通知後連接被關閉，並被移出房間
+	if err := sanctions.Ban(1, 9, SanctionRequest{UserID: 3, Reason: "洗版"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := nextNonSystem(spectator); msg == nil || msg.Type != "banned" || msg.Content != "洗版" {
+		t.Fatalf("banned client got %+v", msg)
+	}
+	if msg := nextNonSystem(spectator); msg != nil {
+		t.Fatalf("banned client still receives %+v", msg)
+	}
+	if _, err := roomService.CheckUserInRoom(1, 3); err == nil {
+		t.Fatal("banned spectator is still in the room")
+	}
+	if err := roomService.JoinRoom(1, 3, "spectator"); err == nil || err.Error() != "您已被禁止加入此房間" {
+		t.Fatalf("banned join error = %v", err)
+	}
+	if err := sanctions.Unban(1, 9, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := roomService.JoinRoom(1, 3, "spectator"); err != nil {
+		t.Fatalf("join after unban: %v", err)
+	}
+
+	// 禁言：內容類的消息被拒絕，檢舉等不產生內容的命令仍然可用
+	handled := 0
+	ws.HandleFrame("question", func(*Client, *ClientFrame) { handled++ })
+	ws.HandleFrame("report", func(*Client, *ClientFrame) { handled++ })
+	if err := sanctions.Mute(1, 9, SanctionRequest{UserID: 4, Minutes: 5}); err != nil {
+		t.Fatal(err)
+	}
+	ws.handleFrame(muted, &ClientFrame{Message: models.Message{Type: "question"}})
+	ws.handleFrame(muted, &ClientFrame{Message: models.Message{Type: "report"}})
+	if handled != 1 {
+		t.Fatalf("handled %d frames while muted, want only the report", handled)
+	}
+	if err := sanctions.Unmute(1, 9, 4); err != nil {
+		t.Fatal(err)
+	}
+	ws.handleFrame(muted, &ClientFrame{Message: models.Message{Type: "question"}})
+	if handled != 2 {
+		t.Fatal("frame rejected after unmute")
+	}
+
+	// 辯論進行中踢出辯手，辯論結束
+	if err := sanctions.Kick(1, 9, SanctionRequest{UserID: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if room, _ := rooms.FindByID(1); room.OpponentID != 0 || room.Status != models.RoomStatusFinished {
+		t.Fatalf("room after kick = %+v", room)
+	}
+
+	log, _ := sanctions.ListSanctions(1, 9)
+	var kinds []models.SanctionKind
+	for _, s := range log {
+		kinds = append(kinds, s.Kind)
+	}
+	want := []models.SanctionKind{models.SanctionBan, models.SanctionMute, models.SanctionKick}
+	if len(kinds) != len(want) || kinds[0] != want[0] || kinds[1] != want[1] || kinds[2] != want[2] {
+		t.Fatalf("sanction log = %v, want %v", kinds, want)
+	}
+	if log[0].RevokedBy != 9 || log[1].RevokedAt == nil {
+		t.Fatal("revocations were not recorded")
+	}
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 搜尋結果的分頁限制和摘要長度
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	snippetRadius      = 40 // 摘要在第一個命中詞前後保留的字數
+)
+
+// 摘要中命中詞的標記，與 Postgres ts_headline 的預設標記一致
+const (
+	highlightStart = "<b>"
+	highlightStop  = "</b>"
+)
+
+// SearchService 提供跨房間的消息全文搜尋
+type SearchService struct {
+	messageRepo repository.MessageRepository
+}
+
+func NewSearchService(messageRepo repository.MessageRepository) *SearchService {
+	return &SearchService{messageRepo: messageRepo}
+}
+
+// SearchParams 是搜尋請求的條件
+type SearchParams struct {
+	Query  string
+	RoomID uint
+	UserID uint
+	Side   string // 發言角色，如 proponent、opponent
+	Tag    string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+// SearchHit 是一筆搜尋結果，Snippet 是已跳脫的 HTML，命中詞以 <b></b> 標記
+type SearchHit struct {
+	MessageID uint      `json:"message_id"`
+	RoomID    uint      `json:"room_id"`
+	RoomName  string    `json:"room_name"`
+	UserID    uint      `json:"user_id"`
+	Role      string    `json:"role"`
+	Channel   string    `json:"channel"`
+	Seq       int       `json:"seq"`
+	Snippet   string    `json:"snippet"`
+	CreatedAt time.Time `json:"created_at"`
+	RoomURL   string    `json:"room_url"`
+	URL       string    `json:"url"` // 指向完整紀錄中該消息的位置
+}
+
+// Search 搜尋消息並產生摘要
+func (s *SearchService) Search(params SearchParams) ([]SearchHit, error) {
+	params.Query = strings.TrimSpace(params.Query)
+	if params.Query == "" {
+		return nil, errors.New("搜尋關鍵字不能為空")
+	}
+	if params.Side != "" {
+		if _, ok := roleNames[params.Side]; !ok {
+			return nil, errors.New("無效的發言方")
+		}
+	}
+	if !params.From.IsZero() && !params.To.IsZero() && !params.From.Before(params.To) {
+		return nil, errors.New("無效的日期範圍")
+	}
+	if params.Limit <= 0 {
+		params.Limit = defaultSearchLimit
+	}
+	params.Limit = min(params.Limit, maxSearchLimit)
+	params.Offset = max(params.Offset, 0)
+
+	results, err := s.messageRepo.Search(repository.MessageSearchFilter{
+		Query:  params.Query,
+		RoomID: params.RoomID,
+		UserID: params.UserID,
+		Role:   params.Side,
+		Tag:    strings.ToLower(strings.TrimSpace(params.Tag)),
+		From:   params.From,
+		To:     params.To,
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	terms := searchTerms(params.Query)
+	hits := make([]SearchHit, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, SearchHit{
+			MessageID: result.MessageID,
+			RoomID:    result.RoomID,
+			RoomName:  result.RoomName,
+			UserID:    result.UserID,
+			Role:      result.Role,
+			Channel:   result.Channel,
+			Seq:       result.Seq,
+			Snippet:   highlightSnippet(result.Content, terms, snippetRadius),
+			CreatedAt: result.CreatedAt,
+			RoomURL:   fmt.Sprintf("/api/rooms/%d", result.RoomID),
+			URL:       fmt.Sprintf("/api/rooms/%d/transcript#%d", result.RoomID, result.Seq),
+		})
+	}
+	return hits, nil
+}
+
+// searchTerms 從查詢中取出需要標記的詞，忽略排除詞和 OR 運算子
+func searchTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"'
+	}) {
+		if field == "or" || strings.HasPrefix(field, "-") {
+			continue
+		}
+		terms = append(terms, field)
+	}
+	return terms
+}
+
+// highlightSnippet 截取內容中第一個命中詞附近的文字，並標記其中所有的命中詞
+// 摘要會被當作 HTML 顯示，除了標記之外的內容都經過跳脫
+func highlightSnippet(content string, terms []string, radius int) string {
+	runes := []rune(content)
+	lower := []rune(strings.ToLower(content))
+	if len(lower) != len(runes) {
+		// 轉小寫改變了字元數時無法對應位置，退回逐字比對原文
+		lower = runes
+	}
+
+	// 找出所有命中的區間
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(lower); {
+		matched := 0
+		for _, term := range terms {
+			t := []rune(term)
+			if len(t) > matched && i+len(t) <= len(lower) && string(lower[i:i+len(t)]) == term {
+				matched = len(t)
+			}
+		}
+		if matched > 0 {
+			spans = append(spans, span{i, i + matched})
+			i += matched
+		} else {
+			i++
+		}
+	}
+
+	start, end := 0, len(runes)
+	if len(spans) > 0 {
+		start = max(spans[0].start-radius, 0)
+		end = min(spans[0].end+radius, len(runes))
+	} else {
+		end = min(2*radius, len(runes))
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, sp := range spans {
+		if sp.start < start || sp.end > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:sp.start])))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(string(runes[sp.start:sp.end])))
+		b.WriteString(highlightStop)
+		pos = sp.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
@@ -0,0 +1,42 @@
+package service
+
+import "testing"
+
+func TestSearchTerms(t *testing.T) {
+	got := searchTerms(`"死刑 存廢" Deterrence or -cost`)
+	want := []string{"死刑", "存廢", "deterrence"}
+	if len(got) != len(want) {
+		t.Fatalf("searchTerms = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("searchTerms = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		radius  int
+		want    string
+	}{
+		{"all terms", "死刑有嚇阻作用，但死刑無法挽回", []string{"死刑"}, 20, "<b>死刑</b>有嚇阻作用，但<b>死刑</b>無法挽回"},
+		{"case insensitive", "The Deterrence effect", []string{"deterrence"}, 20, "The <b>Deterrence</b> effect"},
+		{"window", "一二三四五死刑六七八九十", []string{"死刑"}, 2, "…四五<b>死刑</b>六七…"},
+		{"longest term", "death penalty", []string{"death", "death penalty"}, 20, "<b>death penalty</b>"},
+		{"no match", "一二三四五六", []string{"死刑"}, 2, "一二三四…"},
+		{"escape html", `<script>alert("死刑")</script><img src=x onerror=alert(1)>`, []string{"死刑"}, 100,
+			`&lt;script&gt;alert(&#34;<b>死刑</b>&#34;)&lt;/script&gt;&lt;img src=x onerror=alert(1)&gt;`},
+		{"escape term", "a <b> c", []string{"<b>"}, 20, "a <b>&lt;b&gt;</b> c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightSnippet(tt.content, tt.terms, tt.radius); got != tt.want {
+				t.Errorf("highlightSnippet = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,73 @@
 package service
 
-import "debate_web/internal/repository"
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/mail"
+	"debate_web/internal/repository"
+	"debate_web/internal/storage"
+)
 
 type Services struct {
-	User      *UserService
-	Room      *RoomService
-	WebSocket *WebSocketService
+	User       *UserService
+	Token      *TokenService
+	Password   *PasswordService
+	Email      *EmailService
+	TwoFactor  *TwoFactorService
+	OIDC       *OIDCService
+	Profile    *ProfileService
+	Room       *RoomService
+	Question   *QuestionService
+	Reaction   *ReactionService
+	Argument   *ArgumentService
+	Transcript *TranscriptService
+	Report     *ReportService
+	Replay     *ReplayService
+	Stats      *StatsService
+	Search     *SearchService
+	Sanction   *SanctionService
+	Review     *ReviewService
+	Admin      *AdminService
+	WebSocket  *WebSocketService
 }
 
-func NewServices(repos *repository.Repositories) *Services {
-	ws := NewWebSocketService()
+func NewServices(repos *repository.Repositories, cfg *config.Config, keys *JWTKeySet, blobs storage.BlobStore, mailer mail.Mailer) *Services {
+	ws := NewWebSocketService(repos.Room, repos.Message, repos.User)
+	ws.UseModeration(NewConfiguredModeration(cfg.Moderation))
+	ws.UseRateLimit(cfg.RateLimit.WebSocket)
+	reaction := NewReactionService(repos.Reaction, repos.Message, ws)
+	transcript := NewTranscriptService(repos.Room, repos.User, repos.Message)
+	room := NewRoomService(repos.Room, repos.Moderation, ws)
+	sanction := NewSanctionService(repos.Moderation, repos.Room, repos.User, room, ws)
+	tokens := NewTokenService(repos.Token, repos.User, cfg.Auth, keys)
+	users := NewUserService(repos.User, cfg.RateLimit.Lockout)
+	twoFactor := NewTwoFactorService(repos.TwoFactor, repos.User, repos.Token, users, tokens, cfg.TwoFactor)
+	tokens.RequireTwoFactor(twoFactor)
+	policy := NewPasswordPolicy(cfg.Password)
+	emails := NewEmailService(repos.User, repos.Token, mailer, cfg.Email)
+	if cfg.Email.RequireVerified {
+		room.RequireVerifiedEmail(emails)
+	}
 
 	return &Services{
-		User:      NewUserService(repos.User),
-		Room:      NewRoomService(repos.Room, ws),
-		WebSocket: ws,
+		User:       users,
+		Token:      tokens,
+		Email:      emails,
+		TwoFactor:  twoFactor,
+		OIDC:       NewOIDCService(repos.Identity, repos.User, users, cfg.OIDC),
+		Password:   NewPasswordService(repos.User, repos.Token, tokens, mailer, policy, cfg.Password),
+		Profile:    NewProfileService(repos.User, blobs, cfg.Avatar),
+		Room:       room,
+		Question:   NewQuestionService(repos.Question, repos.Room, repos.User, ws),
+		Reaction:   reaction,
+		Argument:   NewArgumentService(repos.Message, repos.Room, repos.Reaction),
+		Transcript: transcript,
+		Report:     NewReportService(repos.Room, repos.Message, transcript, reaction),
+		Replay:     NewReplayService(repos.Room, repos.Message),
+		Stats:      NewStatsService(repos.Room, repos.Message, repos.Reaction, repos.User),
+		Search:     NewSearchService(repos.Message),
+		Sanction:   sanction,
+		Review:     NewReviewService(repos.Moderation, repos.Message, repos.Room, repos.User, sanction, ws),
+		Admin:      NewAdminService(repos.User, repos.Room, tokens, policy, twoFactor, ws),
+		WebSocket:  ws,
 	}
 }
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"errors"
+	"sort"
+	"time"
+	"unicode"
+)
+
+// StatsService 統計辯論房間和用戶的發言數據
+type StatsService struct {
+	roomRepo     repository.RoomRepository
+	messageRepo  repository.MessageRepository
+	reactionRepo repository.ReactionRepository
+	userRepo     repository.UserRepository
+}
+
+func NewStatsService(roomRepo repository.RoomRepository, messageRepo repository.MessageRepository, reactionRepo repository.ReactionRepository, userRepo repository.UserRepository) *StatsService {
+	return &StatsService{
+		roomRepo:     roomRepo,
+		messageRepo:  messageRepo,
+		reactionRepo: reactionRepo,
+		userRepo:     userRepo,
+	}
+}
+
+// ParticipantStats 是單一用戶在一個房間內的發言統計
+type ParticipantStats struct {
+	UserID          uint           `json:"user_id"`
+	Username        string         `json:"username"`
+	Role            string         `json:"role"`
+	Messages        int            `json:"messages"`
+	FloorMessages   int            `json:"floor_messages"`
+	GalleryMessages int            `json:"gallery_messages"`
+	Words           int            `json:"words"`
+	Responses       int            `json:"responses"`               // 回應對手發言的次數
+	AvgResponseMs   int64          `json:"avg_response_latency_ms"` // 回應對手的平均間隔
+	Reactions       map[string]int `json:"reactions_received"`
+
+	responseTotal time.Duration
+}
+
+// RoomStats 是一個房間內所有發言者的統計
+type RoomStats struct {
+	RoomID       uint                `json:"room_id"`
+	Participants []*ParticipantStats `json:"participants"`
+}
+
+// UserStats 是用戶在所有擔任辯手的房間中累計的統計
+type UserStats struct {
+	UserID        uint           `json:"user_id"`
+	Username      string         `json:"username"`
+	Debates       int            `json:"debates"`
+	AsProponent   int            `json:"as_proponent"`
+	AsOpponent    int            `json:"as_opponent"`
+	Messages      int            `json:"messages"`
+	Words         int            `json:"words"`
+	Responses     int            `json:"responses"`
+	AvgResponseMs int64          `json:"avg_response_latency_ms"`
+	Reactions     map[string]int `json:"reactions_received"`
+}
+
+// RoomStats 統計房間內每位發言者的數據，按發言數排序
+func (s *StatsService) RoomStats(roomID uint) (*RoomStats, error) {
+	if _, err := s.roomRepo.FindByID(roomID); err != nil {
+		return nil, errors.New("房間不存在")
+	}
+
+	participants, err := s.collect(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &RoomStats{RoomID: roomID, Participants: []*ParticipantStats{}}
+	ids := make([]uint, 0, len(participants))
+	for id, p := range participants {
+		ids = append(ids, id)
+		stats.Participants = append(stats.Participants, p)
+	}
+
+	users, err := s.userRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		participants[user.ID].Username = user.Username
+	}
+
+	sort.Slice(stats.Participants, func(i, j int) bool {
+		a, b := stats.Participants[i], stats.Participants[j]
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		return a.UserID < b.UserID
+	})
+	return stats, nil
+}
+
+// UserStats 累計用戶擔任正方或反方的所有房間中的統計
+func (s *StatsService) UserStats(userID uint) (*UserStats, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+
+	rooms, err := s.roomRepo.FindByDebater(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &UserStats{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Debates:   len(rooms),
+		Reactions: map[string]int{},
+	}
+	var responseTotal time.Duration
+	for _, room := range rooms {
+		if room.ProponentID == userID {
+			stats.AsProponent++
+		} else {
+			stats.AsOpponent++
+		}
+
+		participants, err := s.collect(room.ID)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := participants[userID]
+		if !ok {
+			continue
+		}
+		stats.Messages += p.Messages
+		stats.Words += p.Words
+		stats.Responses += p.Responses
+		responseTotal += p.responseTotal
+		for emoji, count := range p.Reactions {
+			stats.Reactions[emoji] += count
+		}
+	}
+	if stats.Responses > 0 {
+		stats.AvgResponseMs = (responseTotal / time.Duration(stats.Responses)).Milliseconds()
+	}
+
+	return stats, nil
+}
+
+// collect 逐批讀取房間消息，統計每位發言者的數據
+// 回應數也逐批查詢，避免長時間的辯論產生超過參數上限的 IN 查詢
+func (s *StatsService) collect(roomID uint) (map[uint]*ParticipantStats, error) {
+	participants := make(map[uint]*ParticipantStats)
+
+	// 對手最近一次發言的時間，用於計算辯手的回應間隔
+	var lastDebater string
+	var lastDebaterAt time.Time
+
+	err := s.messageRepo.EachByRoom(roomID, transcriptBatchSize, func(batch []models.Message) error {
+		owner := make(map[uint]*ParticipantStats, len(batch))
+		var ids []uint
+		for _, msg := range batch {
+			if msg.UserID == 0 || msg.Role == "system" {
+				continue
+			}
+
+			p := participants[msg.UserID]
+			if p == nil {
+				p = &ParticipantStats{UserID: msg.UserID, Role: msg.Role, Reactions: map[string]int{}}
+				participants[msg.UserID] = p
+			}
+			p.Messages++
+			p.Words += countWords(msg.Content)
+			owner[msg.ID] = p
+			ids = append(ids, msg.ID)
+			if msg.Channel == models.ChannelGallery {
+				p.GalleryMessages++
+			} else {
+				p.FloorMessages++
+			}
+
+			if msg.Role != "proponent" && msg.Role != "opponent" {
+				continue
+			}
+			// 只計算輪到自己時的第一則發言，連續發言不重複計算
+			if lastDebater != "" && lastDebater != msg.Role {
+				p.Responses++
+				p.responseTotal += msg.CreatedAt.Sub(lastDebaterAt)
+			}
+			lastDebater, lastDebaterAt = msg.Role, msg.CreatedAt
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		counts, err := s.reactionRepo.CountByMessages(ids)
+		if err != nil {
+			return err
+		}
+		for _, count := range counts {
+			owner[count.MessageID].Reactions[count.Emoji] += count.Count
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range participants {
+		if p.Responses > 0 {
+			p.AvgResponseMs = (p.responseTotal / time.Duration(p.Responses)).Milliseconds()
+		}
+	}
+	return participants, nil
+}
+
+// countWords 計算文字的詞數：每個漢字算一個詞，連續的英文字母或數字算一個詞
+func countWords(text string) int {
+	words := 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			words++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+				inWord = true
+			}
+		default:
+			inWord = false
+		}
+	}
+	return words
+}
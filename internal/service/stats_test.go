@@ -0,0 +1,108 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"testing"
+	"time"
+)
+
+// emptyReactionRepository 是沒有任何回應的 ReactionRepository
+type emptyReactionRepository struct{}
+
+func (emptyReactionRepository) Add(*models.Reaction) (bool, error)      { return true, nil }
+func (emptyReactionRepository) Remove(uint, uint, string) (bool, error) { return true, nil }
+func (emptyReactionRepository) CountByMessages([]uint) ([]repository.ReactionCount, error) {
+	return nil, nil
+}
+func (emptyReactionRepository) CountByRole(uint) ([]repository.SideReactionCount, error) {
+	return nil, nil
+}
+
+func TestCountWords(t *testing.T) {
+	tests := map[string]int{
+		"":                  0,
+		"我方認為":              4,
+		"hello world":       2,
+		"GDP 成長了 3.5%":      6,
+		"死刑 should be 廢除！！": 6,
+	}
+	for text, want := range tests {
+		if got := countWords(text); got != want {
+			t.Errorf("countWords(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestRoomStatsResponseLatency(t *testing.T) {
+	_, rooms, messages := newTestWebSocketService()
+	users := &memoryUserRepository{}
+	users.Create(&models.User{Username: "alice"})
+	users.Create(&models.User{Username: "bob"})
+	room := &models.Room{ProponentID: 1, OpponentID: 2}
+	rooms.Create(room)
+
+	start := time.Now()
+	post := func(userID uint, role string, offset time.Duration) {
+		msg := &models.Message{RoomID: room.ID, UserID: userID, Role: role, Content: "論點"}
+		msg.CreatedAt = start.Add(offset)
+		messages.Create(msg)
+	}
+	post(1, "proponent", 0)
+	post(1, "proponent", 5*time.Second)
+	post(2, "opponent", 15*time.Second)  // 回應正方，間隔 10 秒
+	post(1, "proponent", 45*time.Second) // 回應反方，間隔 30 秒
+	post(2, "opponent", 55*time.Second)  // 回應正方，間隔 10 秒
+
+	s := NewStatsService(rooms, messages, emptyReactionRepository{}, users)
+	stats, err := s.RoomStats(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byUser := map[uint]*ParticipantStats{}
+	for _, p := range stats.Participants {
+		byUser[p.UserID] = p
+	}
+	if p := byUser[1]; p.Messages != 3 || p.Words != 6 || p.Responses != 1 || p.AvgResponseMs != 30000 {
+		t.Errorf("unexpected proponent stats: %+v", p)
+	}
+	if p := byUser[2]; p.Username != "bob" || p.Responses != 2 || p.AvgResponseMs != 10000 {
+		t.Errorf("unexpected opponent stats: %+v", p)
+	}
+
+	career, err := s.UserStats(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if career.Debates != 1 || career.AsOpponent != 1 || career.Messages != 2 {
+		t.Errorf("unexpected career stats: %+v", career)
+	}
+}
+
+func TestRoomStatsCountsReactionsPerBatch(t *testing.T) {
+	_, rooms, messages := newTestWebSocketService()
+	users := &memoryUserRepository{}
+	users.Create(&models.User{Username: "alice"})
+	room := &models.Room{ProponentID: 1}
+	rooms.Create(room)
+
+	reactions := &memoryReactionRepository{messages: messages}
+	const total = transcriptBatchSize*2 + 1
+	for i := 0; i < total; i++ {
+		msg := &models.Message{RoomID: room.ID, UserID: 1, Role: "proponent", Content: "論點", Status: models.MessageStatusPublished}
+		messages.Create(msg)
+		reactions.Add(&models.Reaction{MessageID: msg.ID, UserID: 2, Emoji: "👍", RoomID: room.ID})
+	}
+
+	stats, err := NewStatsService(rooms, messages, reactions, users).RoomStats(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Participants) != 1 || stats.Participants[0].Reactions["👍"] != total {
+		t.Fatalf("participants = %+v", stats.Participants)
+	}
+	if reactions.maxQuery > transcriptBatchSize {
+		t.Errorf("counted reactions for %d messages in one query, want at most %d", reactions.maxQuery, transcriptBatchSize)
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"debate_web/internal/config"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"gorm.io/gorm"
+)
+
+// tokenPruneInterval 清除過期 token 紀錄的間隔
+const tokenPruneInterval = time.Hour
+
+// TokenService 發出和撤銷登入憑證
+// access token 是短期的 JWT；refresh token 是隨機字串，每次換發後即失效，資料庫只保存其雜湊值
+type TokenService struct {
+	repo     repository.TokenRepository
+	userRepo repository.UserRepository
+	cfg      config.AuthConfig
+	keys     *JWTKeySet
+
+	twoFactor *TwoFactorService
+}
+
+// NewTokenService 創建憑證服務並開始定期清除過期的紀錄
+func NewTokenService(repo repository.TokenRepository, userRepo repository.UserRepository, cfg config.AuthConfig, keys *JWTKeySet) *TokenService {
+	s := &TokenService{repo: repo, userRepo: userRepo, cfg: cfg, keys: keys}
+
+	go s.run(tokenPruneInterval)
+
+	return s
+}
+
+// RequireTwoFactor 讓換發憑證時檢查用戶的角色是否必須啟用兩步驟驗證，必須在服務開始處理請求前呼叫
+func (s *TokenService) RequireTwoFactor(twoFactor *TwoFactorService) {
+	s.twoFactor = twoFactor
+}
+
+// TokenPair 是登入或換發後返回給客戶端的憑證
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	TokenType        string    `json:"token_type"`
+	ExpiresIn        int       `json:"expires_in"` // access token 的剩餘秒數
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// Issue 為新的登入發出憑證，開始一個新的 token family
+func (s *TokenService) Issue(user *models.User) (*TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(user, familyID)
+}
+
+// Refresh 以 refresh token 換發新的憑證，舊的 refresh token 隨即失效
+// 已失效的 refresh token 再次被使用時，表示 token 可能已外洩，整個 family 都會被撤銷
+// 新的 access token 使用用戶目前的角色，角色變更在下次換發時生效
+func (s *TokenService) Refresh(refreshToken string) (*TokenPair, error) {
+	token, err := s.repo.FindRefreshToken(hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("無效的 refresh token")
+		}
+		return nil, err
+	}
+	if token.RevokedAt != nil || !time.Now().Before(token.ExpiresAt) {
+		return nil, errors.New("無效的 refresh token")
+	}
+
+	used, err := s.repo.UseRefreshToken(token.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		log.Printf("user %d: refresh token reused, revoking token family %s", token.UserID, token.FamilyID)
+		if err := s.repo.RevokeFamily(token.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("無效的 refresh token")
+	}
+
+	user, err := s.userRepo.FindByID(token.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("無效的 refresh token")
+		}
+		return nil, err
+	}
+	if user.DisabledAt != nil {
+		return nil, errors.New("無效的 refresh token")
+	}
+	// 角色改為必須啟用兩步驟驗證時，需重新登入完成設定
+	if s.twoFactor != nil && s.twoFactor.Required(user) && !user.TwoFactorEnabled() {
+		return nil, errors.New("無效的 refresh token")
+	}
+	return s.issue(user, token.FamilyID)
+}
+
+// Logout 撤銷目前的 access token 及同一次登入的 refresh token
+func (s *TokenService) Logout(userID uint, accessID string, expiresAt time.Time) error {
+	token, err := s.repo.FindRefreshTokenByAccessID(accessID)
+	switch {
+	case err == nil:
+		if err := s.repo.RevokeFamily(token.FamilyID); err != nil {
+			return err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	// access token 可能已經換發過，不一定在 family 中被撤銷
+	return s.repo.RevokeAccessToken(&models.RevokedToken{ID: accessID, UserID: userID, ExpiresAt: expiresAt})
+}
+
+// LogoutAll 撤銷用戶在所有裝置上的憑證
+func (s *TokenService) LogoutAll(userID uint, accessID string, expiresAt time.Time) error {
+	if err := s.repo.RevokeUser(userID); err != nil {
+		return err
+	}
+	return s.repo.RevokeAccessToken(&models.RevokedToken{ID: accessID, UserID: userID, ExpiresAt: expiresAt})
+}
+
+// ParseAccessToken 驗證 access token 的簽名和有效期，不檢查是否已被撤銷
+func (s *TokenService) ParseAccessToken(token string) (*TokenClaims, error) {
+	return s.keys.Parse(token)
+}
+
+// JWKS 返回驗證 access token 用的公鑰
+func (s *TokenService) JWKS() JWKS {
+	return s.keys.JWKS()
+}
+
+// IsRevoked 檢查 access token 是否已被撤銷，或其用戶已被停用
+func (s *TokenService) IsRevoked(accessID string, userID uint) (bool, error) {
+	return s.repo.IsRevoked(accessID, userID)
+}
+
+// RevokeAll 撤銷用戶所有的憑證，用於停用帳號或重設密碼
+func (s *TokenService) RevokeAll(userID uint) error {
+	return s.repo.RevokeUser(userID)
+}
+
+// RevokeOthers 撤銷用戶在其他裝置上的憑證，保留 accessID 所屬的這一次登入
+func (s *TokenService) RevokeOthers(userID uint, accessID string) error {
+	token, err := s.repo.FindRefreshTokenByAccessID(accessID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.repo.RevokeUser(userID)
+	}
+	if err != nil {
+		return err
+	}
+	return s.repo.RevokeUserExcept(userID, token.FamilyID)
+}
+
+// issue 在指定的 family 中發出一組新的憑證
+func (s *TokenService) issue(user *models.User, familyID string) (*TokenPair, error) {
+	now := time.Now()
+	accessID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	accessExpiresAt := now.Add(s.cfg.AccessTTL)
+	access, err := s.keys.Sign(&TokenClaims{
+		UserID: user.ID,
+		Role:   string(user.Role),
+		StandardClaims: jwt.StandardClaims{
+			Id:        accessID,
+			ExpiresAt: accessExpiresAt.Unix(),
+			IssuedAt:  now.Unix(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	token := &models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		TokenHash:       hashToken(refresh),
+		ExpiresAt:       now.Add(s.cfg.RefreshTTL),
+		AccessID:        accessID,
+		AccessExpiresAt: accessExpiresAt,
+	}
+	if err := s.repo.CreateRefreshToken(token); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(s.cfg.AccessTTL.Seconds()),
+		RefreshExpiresAt: token.ExpiresAt,
+	}, nil
+}
+
+// run 定期清除過期的 refresh token 和撤銷紀錄
+func (s *TokenService) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if err := s.repo.PruneExpired(now); err != nil {
+			log.Printf("prune tokens error: %v", err)
+		}
+	}
+}
+
+// randomToken 產生 n 個隨機位元組並以 URL 安全的 base64 編碼
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken 計算 refresh token 的雜湊值，refresh token 本身有足夠的隨機性，不需要加鹽
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
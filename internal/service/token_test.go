@@ -0,0 +1,264 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryTokenRepository 是只保存在記憶體中的 TokenRepository
+type memoryTokenRepository struct {
+	mu      sync.Mutex
+	tokens  []*models.RefreshToken
+	revoked map[string]models.RevokedToken
+	once    []*models.UserToken
+}
+
+func newMemoryTokenRepository() *memoryTokenRepository {
+	return &memoryTokenRepository{revoked: make(map[string]models.RevokedToken)}
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *memoryTokenRepository) find(match func(*models.RefreshToken) bool) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.tokens {
+		if match(token) {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) FindRefreshToken(hash string) (*models.RefreshToken, error) {
+	return r.find(func(t *models.RefreshToken) bool { return t.TokenHash == hash })
+}
+
+func (r *memoryTokenRepository) FindRefreshTokenByAccessID(accessID string) (*models.RefreshToken, error) {
+	return r.find(func(t *models.RefreshToken) bool { return t.AccessID == accessID })
+}
+
+func (r *memoryTokenRepository) UseRefreshToken(id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token := r.tokens[id-1]
+	if token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (r *memoryTokenRepository) revoke(match func(*models.RefreshToken) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, token := range r.tokens {
+		if !match(token) {
+			continue
+		}
+		if token.AccessExpiresAt.After(now) {
+			r.revoked[token.AccessID] = models.RevokedToken{ID: token.AccessID, UserID: token.UserID, ExpiresAt: token.AccessExpiresAt}
+		}
+		if token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+}
+
+func (r *memoryTokenRepository) RevokeFamily(familyID string) error {
+	r.revoke(func(t *models.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeUser(userID uint) error {
+	r.revoke(func(t *models.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeUserExcept(userID uint, familyID string) error {
+	r.revoke(func(t *models.RefreshToken) bool { return t.UserID == userID && t.FamilyID != familyID })
+	return nil
+}
+
+func (r *memoryTokenRepository) CreateUserToken(token *models.UserToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uint(len(r.once) + 1)
+	r.once = append(r.once, token)
+	return nil
+}
+
+func (r *memoryTokenRepository) FindUserToken(purpose, hash string) (*models.UserToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.once {
+		if token.Purpose == purpose && token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) UseUserToken(id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token := r.once[id-1]
+	now := time.Now()
+	if token.UsedAt != nil || !now.Before(token.ExpiresAt) {
+		return false, nil
+	}
+	token.UsedAt = &now
+	return true, nil
+}
+
+func (r *memoryTokenRepository) InvalidateUserTokens(userID uint, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, token := range r.once {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(token *models.RevokedToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked[token.ID] = *token
+	return nil
+}
+
+func (r *memoryTokenRepository) IsRevoked(accessID string, userID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.revoked[accessID]
+	return ok, nil
+}
+
+func (r *memoryTokenRepository) PruneExpired(now time.Time) error {
+	return nil
+}
+
+// testJWTKeys 是測試用的 HS256 金鑰
+var testJWTKeys, _ = NewJWTKeySet(config.JWTConfig{
+	SigningKey: "test",
+	Keys:       []config.JWTKeyConfig{{ID: "test", Algorithm: "HS256", Secret: "0123456789abcdef0123456789abcdef"}},
+})
+
+// newTestTokenService 建立使用記憶體 repository 的 TokenService，並建立一個一般用戶
+func newTestTokenService() (*TokenService, *memoryUserRepository, *models.User) {
+	users := &memoryUserRepository{}
+	user := &models.User{Username: "alice", Role: models.UserRoleUser}
+	users.Create(user)
+	s := NewTokenService(newMemoryTokenRepository(), users, config.AuthConfig{AccessTTL: 15 * time.Minute, RefreshTTL: time.Hour}, testJWTKeys)
+	return s, users, user
+}
+
+func accessID(t *testing.T, token string) string {
+	t.Helper()
+	claims, err := testJWTKeys.Parse(token)
+	if err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+	return claims.Id
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s, _, user := newTestTokenService()
+	first, err := s.Issue(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || accessID(t, second.AccessToken) == accessID(t, first.AccessToken) {
+		t.Error("refresh returned the same tokens")
+	}
+	if _, err := s.Refresh(second.RefreshToken); err != nil {
+		t.Errorf("refresh with rotated token: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	s, _, user := newTestTokenService()
+	first, _ := s.Issue(user)
+	other, _ := s.Issue(user)
+	second, _ := s.Refresh(first.RefreshToken)
+
+	if _, err := s.Refresh(first.RefreshToken); err == nil {
+		t.Fatal("reused refresh token was accepted")
+	}
+	if _, err := s.Refresh(second.RefreshToken); err == nil {
+		t.Error("token from the revoked family was accepted")
+	}
+	if revoked, _ := s.IsRevoked(accessID(t, second.AccessToken), user.ID); !revoked {
+		t.Error("access token from the revoked family is still valid")
+	}
+	if _, err := s.Refresh(other.RefreshToken); err != nil {
+		t.Errorf("other login was revoked: %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	s, _, user := newTestTokenService()
+	phone, _ := s.Issue(user)
+	laptop, _ := s.Issue(user)
+	expires := time.Now().Add(time.Minute)
+
+	if err := s.Logout(user.ID, accessID(t, phone.AccessToken), expires); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := s.IsRevoked(accessID(t, phone.AccessToken), user.ID); !revoked {
+		t.Error("access token is valid after logout")
+	}
+	if _, err := s.Refresh(phone.RefreshToken); err == nil {
+		t.Error("refresh token is valid after logout")
+	}
+	if revoked, _ := s.IsRevoked(accessID(t, laptop.AccessToken), user.ID); revoked {
+		t.Error("logout revoked another device")
+	}
+
+	if err := s.LogoutAll(user.ID, accessID(t, laptop.AccessToken), expires); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(laptop.RefreshToken); err == nil {
+		t.Error("refresh token is valid after logging out all devices")
+	}
+}
+
+func TestRefreshUsesCurrentRole(t *testing.T) {
+	s, users, user := newTestTokenService()
+	pair, _ := s.Issue(user)
+	if claims, _ := testJWTKeys.Parse(pair.AccessToken); claims.Role != "user" {
+		t.Fatalf("role = %q, want user", claims.Role)
+	}
+
+	users.UpdateRole(user.ID, models.UserRoleModerator)
+	pair, err := s.Refresh(pair.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, _ := testJWTKeys.Parse(pair.AccessToken); claims.Role != "moderator" {
+		t.Errorf("role after refresh = %q, want moderator", claims.Role)
+	}
+}
@@ -0,0 +1,423 @@
+package service
+
+import (
+	"bufio"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transcriptBatchSize 匯出紀錄時每批從資料庫讀取的消息數
+const transcriptBatchSize = 500
+
+// TranscriptFormats 支援的紀錄匯出格式
+var TranscriptFormats = map[string]struct {
+	ContentType string
+	Extension   string
+}{
+	"markdown": {"text/markdown; charset=utf-8", "md"},
+	"json":     {"application/json; charset=utf-8", "json"},
+	"csv":      {"text/csv; charset=utf-8", "csv"},
+	"text":     {"text/plain; charset=utf-8", "txt"},
+}
+
+// roleNames 各角色的顯示名稱
+var roleNames = map[string]string{
+	"proponent": "正方",
+	"opponent":  "反方",
+	"moderator": "主持人",
+	"spectator": "觀眾",
+	"system":    "系統",
+}
+
+// TranscriptService 匯出辯論房間的完整紀錄
+type TranscriptService struct {
+	roomRepo    repository.RoomRepository
+	userRepo    repository.UserRepository
+	messageRepo repository.MessageRepository
+}
+
+func NewTranscriptService(roomRepo repository.RoomRepository, userRepo repository.UserRepository, messageRepo repository.MessageRepository) *TranscriptService {
+	return &TranscriptService{
+		roomRepo:    roomRepo,
+		userRepo:    userRepo,
+		messageRepo: messageRepo,
+	}
+}
+
+// Participant 是紀錄中的一位參與者
+type Participant struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// TranscriptMeta 是紀錄開頭的房間資訊
+type TranscriptMeta struct {
+	RoomID       uint          `json:"room_id"`
+	Topic        string        `json:"topic"`
+	Format       string        `json:"format"`
+	Status       string        `json:"status"`
+	CreatedAt    time.Time     `json:"created_at"`
+	StartTime    *time.Time    `json:"start_time,omitempty"`
+	EndTime      *time.Time    `json:"end_time,omitempty"`
+	Participants []Participant `json:"participants"`
+}
+
+// TranscriptEntry 是紀錄中的一則消息
+type TranscriptEntry struct {
+	Seq       int       `json:"seq"` // 消息在房間完整紀錄中的位置，從 1 開始
+	ID        uint      `json:"id"`
+	Time      time.Time `json:"time"`
+	UserID    uint      `json:"user_id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	Channel   string    `json:"channel"`
+	Type      string    `json:"type"`
+	Kind      string    `json:"kind,omitempty"`
+	ReplyToID uint      `json:"reply_to_id,omitempty"`
+	Content   string    `json:"content"`
+}
+
+// TranscriptOptions 控制紀錄匯出的內容
+type TranscriptOptions struct {
+	Format        string
+	IncludeSystem bool
+}
+
+// Meta 查詢房間資訊和參與者，房間不存在時返回錯誤
+func (s *TranscriptService) Meta(roomID uint) (*TranscriptMeta, error) {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return nil, errors.New("房間不存在")
+	}
+
+	meta := &TranscriptMeta{
+		RoomID:    room.ID,
+		Topic:     room.Name,
+		Format:    room.Format,
+		Status:    string(room.Status),
+		CreatedAt: room.CreatedAt,
+	}
+	if !room.StartTime.IsZero() {
+		meta.StartTime = &room.StartTime
+	}
+	if !room.EndTime.IsZero() {
+		meta.EndTime = &room.EndTime
+	}
+
+	roles := []struct {
+		id   uint
+		role string
+	}{
+		{room.ProponentID, "proponent"},
+		{room.OpponentID, "opponent"},
+		{room.OwnerID, "moderator"},
+	}
+	for _, spectatorID := range room.Spectators {
+		roles = append(roles, struct {
+			id   uint
+			role string
+		}{spectatorID, "spectator"})
+	}
+
+	ids := make([]uint, 0, len(roles))
+	for _, r := range roles {
+		if r.id != 0 {
+			ids = append(ids, r.id)
+		}
+	}
+	usernames, err := s.usernames(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Participants = []Participant{}
+	for _, r := range roles {
+		if r.id != 0 {
+			meta.Participants = append(meta.Participants, Participant{
+				UserID:   r.id,
+				Username: usernames[r.id],
+				Role:     r.role,
+			})
+		}
+	}
+
+	return meta, nil
+}
+
+// Write 將房間紀錄以指定格式寫入 w，消息分批讀取並逐則輸出
+func (s *TranscriptService) Write(w io.Writer, meta *TranscriptMeta, opts TranscriptOptions) error {
+	var tw transcriptWriter
+	switch opts.Format {
+	case "markdown":
+		tw = &markdownTranscript{w: bufio.NewWriter(w)}
+	case "json":
+		tw = &jsonTranscript{w: bufio.NewWriter(w)}
+	case "csv":
+		tw = &csvTranscript{w: csv.NewWriter(w)}
+	case "text":
+		tw = &textTranscript{w: bufio.NewWriter(w)}
+	default:
+		return fmt.Errorf("unsupported transcript format: %s", opts.Format)
+	}
+
+	usernames := make(map[uint]string, len(meta.Participants))
+	for _, p := range meta.Participants {
+		usernames[p.UserID] = p.Username
+	}
+
+	if err := tw.header(meta); err != nil {
+		return err
+	}
+
+	seq := 0
+	err := s.messageRepo.EachByRoom(meta.RoomID, transcriptBatchSize, func(batch []models.Message) error {
+		// 查詢不在參與者列表中的發言者，例如已離開的觀眾
+		var missing []uint
+		for _, msg := range batch {
+			if _, ok := usernames[msg.UserID]; !ok && msg.UserID != 0 {
+				missing = append(missing, msg.UserID)
+				usernames[msg.UserID] = ""
+			}
+		}
+		found, err := s.usernames(missing)
+		if err != nil {
+			return err
+		}
+		for id, name := range found {
+			usernames[id] = name
+		}
+
+		for _, msg := range batch {
+			seq++
+			if msg.Role == "system" && !opts.IncludeSystem {
+				continue
+			}
+			channel := msg.Channel
+			if channel == "" {
+				channel = models.ChannelFloor
+			}
+			err := tw.entry(&TranscriptEntry{
+				Seq:       seq,
+				ID:        msg.ID,
+				Time:      msg.CreatedAt,
+				UserID:    msg.UserID,
+				Username:  usernames[msg.UserID],
+				Role:      msg.Role,
+				Channel:   channel,
+				Type:      msg.Type,
+				Kind:      string(msg.Kind),
+				ReplyToID: msg.ReplyToID,
+				Content:   msg.Content,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.close()
+}
+
+// usernames 批量查詢用戶名
+func (s *TranscriptService) usernames(ids []uint) (map[uint]string, error) {
+	users, err := s.userRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.Username
+	}
+	return names, nil
+}
+
+// transcriptWriter 是單一匯出格式的輸出器，依序呼叫 header、entry 和 close
+type transcriptWriter interface {
+	header(meta *TranscriptMeta) error
+	entry(e *TranscriptEntry) error
+	close() error
+}
+
+// speaker 返回消息發言者的顯示文字
+func (e *TranscriptEntry) speaker() string {
+	role := roleNames[e.Role]
+	if role == "" {
+		role = e.Role
+	}
+	if e.Username == "" {
+		return role
+	}
+	return role + " " + e.Username
+}
+
+// formatTime 以統一格式顯示時間，零值顯示為空
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// markdownTranscript 輸出 Markdown 格式的紀錄
+type markdownTranscript struct {
+	w *bufio.Writer
+}
+
+func (t *markdownTranscript) header(meta *TranscriptMeta) error {
+	fmt.Fprintf(t.w, "# %s\n\n", meta.Topic)
+	fmt.Fprintf(t.w, "- 房間：%d\n", meta.RoomID)
+	fmt.Fprintf(t.w, "- 賽制：%s\n", meta.Format)
+	fmt.Fprintf(t.w, "- 狀態：%s\n", meta.Status)
+	fmt.Fprintf(t.w, "- 建立時間：%s\n", formatTime(&meta.CreatedAt))
+	if meta.StartTime != nil {
+		fmt.Fprintf(t.w, "- 開始時間：%s\n", formatTime(meta.StartTime))
+	}
+	if meta.EndTime != nil {
+		fmt.Fprintf(t.w, "- 結束時間：%s\n", formatTime(meta.EndTime))
+	}
+
+	fmt.Fprintf(t.w, "\n## 參與者\n\n")
+	for _, p := range meta.Participants {
+		fmt.Fprintf(t.w, "- %s：%s (#%d)\n", roleNames[p.Role], p.Username, p.UserID)
+	}
+
+	fmt.Fprintf(t.w, "\n## 紀錄\n\n")
+	return nil
+}
+
+func (t *markdownTranscript) entry(e *TranscriptEntry) error {
+	content := strings.ReplaceAll(e.Content, "\n", "  \n  ")
+	if e.Role == "system" {
+		fmt.Fprintf(t.w, "%d. `%s` _%s_\n", e.Seq, e.Time.Format("15:04:05"), content)
+	} else {
+		fmt.Fprintf(t.w, "%d. `%s` **%s**", e.Seq, e.Time.Format("15:04:05"), e.speaker())
+		if e.Channel == models.ChannelGallery {
+			fmt.Fprintf(t.w, " (觀眾席)")
+		}
+		if e.Kind != "" {
+			fmt.Fprintf(t.w, " [%s]", e.Kind)
+		}
+		if e.ReplyToID != 0 {
+			fmt.Fprintf(t.w, " ↩ #%d", e.ReplyToID)
+		}
+		fmt.Fprintf(t.w, "：%s\n", content)
+	}
+	return nil
+}
+
+func (t *markdownTranscript) close() error {
+	return t.w.Flush()
+}
+
+// jsonTranscript 輸出 {"room": {...}, "messages": [...]} 格式的紀錄，消息逐則編碼
+type jsonTranscript struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (t *jsonTranscript) header(meta *TranscriptMeta) error {
+	room, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.w, `{"room":%s,"messages":[`, room)
+	return nil
+}
+
+func (t *jsonTranscript) entry(e *TranscriptEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if t.count > 0 {
+		t.w.WriteByte(',')
+	}
+	t.count++
+	_, err = t.w.Write(data)
+	return err
+}
+
+func (t *jsonTranscript) close() error {
+	t.w.WriteString("]}\n")
+	return t.w.Flush()
+}
+
+// csvTranscript 輸出每則消息一行的 CSV，房間資訊不適合表格格式因此不包含在內
+type csvTranscript struct {
+	w *csv.Writer
+}
+
+func (t *csvTranscript) header(meta *TranscriptMeta) error {
+	return t.w.Write([]string{"seq", "id", "time", "user_id", "username", "role", "channel", "type", "kind", "reply_to_id", "content"})
+}
+
+func (t *csvTranscript) entry(e *TranscriptEntry) error {
+	replyTo := ""
+	if e.ReplyToID != 0 {
+		replyTo = strconv.FormatUint(uint64(e.ReplyToID), 10)
+	}
+	return t.w.Write([]string{
+		strconv.Itoa(e.Seq),
+		strconv.FormatUint(uint64(e.ID), 10),
+		e.Time.Format(time.RFC3339),
+		strconv.FormatUint(uint64(e.UserID), 10),
+		e.Username,
+		e.Role,
+		e.Channel,
+		e.Type,
+		e.Kind,
+		replyTo,
+		e.Content,
+	})
+}
+
+func (t *csvTranscript) close() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// textTranscript 輸出純文字格式的紀錄
+type textTranscript struct {
+	w *bufio.Writer
+}
+
+func (t *textTranscript) header(meta *TranscriptMeta) error {
+	fmt.Fprintf(t.w, "辯題：%s\n", meta.Topic)
+	fmt.Fprintf(t.w, "房間：%d  賽制：%s  狀態：%s\n", meta.RoomID, meta.Format, meta.Status)
+	fmt.Fprintf(t.w, "建立時間：%s\n", formatTime(&meta.CreatedAt))
+	if meta.StartTime != nil || meta.EndTime != nil {
+		fmt.Fprintf(t.w, "辯論時間：%s - %s\n", formatTime(meta.StartTime), formatTime(meta.EndTime))
+	}
+	fmt.Fprintf(t.w, "參與者：\n")
+	for _, p := range meta.Participants {
+		fmt.Fprintf(t.w, "  %s：%s\n", roleNames[p.Role], p.Username)
+	}
+	fmt.Fprintf(t.w, "\n")
+	return nil
+}
+
+func (t *textTranscript) entry(e *TranscriptEntry) error {
+	channel := ""
+	if e.Channel == models.ChannelGallery {
+		channel = "[觀眾席] "
+	}
+	fmt.Fprintf(t.w, "[%s] %s%s：%s\n", e.Time.Format("15:04:05"), channel, e.speaker(), e.Content)
+	return nil
+}
+
+func (t *textTranscript) close() error {
+	return t.w.Flush()
+}
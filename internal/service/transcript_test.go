@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"debate_web/internal/repository/models"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTranscriptJSON(t *testing.T) {
+	_, rooms, messages := newTestWebSocketService()
+	users := &memoryUserRepository{}
+	users.Create(&models.User{Username: "alice"})
+	users.Create(&models.User{Username: "bob"})
+
+	room := &models.Room{Name: "死刑應該廢除", ProponentID: 1, OpponentID: 2}
+	rooms.Create(room)
+	messages.Create(&models.Message{RoomID: room.ID, Role: "system", Content: "辯論開始"})
+	messages.Create(&models.Message{RoomID: room.ID, UserID: 1, Role: "proponent", Content: "我方認為\n應該廢除"})
+	messages.Create(&models.Message{RoomID: room.ID, UserID: 2, Role: "opponent", Content: `反對 "廢除"`, ReplyToID: 2})
+
+	s := NewTranscriptService(rooms, users, messages)
+	meta, err := s.Meta(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf, meta, TranscriptOptions{Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Room     TranscriptMeta    `json:"room"`
+		Messages []TranscriptEntry `json:"messages"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, buf.String())
+	}
+	if len(out.Room.Participants) != 2 || out.Room.Participants[0].Username != "alice" {
+		t.Errorf("unexpected participants: %+v", out.Room.Participants)
+	}
+	// 系統消息被略過，但序號仍對應完整紀錄中的位置
+	if len(out.Messages) != 2 || out.Messages[0].Seq != 2 || out.Messages[1].Username != "bob" {
+		t.Errorf("unexpected messages: %+v", out.Messages)
+	}
+}
+
+func TestTranscriptFormats(t *testing.T) {
+	_, rooms, messages := newTestWebSocketService()
+	users := &memoryUserRepository{}
+	room := &models.Room{Name: "辯題"}
+	rooms.Create(room)
+	messages.Create(&models.Message{RoomID: room.ID, Role: "system", Content: "辯論開始"})
+
+	s := NewTranscriptService(rooms, users, messages)
+	meta, _ := s.Meta(room.ID)
+
+	for format := range TranscriptFormats {
+		var buf bytes.Buffer
+		if err := s.Write(&buf, meta, TranscriptOptions{Format: format, IncludeSystem: true}); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+		if !strings.Contains(buf.String(), "辯論開始") {
+			t.Errorf("%s: system message missing from output:\n%s", format, buf.String())
+		}
+	}
+}
@@ -0,0 +1,508 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"debate_web/internal/config"
+	"debate_web/internal/qrcode"
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// TOTP 的參數，使用驗證器應用程式普遍支援的 RFC 6238 預設值
+const (
+	totpPeriod      = 30
+	totpDigits      = 6 // 預設的驗證碼位數
+	totpSkew        = 1 // 允許前後各一個時間步的時鐘誤差
+	totpSecretBytes = 20
+
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 10
+
+	defaultTwoFactorChallengeTTL = 5 * time.Minute
+	defaultTwoFactorIssuer       = "debate_web"
+	qrCodeScale                  = 6
+)
+
+// totpModulus 是各個支援的驗證碼位數對應的模數
+var totpModulus = map[int]uint32{6: 1000000, 8: 100000000}
+
+// totpEncoding 是 otpauth 網址中密鑰使用的 base32 編碼
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorService 管理以 TOTP 驗證器進行的兩步驟驗證
+// 啟用後登入分為兩步：密碼正確時只發出短期的 challenge token，輸入驗證碼或備用碼後才發出正式的憑證
+type TwoFactorService struct {
+	repo      repository.TwoFactorRepository
+	userRepo  repository.UserRepository
+	tokenRepo repository.TokenRepository
+	users     *UserService // 驗證碼錯誤時沿用登入失敗的鎖定規則
+	tokens    *TokenService
+	cfg       config.TwoFactorConfig
+	required  map[models.UserRole]bool
+}
+
+func NewTwoFactorService(repo repository.TwoFactorRepository, userRepo repository.UserRepository, tokenRepo repository.TokenRepository, users *UserService, tokens *TokenService, cfg config.TwoFactorConfig) *TwoFactorService {
+	if cfg.ChallengeTTL <= 0 {
+		cfg.ChallengeTTL = defaultTwoFactorChallengeTTL
+	}
+	if cfg.Issuer == "" {
+		cfg.Issuer = defaultTwoFactorIssuer
+	}
+	if _, ok := totpModulus[cfg.Digits]; !ok {
+		if cfg.Digits != 0 {
+			log.Printf("two_factor.digits %d is not supported, using %d", cfg.Digits, totpDigits)
+		}
+		cfg.Digits = totpDigits
+	}
+	required := make(map[models.UserRole]bool)
+	for _, role := range cfg.RequiredRoles {
+		required[models.UserRole(role)] = true
+	}
+	return &TwoFactorService{
+		repo:      repo,
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		users:     users,
+		tokens:    tokens,
+		cfg:       cfg,
+		required:  required,
+	}
+}
+
+// TwoFactorStatus 是用戶兩步驟驗證的狀態
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	Required               bool       `json:"required"` // 用戶的角色必須啟用兩步驟驗證
+	RecoveryCodesRemaining int64      `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorEnrollment 是設定驗證器需要的資料，QRCode 是 PNG 圖片的 data URL
+type TwoFactorEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+// TwoFactorChallenge 是密碼驗證通過後、輸入驗證碼前的登入狀態
+// SetupRequired 表示用戶的角色必須啟用兩步驟驗證但尚未設定，需先以 challenge token 完成設定
+type TwoFactorChallenge struct {
+	Token         string `json:"challenge_token"`
+	ExpiresIn     int    `json:"expires_in"`
+	SetupRequired bool   `json:"setup_required"`
+}
+
+// Required 返回用戶的角色是否必須啟用兩步驟驗證
+func (s *TwoFactorService) Required(user *models.User) bool {
+	return s.required[user.Role]
+}
+
+// Status 返回用戶兩步驟驗證的狀態
+func (s *TwoFactorService) Status(userID uint) (*TwoFactorStatus, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	status := &TwoFactorStatus{
+		Enabled:  user.TwoFactorEnabled(),
+		Required: s.Required(user),
+	}
+	if status.Enabled {
+		status.EnabledAt = user.TOTPEnabledAt
+		if status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnroll 驗證密碼後產生新的密鑰，以驗證碼確認前不會啟用
+func (s *TwoFactorService) BeginEnroll(userID uint, password string) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, errors.New("密碼不正確")
+	}
+	return s.beginEnroll(user)
+}
+
+// ConfirmEnroll 以驗證器產生的驗證碼確認密鑰並啟用兩步驟驗證，返回只顯示這一次的備用碼
+// 啟用後登出其他裝置，目前的登入保持有效
+func (s *TwoFactorService) ConfirmEnroll(userID uint, accessID, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	codes, err := s.confirmEnroll(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.RevokeOthers(userID, accessID); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable 驗證密碼和驗證碼後停用兩步驟驗證，角色必須啟用時不能停用
+func (s *TwoFactorService) Disable(userID uint, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return errors.New("用戶不存在")
+	}
+	if !user.TwoFactorEnabled() {
+		return errors.New("尚未啟用兩步驟驗證")
+	}
+	if s.Required(user) {
+		return errors.New("你的角色必須啟用兩步驟驗證")
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return errors.New("密碼不正確")
+	}
+	if err := s.checkCode(user, code); err != nil {
+		return err
+	}
+
+	if err := s.repo.Disable(userID); err != nil {
+		return err
+	}
+	log.Printf("user %d disabled two-factor authentication", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes 以驗證碼確認後產生新的備用碼，舊的備用碼隨即失效
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("用戶不存在")
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, errors.New("尚未啟用兩步驟驗證")
+	}
+	step, ok := verifyTOTP(user.TOTPSecret, code, s.cfg.Digits, time.Now())
+	if !ok {
+		return nil, errors.New("驗證碼錯誤")
+	}
+	if err := s.useStep(user.ID, step); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Reset 由管理員為遺失驗證器的用戶停用兩步驟驗證，並登出其所有裝置
+// 角色必須啟用時，用戶下次登入會被要求重新設定
+func (s *TwoFactorService) Reset(userID uint) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return errors.New("用戶不存在")
+	}
+	if err := s.repo.Disable(userID); err != nil {
+		return err
+	}
+	if err := s.tokenRepo.InvalidateUserTokens(userID, models.UserTokenTwoFactor); err != nil {
+		return err
+	}
+	return s.tokens.RevokeAll(userID)
+}
+
+// Challenge 在密碼驗證通過後決定是否需要第二步，不需要時返回 nil
+func (s *TwoFactorService) Challenge(user *models.User) (*TwoFactorChallenge, error) {
+	enabled := user.TwoFactorEnabled()
+	if !enabled && !s.Required(user) {
+		return nil, nil
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	err = s.tokenRepo.CreateUserToken(&models.UserToken{
+		UserID:    user.ID,
+		Purpose:   models.UserTokenTwoFactor,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.ChallengeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{
+		Token:         token,
+		ExpiresIn:     int(s.cfg.ChallengeTTL.Seconds()),
+		SetupRequired: !enabled,
+	}, nil
+}
+
+// ChallengeEnroll 為登入時被要求設定兩步驟驗證的用戶產生密鑰，密碼已在登入的第一步驗證過
+func (s *TwoFactorService) ChallengeEnroll(challengeToken string) (*TwoFactorEnrollment, error) {
+	_, user, err := s.findChallenge(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginEnroll(user)
+}
+
+// CompleteLogin 以驗證碼或備用碼完成登入，返回通過驗證的用戶
+// 登入時才完成設定的用戶會同時得到新的備用碼
+// 驗證碼錯誤計入連續登入失敗的次數，達到上限時帳號被鎖定，challenge token 隨即失效
+func (s *TwoFactorService) CompleteLogin(challengeToken, code string) (*models.User, []string, error) {
+	challenge, user, err := s.findChallenge(challengeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return nil, nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
+	var codes []string
+	if user.TwoFactorEnabled() {
+		err = s.checkCode(user, code)
+	} else {
+		codes, err = s.confirmEnroll(user, code)
+	}
+	if err != nil {
+		if err.Error() != "驗證碼錯誤" {
+			return nil, nil, err
+		}
+		return nil, nil, s.recordFailure(user)
+	}
+
+	used, err := s.tokenRepo.UseUserToken(challenge.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !used {
+		return nil, nil, errors.New("無效或已過期的登入驗證")
+	}
+	if user.FailedLogins > 0 {
+		if err := s.userRepo.ResetLoginFailures(user.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, codes, nil
+}
+
+// findChallenge 查詢尚未使用且未過期的 challenge token 及其用戶
+func (s *TwoFactorService) findChallenge(challengeToken string) (*models.UserToken, *models.User, error) {
+	challenge, err := s.tokenRepo.FindUserToken(models.UserTokenTwoFactor, hashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("無效或已過期的登入驗證")
+		}
+		return nil, nil, err
+	}
+	if challenge.UsedAt != nil || !time.Now().Before(challenge.ExpiresAt) {
+		return nil, nil, errors.New("無效或已過期的登入驗證")
+	}
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return nil, nil, errors.New("無效或已過期的登入驗證")
+	}
+	if user.DisabledAt != nil {
+		return nil, nil, errors.New("帳號已被停用")
+	}
+	return challenge, user, nil
+}
+
+// recordFailure 記錄一次驗證碼錯誤，帳號被鎖定時讓尚未使用的 challenge token 失效
+func (s *TwoFactorService) recordFailure(user *models.User) error {
+	err := s.users.recordFailure(user, "驗證碼錯誤")
+	var locked *AccountLockedError
+	if errors.As(err, &locked) {
+		if err := s.tokenRepo.InvalidateUserTokens(user.ID, models.UserTokenTwoFactor); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// beginEnroll 為尚未啟用的用戶產生並保存新的密鑰
+func (s *TwoFactorService) beginEnroll(user *models.User) (*TwoFactorEnrollment, error) {
+	if user.TwoFactorEnabled() {
+		return nil, errors.New("已經啟用兩步驟驗證")
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPendingSecret(user.ID, secret); err != nil {
+		return nil, err
+	}
+
+	uri := totpURI(s.cfg.Issuer, user.Username, secret, s.cfg.Digits)
+	code, err := qrcode.Encode([]byte(uri))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := code.WritePNG(&buf, qrCodeScale); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollment{
+		Secret:     secret,
+		OTPAuthURI: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// confirmEnroll 以驗證碼確認設定中的密鑰，啟用兩步驟驗證並產生備用碼
+func (s *TwoFactorService) confirmEnroll(user *models.User, code string) ([]string, error) {
+	if user.TwoFactorEnabled() {
+		return nil, errors.New("已經啟用兩步驟驗證")
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, errors.New("請先開始設定兩步驟驗證")
+	}
+	step, ok := verifyTOTP(user.TOTPPendingSecret, code, s.cfg.Digits, time.Now())
+	if !ok {
+		return nil, errors.New("驗證碼錯誤")
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(user.ID, user.TOTPPendingSecret, step, time.Now(), hashes); err != nil {
+		return nil, err
+	}
+	log.Printf("user %d enabled two-factor authentication", user.ID)
+	return codes, nil
+}
+
+// checkCode 驗證已啟用用戶的驗證碼或備用碼，兩者都只能使用一次
+func (s *TwoFactorService) checkCode(user *models.User, code string) error {
+	if step, ok := verifyTOTP(user.TOTPSecret, code, s.cfg.Digits, time.Now()); ok {
+		return s.useStep(user.ID, step)
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if len(normalized) != base32.StdEncoding.EncodedLen(recoveryCodeBytes) {
+		return errors.New("驗證碼錯誤")
+	}
+	used, err := s.repo.UseRecoveryCode(user.ID, hashToken(normalized))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("驗證碼錯誤")
+	}
+	log.Printf("user %d used a recovery code", user.ID)
+	return nil
+}
+
+// useStep 記錄驗證碼的時間步，同一個驗證碼第二次使用時視為錯誤
+func (s *TwoFactorService) useStep(userID uint, step int64) error {
+	used, err := s.repo.UseStep(userID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("驗證碼錯誤")
+	}
+	return nil
+}
+
+// newTOTPSecret 產生 base32 編碼的隨機密鑰
+func newTOTPSecret() (string, error) {
+	b := make([]byte, totpSecretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpURI 返回驗證器應用程式掃描的 otpauth 網址
+func totpURI(issuer, username, secret string, digits int) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	uri := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + username,
+		// 部分驗證器不會將 + 解碼為空白
+		RawQuery: strings.ReplaceAll(query.Encode(), "+", "%20"),
+	}
+	return uri.String()
+}
+
+// totpCode 依 RFC 6238 計算密鑰在時間步 step 的 digits 位驗證碼，digits 必須是 totpModulus 中的位數
+func totpCode(key []byte, step int64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%totpModulus[digits])
+}
+
+// verifyTOTP 檢查驗證碼是否符合 now 前後允許誤差內的時間步，返回符合的時間步
+func verifyTOTP(secret, code string, digits int, now time.Time) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != digits {
+		return 0, false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	current := now.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step, digits)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes 產生一組備用碼及其雜湊值，備用碼格式為 xxxx-xxxx-xxxx-xxxx
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+		var groups []string
+		for j := 0; j < len(raw); j += 4 {
+			groups = append(groups, raw[j:j+4])
+		}
+		codes = append(codes, strings.Join(groups, "-"))
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 移除備用碼中的分隔符號並轉為小寫
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
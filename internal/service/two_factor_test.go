@@ -0,0 +1,330 @@
+package service
+
+import (
+	"bytes"
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryTwoFactorRepository 是只保存在記憶體中的 TwoFactorRepository，直接修改 memoryUserRepository 中的用戶
+type memoryTwoFactorRepository struct {
+	users *memoryUserRepository
+	mu    sync.Mutex
+	codes []*models.RecoveryCode
+}
+
+func (r *memoryTwoFactorRepository) update(userID uint, fn func(*models.User)) {
+	r.users.mu.Lock()
+	defer r.users.mu.Unlock()
+	fn(r.users.users[userID-1])
+}
+
+func (r *memoryTwoFactorRepository) SetPendingSecret(userID uint, secret string) error {
+	r.update(userID, func(u *models.User) { u.TOTPPendingSecret = secret })
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) Enable(userID uint, secret string, step int64, at time.Time, codeHashes []string) error {
+	r.update(userID, func(u *models.User) {
+		u.TOTPSecret, u.TOTPPendingSecret, u.TOTPEnabledAt, u.TOTPLastStep = secret, "", &at, step
+	})
+	return r.ReplaceRecoveryCodes(userID, codeHashes)
+}
+
+func (r *memoryTwoFactorRepository) Disable(userID uint) error {
+	r.update(userID, func(u *models.User) {
+		u.TOTPSecret, u.TOTPPendingSecret, u.TOTPEnabledAt, u.TOTPLastStep = "", "", nil, 0
+	})
+	return r.ReplaceRecoveryCodes(userID, nil)
+}
+
+func (r *memoryTwoFactorRepository) UseStep(userID uint, step int64) (bool, error) {
+	used := false
+	r.update(userID, func(u *models.User) {
+		if u.TOTPLastStep < step {
+			u.TOTPLastStep, used = step, true
+		}
+	})
+	return used, nil
+}
+
+func (r *memoryTwoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var kept []*models.RecoveryCode
+	for _, code := range r.codes {
+		if code.UserID != userID {
+			kept = append(kept, code)
+		}
+	}
+	for _, hash := range codeHashes {
+		kept = append(kept, &models.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	r.codes = kept
+	return nil
+}
+
+func (r *memoryTwoFactorRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, code := range r.codes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			now := time.Now()
+			code.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryTwoFactorRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var count int64
+	for _, code := range r.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newTestTwoFactorService(t *testing.T, role models.UserRole, lockout config.LockoutConfig) (*TwoFactorService, *TokenService, *memoryUserRepository, *models.User) {
+	t.Helper()
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	users := &memoryUserRepository{}
+	user := &models.User{Username: "alice", Password: string(hash), Role: role}
+	users.Create(user)
+
+	repo := newMemoryTokenRepository()
+	tokens := NewTokenService(repo, users, config.AuthConfig{AccessTTL: 15 * time.Minute, RefreshTTL: time.Hour}, testJWTKeys)
+	s := NewTwoFactorService(&memoryTwoFactorRepository{users: users}, users, repo, NewUserService(users, lockout), tokens, config.TwoFactorConfig{
+		Issuer:        "Debate Web",
+		RequiredRoles: []string{"admin", "moderator"},
+	})
+	tokens.RequireTwoFactor(s)
+	return s, tokens, users, user
+}
+
+// currentCode 返回密鑰目前的驗證碼
+func currentCode(t *testing.T, secret string) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totpCode(key, time.Now().Unix()/totpPeriod, totpDigits)
+}
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 附錄 B 的 SHA1 測試向量，6 位驗證碼取其最後 6 位
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1234567890, "89005924"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(key, tt.unix/totpPeriod, 8); got != tt.want {
+			t.Errorf("8-digit totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+		if got := totpCode(key, tt.unix/totpPeriod, 6); got != tt.want[2:] {
+			t.Errorf("6-digit totpCode at %d = %s, want %s", tt.unix, got, tt.want[2:])
+		}
+	}
+
+	secret := totpEncoding.EncodeToString(key)
+	now := time.Unix(1111111109, 0)
+	if _, ok := verifyTOTP(secret, "081804", 6, now.Add(totpPeriod*time.Second)); !ok {
+		t.Error("code from the previous step was rejected")
+	}
+	if _, ok := verifyTOTP(secret, "081804", 6, now.Add(2*totpPeriod*time.Second)); ok {
+		t.Error("code from two steps ago was accepted")
+	}
+	if _, ok := verifyTOTP(secret, "07081804", 8, now); !ok {
+		t.Error("8-digit code was rejected")
+	}
+	if _, ok := verifyTOTP(secret, "081804", 8, now); ok {
+		t.Error("6-digit code was accepted when 8 digits are configured")
+	}
+}
+
+func TestTwoFactorDigitsConfig(t *testing.T) {
+	newService := func(digits int) *TwoFactorService {
+		users := &memoryUserRepository{}
+		repo := newMemoryTokenRepository()
+		tokens := NewTokenService(repo, users, config.AuthConfig{AccessTTL: 15 * time.Minute, RefreshTTL: time.Hour}, testJWTKeys)
+		return NewTwoFactorService(&memoryTwoFactorRepository{users: users}, users, repo, NewUserService(users, config.LockoutConfig{}), tokens, config.TwoFactorConfig{Digits: digits})
+	}
+	for digits, want := range map[int]int{0: 6, 6: 6, 7: 6, 8: 8} {
+		if got := newService(digits).cfg.Digits; got != want {
+			t.Errorf("digits %d: configured %d, want %d", digits, got, want)
+		}
+	}
+
+	// 以 8 位驗證碼完成設定，otpauth 網址同樣告知驗證器使用 8 位
+	s := newService(8)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	user := &models.User{Username: "alice", Password: string(hash)}
+	s.userRepo.Create(user)
+	enrollment, err := s.BeginEnroll(user.ID, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, _ := url.Parse(enrollment.OTPAuthURI)
+	if uri.Query().Get("digits") != "8" {
+		t.Errorf("uri = %s, want digits=8", uri)
+	}
+	key, _ := totpEncoding.DecodeString(enrollment.Secret)
+	step := time.Now().Unix() / totpPeriod
+	if _, err := s.ConfirmEnroll(user.ID, "", totpCode(key, step, 6)); err == nil {
+		t.Fatal("6-digit code accepted")
+	}
+	if _, err := s.ConfirmEnroll(user.ID, "", totpCode(key, step, 8)); err != nil {
+		t.Fatalf("confirm with 8-digit code: %v", err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri, err := url.Parse(totpURI("Debate Web", "alice", "JBSWY3DPEHPK3PXP", 6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uri.Scheme != "otpauth" || uri.Host != "totp" || uri.Path != "/Debate Web:alice" {
+		t.Errorf("uri = %s", uri)
+	}
+	if strings.Contains(uri.RawQuery, "+") {
+		t.Errorf("query %q encodes spaces as +", uri.RawQuery)
+	}
+	query := uri.Query()
+	if query.Get("secret") != "JBSWY3DPEHPK3PXP" || query.Get("issuer") != "Debate Web" || query.Get("digits") != "6" {
+		t.Errorf("query = %v", query)
+	}
+}
+
+func TestTwoFactorEnrollAndLogin(t *testing.T) {
+	s, _, _, user := newTestTwoFactorService(t, models.UserRoleUser, config.LockoutConfig{})
+
+	if challenge, _ := s.Challenge(user); challenge != nil {
+		t.Fatal("challenge issued before two-factor authentication was enabled")
+	}
+	if _, err := s.BeginEnroll(user.ID, "wrong"); err == nil || err.Error() != "密碼不正確" {
+		t.Fatalf("enroll with wrong password: err = %v", err)
+	}
+	enrollment, err := s.BeginEnroll(user.ID, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.TrimPrefix(enrollment.QRCode, "data:image/png;base64,")
+	raw, _ := base64.StdEncoding.DecodeString(data)
+	if _, err := png.Decode(bytes.NewReader(raw)); err != nil {
+		t.Errorf("QR code is not a PNG: %v", err)
+	}
+
+	if _, err := s.ConfirmEnroll(user.ID, "", "000000"); err == nil || err.Error() != "驗證碼錯誤" {
+		t.Fatalf("confirm with wrong code: err = %v", err)
+	}
+	code := currentCode(t, enrollment.Secret)
+	recovery, err := s.ConfirmEnroll(user.ID, "", code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recovery), recoveryCodeCount)
+	}
+
+	user, _ = s.userRepo.FindByID(user.ID)
+	challenge, err := s.Challenge(user)
+	if err != nil || challenge == nil || challenge.SetupRequired {
+		t.Fatalf("challenge = %+v, err = %v", challenge, err)
+	}
+	// 確認設定時用過的驗證碼不能再用來登入
+	if _, _, err := s.CompleteLogin(challenge.Token, code); err == nil || err.Error() != "驗證碼錯誤" {
+		t.Fatalf("replayed code: err = %v", err)
+	}
+	if _, _, err := s.CompleteLogin(challenge.Token, strings.ToUpper(recovery[0])); err != nil {
+		t.Fatalf("login with recovery code: %v", err)
+	}
+	if _, _, err := s.CompleteLogin(challenge.Token, recovery[1]); err == nil || err.Error() != "無效或已過期的登入驗證" {
+		t.Errorf("reused challenge: err = %v", err)
+	}
+
+	challenge, _ = s.Challenge(user)
+	if _, _, err := s.CompleteLogin(challenge.Token, recovery[0]); err == nil || err.Error() != "驗證碼錯誤" {
+		t.Errorf("reused recovery code: err = %v", err)
+	}
+	if status, _ := s.Status(user.ID); !status.Enabled || status.RecoveryCodesRemaining != recoveryCodeCount-1 {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestTwoFactorRequiredRole(t *testing.T) {
+	s, tokens, _, user := newTestTwoFactorService(t, models.UserRoleModerator, config.LockoutConfig{})
+
+	// 在設定兩步驟驗證前取得的登入，換發時被拒絕
+	pair, _ := tokens.Issue(user)
+	if _, err := tokens.Refresh(pair.RefreshToken); err == nil {
+		t.Error("refresh succeeded without two-factor authentication")
+	}
+
+	challenge, err := s.Challenge(user)
+	if err != nil || challenge == nil || !challenge.SetupRequired {
+		t.Fatalf("challenge = %+v, err = %v", challenge, err)
+	}
+	if _, _, err := s.CompleteLogin(challenge.Token, "123456"); err == nil || err.Error() != "請先開始設定兩步驟驗證" {
+		t.Fatalf("login before setup: err = %v", err)
+	}
+	enrollment, err := s.ChallengeEnroll(challenge.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, recovery, err := s.CompleteLogin(challenge.Token, currentCode(t, enrollment.Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovery) != recoveryCodeCount {
+		t.Errorf("got %d recovery codes after setup", len(recovery))
+	}
+
+	if err := s.Disable(user.ID, "secret", recovery[0]); err == nil || err.Error() != "你的角色必須啟用兩步驟驗證" {
+		t.Errorf("disable for moderator: err = %v", err)
+	}
+	user, _ = s.userRepo.FindByID(user.ID)
+	pair, _ = tokens.Issue(user)
+	if _, err := tokens.Refresh(pair.RefreshToken); err != nil {
+		t.Errorf("refresh after setup: %v", err)
+	}
+}
+
+func TestTwoFactorFailuresLockAccount(t *testing.T) {
+	s, _, users, user := newTestTwoFactorService(t, models.UserRoleUser, config.LockoutConfig{MaxFailures: 2, Duration: time.Minute})
+	enrollment, _ := s.BeginEnroll(user.ID, "secret")
+	s.ConfirmEnroll(user.ID, "", currentCode(t, enrollment.Secret))
+
+	user, _ = users.FindByID(user.ID)
+	challenge, _ := s.Challenge(user)
+	if _, _, err := s.CompleteLogin(challenge.Token, "000000"); err == nil || err.Error() != "驗證碼錯誤" {
+		t.Fatalf("first wrong code: err = %v", err)
+	}
+	var locked *AccountLockedError
+	if _, _, err := s.CompleteLogin(challenge.Token, "000000"); !errors.As(err, &locked) {
+		t.Fatalf("second wrong code: err = %v, want AccountLockedError", err)
+	}
+	if _, _, err := s.CompleteLogin(challenge.Token, "000000"); err == nil || err.Error() != "無效或已過期的登入驗證" {
+		t.Errorf("challenge after lockout: err = %v", err)
+	}
+}
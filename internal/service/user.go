@@ -1,20 +1,100 @@
 package service
 
 import (
+	"debate_web/internal/config"
 	"debate_web/internal/repository"
 	"debate_web/internal/repository/models"
-	"debate_web/internal/utils"
 	"errors"
+	"log"
+	"time"
 
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 type UserService struct {
-	repo repository.UserRepository
+	repo    repository.UserRepository
+	lockout config.LockoutConfig
 }
 
-func NewUserService(repo repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func NewUserService(repo repository.UserRepository, lockout config.LockoutConfig) *UserService {
+	return &UserService{repo: repo, lockout: lockout}
+}
+
+// AccountLockedError 表示帳號因連續登入失敗而被暫時鎖定
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return "登入失敗次數過多，帳號已被暫時鎖定"
+}
+
+// dummyPasswordHash 用於用戶不存在時仍執行一次密碼比對，避免從回應時間推測用戶名是否存在
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("debate_web"), bcrypt.DefaultCost)
+
+// Authenticate 驗證用戶名和密碼
+// 連續失敗達到上限時鎖定帳號，每次鎖定的時長加倍，鎖定期間即使密碼正確也會被拒絕
+func (s *UserService) Authenticate(username, password string) (*models.User, error) {
+	user, err := s.repo.FindByUsername(username)
+	if err != nil {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, errors.New("用戶名或密碼錯誤")
+	}
+
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, s.recordFailure(user, "用戶名或密碼錯誤")
+	}
+
+	// 密碼正確後才透露帳號已被停用
+	if user.DisabledAt != nil {
+		return nil, errors.New("帳號已被停用")
+	}
+
+	if user.FailedLogins > 0 || user.Lockouts > 0 || user.LockedUntil != nil {
+		if err := s.repo.ResetLoginFailures(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// recordFailure 記錄一次登入失敗，達到上限時鎖定帳號，未鎖定時返回以 reason 為訊息的錯誤
+func (s *UserService) recordFailure(user *models.User, reason string) error {
+	if s.lockout.MaxFailures <= 0 {
+		return errors.New(reason)
+	}
+
+	failures, err := s.repo.IncrementFailedLogins(user.ID)
+	if err != nil {
+		return err
+	}
+	if failures < s.lockout.MaxFailures {
+		return errors.New(reason)
+	}
+
+	until := time.Now().Add(lockoutDuration(s.lockout, user.Lockouts))
+	if err := s.repo.Lock(user.ID, until); err != nil {
+		return err
+	}
+	log.Printf("user %d locked until %s after %d failed logins", user.ID, until.Format(time.RFC3339), failures)
+	return &AccountLockedError{Until: until}
+}
+
+// lockoutDuration 計算第 n+1 次鎖定的時長，每次加倍直到上限
+func lockoutDuration(cfg config.LockoutConfig, lockouts int) time.Duration {
+	duration := cfg.Duration
+	for i := 0; i < lockouts && (cfg.MaxDuration <= 0 || duration < cfg.MaxDuration); i++ {
+		duration *= 2
+	}
+	if cfg.MaxDuration > 0 && duration > cfg.MaxDuration {
+		duration = cfg.MaxDuration
+	}
+	return duration
 }
 
 // CheckUserExists 檢查用戶是否存在
@@ -40,6 +120,9 @@ func (s *UserService) CreateUser(user *models.User) error {
 		return errors.New("用戶名已被使用")
 	}
 
+	if user.Role == "" {
+		user.Role = models.UserRoleUser
+	}
 	return s.repo.Create(user)
 }
 
@@ -47,6 +130,24 @@ func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 	return s.repo.FindByUsername(username)
 }
 
-func (s *UserService) GenerateToken(userID uint) (string, error) {
-	return utils.GenerateToken(userID)
+// PromoteAdmins 將指定的用戶設為管理員，不存在的用戶會被略過
+func (s *UserService) PromoteAdmins(usernames []string) error {
+	for _, username := range usernames {
+		user, err := s.repo.FindByUsername(username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("admin %q does not exist, skipped", username)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if user.Role == models.UserRoleAdmin {
+			continue
+		}
+		if err := s.repo.UpdateRole(user.ID, models.UserRoleAdmin); err != nil {
+			return err
+		}
+		log.Printf("user %d (%s) promoted to admin", user.ID, user.Username)
+	}
+	return nil
 }
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"debate_web/internal/config"
+	"debate_web/internal/repository/models"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticateLocksAfterRepeatedFailures(t *testing.T) {
+	users := &memoryUserRepository{}
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	users.Create(&models.User{Username: "alice", Password: string(hash)})
+	s := NewUserService(users, config.LockoutConfig{MaxFailures: 3, Duration: time.Minute, MaxDuration: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Authenticate("alice", "wrong"); err == nil || err.Error() != "用戶名或密碼錯誤" {
+			t.Fatalf("attempt %d: err = %v, want wrong password", i+1, err)
+		}
+	}
+
+	var locked *AccountLockedError
+	if _, err := s.Authenticate("alice", "wrong"); !errors.As(err, &locked) {
+		t.Fatalf("third failure: err = %v, want AccountLockedError", err)
+	}
+	if until := time.Until(locked.Until); until <= 0 || until > time.Minute {
+		t.Errorf("locked for %v, want about 1m", until)
+	}
+	if _, err := s.Authenticate("alice", "secret"); !errors.As(err, &locked) {
+		t.Errorf("correct password while locked: err = %v, want AccountLockedError", err)
+	}
+
+	// 鎖定到期後登入成功會清除紀錄
+	expired := time.Now().Add(-time.Second)
+	users.users[0].LockedUntil = &expired
+	if _, err := s.Authenticate("alice", "secret"); err != nil {
+		t.Fatalf("after lockout: err = %v", err)
+	}
+	if u := users.users[0]; u.FailedLogins != 0 || u.Lockouts != 0 || u.LockedUntil != nil {
+		t.Errorf("after success: failures = %d, lockouts = %d, locked = %v", u.FailedLogins, u.Lockouts, u.LockedUntil)
+	}
+}
+
+func TestLockoutDurationDoubles(t *testing.T) {
+	cfg := config.LockoutConfig{Duration: time.Minute, MaxDuration: 5 * time.Minute}
+	for lockouts, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := lockoutDuration(cfg, lockouts); got != want {
+			t.Errorf("lockoutDuration(%d) = %v, want %v", lockouts, got, want)
+		}
+	}
+}
+
+func TestAuthenticateUnknownUser(t *testing.T) {
+	s := NewUserService(&memoryUserRepository{}, config.LockoutConfig{MaxFailures: 3, Duration: time.Minute})
+	if _, err := s.Authenticate("nobody", "secret"); err == nil || err.Error() != "用戶名或密碼錯誤" {
+		t.Errorf("err = %v, want wrong password", err)
+	}
+}
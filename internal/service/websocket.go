@@ -1,6 +1,7 @@
 package service
 
 import (
+	"debate_web/internal/repository"
 	"debate_web/internal/repository/models"
 	"encoding/json"
 	"fmt"
@@ -13,44 +14,128 @@ import (
 
 // Client 代表一個 WebSocket 客戶端連接
 type Client struct {
-	Conn     *websocket.Conn      // WebSocket 連接
-	UserID   uint                 // 用戶 ID
-	RoomID   uint                 // 房間 ID
-	Role     string               // 用戶角色 (proponent/opponent/spectator)
-	SendChan chan *models.Message // 消息發送通道，用於異步傳送消息
+	Conn      *websocket.Conn      // WebSocket 連接
+	UserID    uint                 // 用戶 ID
+	Name      string               // 用戶的顯示名稱，連接時讀取
+	RoomID    uint                 // 房間 ID
+	Role      string               // 用戶角色 (proponent/opponent/spectator)
+	Format    *DebateFormat        // 房間的辯論賽制
+	Moderator bool                 // 是否擁有房間的主持權限
+	SendChan  chan *models.Message // 消息發送通道，只由所屬房間的 hub 關閉
+
+	hideGallery bool                 // 是否隱藏觀眾席消息，只在 hub 中讀寫
+	lastPost    map[string]time.Time // 各頻道最後發言時間，只在 readPump 中讀寫
+
+	// 以下速率限制的狀態只在 readPump 中讀寫
+	bucket          tokenBucket // 連接的令牌桶
+	violations      int         // 目前窗口內被丟棄的消息數
+	violationsSince time.Time   // 目前窗口的開始時間
+	closing         bool        // 已因濫用被斷開，等待連接關閉
 }
 
 // WebSocketService 管理所有的 WebSocket 連接和消息傳遞
+//
+// 每個有在線客戶端的房間都由一個獨立的 roomHub goroutine 負責，
+// 房間內的客戶端集合只在該 goroutine 中讀寫，其餘操作都透過 channel 傳遞命令。
 type WebSocketService struct {
-	clients    map[uint]map[*Client]bool // 兩層 map: roomID -> client -> bool
-	clientsMux sync.RWMutex              // 用於保護 clients map 的讀寫鎖
+	roomRepo      repository.RoomRepository
+	messageRepo   repository.MessageRepository
+	userRepo      repository.UserRepository
+	frameHandlers map[string]FrameHandler // 消息類型 -> 處理函數，只在啟動時註冊
+	moderation    *ModerationPipeline     // 聊天消息發送前的審核流程，只在啟動時設定
+	limiter       *frameLimiter           // 客戶端消息的速率限制，nil 表示不限制，只在啟動時設定
+	hubs          map[uint]*roomHub       // roomID -> 房間 hub
+	hubsMux       sync.Mutex              // 只保護 hubs map 及 hub 的引用計數
+
+	mutes    map[uint]map[uint]time.Time // roomID -> userID -> 禁言到期時間
+	mutesMux sync.Mutex
+}
+
+// ClientFrame 是客戶端發送的 WebSocket 消息
+// 聊天消息只使用內嵌的 Message，其餘欄位供控制命令使用
+type ClientFrame struct {
+	models.Message
+	TargetID uint   // 命令的目標，例如問題 ID
+	Side     string // 命令指定的一方 (proponent/opponent)
+}
+
+// FrameHandler 處理特定類型的客戶端消息
+type FrameHandler func(client *Client, frame *ClientFrame)
+
+// roomHub 擁有單一房間的所有客戶端狀態
+type roomHub struct {
+	roomID     uint
+	refs       int // 已註冊或正在註冊的連接數，受 WebSocketService.hubsMux 保護
+	register   chan *Client
+	unregister chan *Client
+	broadcast  chan outbound
+	gallery    chan galleryPreference
+	quit       chan struct{} // 由 WebSocketService 關閉，通知 hub 結束
+	done       chan struct{} // hub 結束後關閉，避免向已結束的 hub 發送命令時阻塞
+}
+
+// outbound 是交給 hub 投遞的消息，to 為 nil 時發送給房間內所有客戶端
+// evict 為 true 時，收到消息的客戶端隨後會被移出房間並斷開連接
+type outbound struct {
+	message *models.Message
+	to      func(*Client) bool
+	evict   bool
+}
+
+// galleryPreference 是客戶端切換觀眾席顯示的命令
+type galleryPreference struct {
+	client *Client
+	hide   bool
 }
 
 // NewWebSocketService 創建並初始化新的 WebSocket 服務
-func NewWebSocketService() *WebSocketService {
-	return &WebSocketService{
-		clients: make(map[uint]map[*Client]bool),
+func NewWebSocketService(roomRepo repository.RoomRepository, messageRepo repository.MessageRepository, userRepo repository.UserRepository) *WebSocketService {
+	s := &WebSocketService{
+		roomRepo:      roomRepo,
+		messageRepo:   messageRepo,
+		userRepo:      userRepo,
+		frameHandlers: make(map[string]FrameHandler),
+		moderation:    NewModerationPipeline(NewLengthFilter(nil)),
+		hubs:          make(map[uint]*roomHub),
+		mutes:         make(map[uint]map[uint]time.Time),
 	}
+
+	// 切換觀眾席顯示的控制消息，不需要廣播
+	s.HandleFrame("gallery_visibility", func(client *Client, frame *ClientFrame) {
+		s.setGalleryHidden(client, frame.Content == "hide")
+	})
+
+	return s
+}
+
+// HandleFrame 註冊特定類型客戶端消息的處理函數
+// 必須在開始接受連接之前呼叫，未註冊的類型都視為聊天消息
+func (s *WebSocketService) HandleFrame(frameType string, handler FrameHandler) {
+	s.frameHandlers[frameType] = handler
+}
+
+// UseModeration 替換聊天消息的審核流程，必須在開始接受連接之前呼叫
+func (s *WebSocketService) UseModeration(pipeline *ModerationPipeline) {
+	s.moderation = pipeline
 }
 
 // HandleConnection 處理新的 WebSocket 連接請求
 // 參數: websocket 連接、房間ID、用戶ID、用戶角色
 func (s *WebSocketService) HandleConnection(conn *websocket.Conn, roomID, userID uint, role string) {
-	client := &Client{
-		Conn:     conn,
-		UserID:   userID,
-		RoomID:   roomID,
-		Role:     role,
-		SendChan: make(chan *models.Message, 256), // 設置緩衝大小為 256 的消息通道
-	}
+	client := s.newClient(conn, roomID, userID, role)
 
 	s.addClient(client)
+	if s.limiter != nil {
+		s.limiter.connect(userID)
+	}
 
-	// 確保連接關閉時清理資源
+	// 確保連接關閉時清理資源，SendChan 由 hub 在註銷時關閉
 	defer func() {
+		if s.limiter != nil {
+			s.limiter.disconnect(userID)
+		}
 		s.removeClient(client)
 		conn.Close()
-		close(client.SendChan)
 	}()
 
 	// 啟動讀寫處理
@@ -58,6 +143,33 @@ func (s *WebSocketService) HandleConnection(conn *websocket.Conn, roomID, userID
 	s.readPump(client)
 }
 
+// newClient 建立連接的客戶端，房間主持人和擁有全站審核權限的用戶取得主持權限
+func (s *WebSocketService) newClient(conn *websocket.Conn, roomID, userID uint, role string) *Client {
+	client := &Client{
+		Conn:     conn,
+		UserID:   userID,
+		Name:     s.DisplayName(userID),
+		RoomID:   roomID,
+		Role:     role,
+		Format:   GetDebateFormat(DefaultFormat),
+		SendChan: make(chan *models.Message, 256), // 設置緩衝大小為 256 的消息通道
+		lastPost: make(map[string]time.Time),
+	}
+	if room, err := s.roomRepo.FindByID(roomID); err == nil {
+		client.Format = GetDebateFormat(room.Format)
+		client.Moderator = canModerateRoom(s.userRepo, room, userID)
+	}
+	return client
+}
+
+// DisplayName 返回用戶在系統消息中的名稱，查不到用戶時以 ID 表示
+func (s *WebSocketService) DisplayName(userID uint) string {
+	if user, err := s.userRepo.FindByID(userID); err == nil {
+		return user.Name()
+	}
+	return fmt.Sprintf("用戶 %d", userID)
+}
+
 // readPump 持續監聽並處理從客戶端接收的消息
 func (s *WebSocketService) readPump(client *Client) {
 	client.Conn.SetReadLimit(4096) // 設置最大消息大小為 4KB
@@ -76,28 +188,167 @@ func (s *WebSocketService) readPump(client *Client) {
 			break
 		}
 
+		// 已被斷開的連接在關閉前收到的消息直接丟棄
+		if client.closing || !s.allowFrame(client) {
+			continue
+		}
+
 		// 解析接收到的消息
-		var msg models.Message
-		if err := json.Unmarshal(message, &msg); err != nil {
+		var frame ClientFrame
+		if err := json.Unmarshal(message, &frame); err != nil {
 			log.Printf("message parse error: %v", err)
 			continue
 		}
 
-		// 設置消息的基本屬性
-		msg.UserID = client.UserID
-		msg.RoomID = client.RoomID
-		msg.Role = client.Role
+		s.handleFrame(client, &frame)
+	}
+}
+
+// mutedFrames 是被禁言的用戶仍可發送的消息類型，這些消息不會在房間內產生內容
+var mutedFrames = map[string]bool{
+	"gallery_visibility": true,
+	"report":             true,
+}
+
+// handleFrame 將客戶端消息分派給已註冊的處理函數，其餘視為聊天消息
+func (s *WebSocketService) handleFrame(client *Client, frame *ClientFrame) {
+	if until, ok := s.MutedUntil(client.RoomID, client.UserID); ok && !mutedFrames[frame.Type] {
+		s.SendError(client, fmt.Sprintf("您已被禁言至 %s", until.Format("15:04:05")))
+		return
+	}
+
+	if handler, ok := s.frameHandlers[frame.Type]; ok {
+		handler(client, frame)
+		return
+	}
+	s.handleMessage(client, &frame.Message)
+}
+
+// handleMessage 根據房間賽制的頻道規則處理客戶端發送的聊天消息
+// 只採用客戶端可以決定的欄位，類型、時間戳記和刪除狀態一律由伺服器設定
+func (s *WebSocketService) handleMessage(client *Client, input *models.Message) {
+	msg := &models.Message{
+		Type:      models.MessageTypeChat,
+		RoomID:    client.RoomID,
+		UserID:    client.UserID,
+		Role:      client.Role,
+		Content:   input.Content,
+		Channel:   input.Channel,
+		ReplyToID: input.ReplyToID,
+		Kind:      input.Kind,
+		Status:    models.MessageStatusPublished,
+	}
+	if msg.Channel == "" {
+		msg.Channel = models.ChannelFloor
+	}
+
+	policy := client.Format.Policy(msg.Channel)
+	switch {
+	case !policy.Enabled:
+		s.SendError(client, "此賽制未開放該頻道")
+		return
+	case !policy.CanWrite(client.Role):
+		s.SendError(client, "您的角色無法在該頻道發言")
+		return
+	}
+
+	if err := validateArgument(s.messageRepo, msg); err != nil {
+		s.SendError(client, err.Error())
+		return
+	}
+
+	verdict, ok := s.screen(client, msg, policy)
+	if !ok {
+		return
+	}
+	if verdict.Action == ModerationHold {
+		s.holdMessage(client, msg, verdict.Reason)
+		return
+	}
+
+	if policy.Persist {
+		if err := s.messageRepo.Create(msg); err != nil {
+			log.Printf("message persist error: %v", err)
+			s.SendError(client, "消息保存失敗")
+			return
+		}
+	}
+
+	// 廣播消息給房間內所有用戶
+	s.BroadcastToRoom(client.RoomID, msg)
+}
 
-		// 廣播消息給房間內所有用戶
-		s.BroadcastToRoom(client.RoomID, &msg)
+// screen 以審核流程檢查消息，並套用頻道的慢速模式，msg.Content 會替換為審核後的內容
+// 消息被拒絕或發言過於頻繁時向客戶端發送錯誤並返回 false，暫緩的消息不計入慢速模式
+func (s *WebSocketService) screen(client *Client, msg *models.Message, policy ChannelPolicy) (ModerationVerdict, bool) {
+	verdict := s.moderation.Run(client, msg)
+	msg.Content = verdict.Content
+	switch verdict.Action {
+	case ModerationReject:
+		s.SendError(client, verdict.Reason)
+		return verdict, false
+	case ModerationHold:
+		return verdict, true
+	}
+
+	if policy.SlowMode > 0 {
+		now := time.Now()
+		if last, ok := client.lastPost[msg.Channel]; ok && now.Sub(last) < policy.SlowMode {
+			s.SendError(client, "發言過於頻繁，請稍後再試")
+			return verdict, false
+		}
+		client.lastPost[msg.Channel] = now
 	}
+	return verdict, true
+}
+
+// holdMessage 保存被暫緩的消息，通知發送者等待審核，並提醒房間主持人
+// 暫緩的消息不論頻道規則都會保存，以便主持人審核
+func (s *WebSocketService) holdMessage(client *Client, msg *models.Message, reason string) {
+	msg.Status = models.MessageStatusHeld
+	if err := s.messageRepo.Create(msg); err != nil {
+		log.Printf("message persist error: %v", err)
+		s.SendError(client, "消息保存失敗")
+		return
+	}
+
+	s.SendToClient(client, &models.Message{
+		Type:    "message_held",
+		RoomID:  client.RoomID,
+		Content: reason,
+		Data:    msg,
+	})
+	s.deliver(client.RoomID, outbound{
+		message: &models.Message{
+			Type:    "message_held",
+			RoomID:  client.RoomID,
+			Content: reason,
+			Data:    msg,
+		},
+		to: func(c *Client) bool {
+			return c.Moderator && c != client
+		},
+	})
+}
+
+// SendError 向單一客戶端發送錯誤消息
+func (s *WebSocketService) SendError(client *Client, content string) {
+	s.SendToClient(client, &models.Message{
+		Type:    "error",
+		RoomID:  client.RoomID,
+		Content: content,
+	})
 }
 
 // writePump 處理向客戶端發送消息的邏輯
 func (s *WebSocketService) writePump(client *Client) {
 	// 設置心跳檢查計時器
 	ticker := time.NewTicker(54 * time.Second)
-	defer ticker.Stop()
+	defer func() {
+		ticker.Stop()
+		// 被 hub 踢出時關閉連接，讓 readPump 結束並完成註銷
+		client.Conn.Close()
+	}()
 
 	for {
 		select {
@@ -140,66 +391,301 @@ func (s *WebSocketService) writePump(client *Client) {
 }
 
 // BroadcastToRoom 向房間內的所有客戶端廣播消息
+// 房間沒有在線客戶端時消息會被直接丟棄
 func (s *WebSocketService) BroadcastToRoom(roomID uint, message *models.Message) {
-	s.clientsMux.RLock()
-	clients := s.clients[roomID]
-	s.clientsMux.RUnlock()
+	s.deliver(roomID, outbound{message: message})
+}
 
-	for client := range clients {
-		select {
-		case client.SendChan <- message:
-			// 消息成功加入發送隊列
-		default:
-			// 客戶端消息隊列已滿，關閉連接
-			s.removeClient(client)
-			client.Conn.Close()
+// SendToModerators 向房間內擁有主持權限的客戶端發送消息
+func (s *WebSocketService) SendToModerators(roomID uint, message *models.Message) {
+	s.deliver(roomID, outbound{message: message, to: func(c *Client) bool {
+		return c.Moderator
+	}})
+}
+
+// SendToUser 向用戶在房間內的所有連接發送消息
+func (s *WebSocketService) SendToUser(roomID, userID uint, message *models.Message) {
+	s.deliver(roomID, outbound{message: message, to: func(c *Client) bool {
+		return c.UserID == userID
+	}})
+}
+
+// DisconnectUser 向用戶在房間內的所有連接發送最後一則消息後斷開連接
+func (s *WebSocketService) DisconnectUser(roomID, userID uint, message *models.Message) {
+	s.deliver(roomID, outbound{message: message, evict: true, to: func(c *Client) bool {
+		return c.UserID == userID
+	}})
+}
+
+// DisconnectRoom 向房間內的所有連接發送最後一則消息後斷開連接
+func (s *WebSocketService) DisconnectRoom(roomID uint, message *models.Message) {
+	s.deliver(roomID, outbound{message: message, evict: true})
+}
+
+// DisconnectUserEverywhere 斷開用戶在所有房間的連接，斷開前發送 frameType 類型的消息
+func (s *WebSocketService) DisconnectUserEverywhere(userID uint, frameType, content string) {
+	s.hubsMux.Lock()
+	roomIDs := make([]uint, 0, len(s.hubs))
+	for roomID := range s.hubs {
+		roomIDs = append(roomIDs, roomID)
+	}
+	s.hubsMux.Unlock()
+
+	for _, roomID := range roomIDs {
+		s.DisconnectUser(roomID, userID, &models.Message{Type: frameType, RoomID: roomID, Content: content})
+	}
+}
+
+// SendToClient 向單一客戶端發送消息，客戶端已離開時直接丟棄
+func (s *WebSocketService) SendToClient(client *Client, message *models.Message) {
+	s.deliver(client.RoomID, outbound{message: message, to: func(c *Client) bool {
+		return c == client
+	}})
+}
+
+// deliver 將消息交給房間的 hub 投遞
+func (s *WebSocketService) deliver(roomID uint, out outbound) {
+	hub := s.hub(roomID)
+	if hub == nil {
+		return
+	}
+
+	select {
+	case hub.broadcast <- out:
+	case <-hub.done:
+		// hub 已經結束，房間內沒有客戶端
+	}
+}
+
+// PublishMessage 依照房間賽制的頻道規則保存並廣播消息
+// 用於服務端產生的消息，不檢查發言權限
+func (s *WebSocketService) PublishMessage(msg *models.Message) error {
+	if msg.Channel == "" {
+		msg.Channel = models.ChannelFloor
+	}
+	if s.roomFormat(msg.RoomID).Policy(msg.Channel).Persist {
+		if err := s.messageRepo.Create(msg); err != nil {
+			return err
 		}
 	}
+	s.BroadcastToRoom(msg.RoomID, msg)
+	return nil
 }
 
-// BroadcastSystemMessage 發送系統消息到指定房間
+// BroadcastSystemMessage 發送系統消息到指定房間的辯論場
+// 賽制要求保存辯論場消息時，系統消息也會被寫入資料庫
 func (s *WebSocketService) BroadcastSystemMessage(roomID uint, content string) {
-	msg := &models.Message{
+	if err := s.PublishMessage(newSystemMessage(roomID, content)); err != nil {
+		log.Printf("system message persist error: %v", err)
+	}
+}
+
+// newSystemMessage 建立一則辯論場上的系統消息
+func newSystemMessage(roomID uint, content string) *models.Message {
+	return &models.Message{
 		Type:    "system",
 		Content: content,
 		RoomID:  roomID,
+		Role:    "system",
+		Channel: models.ChannelFloor,
 	}
-	s.BroadcastToRoom(roomID, msg)
 }
 
-// addClient 安全地添加新的客戶端連接
-func (s *WebSocketService) addClient(client *Client) {
-	s.clientsMux.Lock()
-	defer s.clientsMux.Unlock()
+// Mute 禁止用戶在房間內發言直到指定時間，重複禁言時以較晚的到期時間為準
+func (s *WebSocketService) Mute(roomID, userID uint, until time.Time) {
+	s.mutesMux.Lock()
+	defer s.mutesMux.Unlock()
 
-	if s.clients[client.RoomID] == nil {
-		s.clients[client.RoomID] = make(map[*Client]bool)
+	if s.mutes[roomID] == nil {
+		s.mutes[roomID] = make(map[uint]time.Time)
+	}
+	if until.After(s.mutes[roomID][userID]) {
+		s.mutes[roomID][userID] = until
 	}
-	s.clients[client.RoomID][client] = true
+}
+
+// Unmute 解除用戶在房間內的禁言
+func (s *WebSocketService) Unmute(roomID, userID uint) {
+	s.mutesMux.Lock()
+	defer s.mutesMux.Unlock()
 
-	// 發送用戶加入通知
-	s.BroadcastSystemMessage(client.RoomID,
-		fmt.Sprintf("用戶 %d 加入房間", client.UserID))
+	delete(s.mutes[roomID], userID)
 }
 
-// removeClient 安全地移除客戶端連接
-func (s *WebSocketService) removeClient(client *Client) {
-	s.clientsMux.Lock()
-	defer s.clientsMux.Unlock()
+// MutedUntil 返回用戶在房間內的禁言到期時間，未被禁言時 ok 為 false
+func (s *WebSocketService) MutedUntil(roomID, userID uint) (until time.Time, ok bool) {
+	s.mutesMux.Lock()
+	defer s.mutesMux.Unlock()
 
-	if clients, ok := s.clients[client.RoomID]; ok {
-		delete(clients, client)
-		// 如果房間空了，刪除房間
-		if len(clients) == 0 {
-			delete(s.clients, client.RoomID)
+	until, ok = s.mutes[roomID][userID]
+	if ok && !time.Now().Before(until) {
+		delete(s.mutes[roomID], userID)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// setGalleryHidden 透過 hub 切換客戶端是否接收觀眾席消息
+func (s *WebSocketService) setGalleryHidden(client *Client, hide bool) {
+	if hub := s.hub(client.RoomID); hub != nil {
+		select {
+		case hub.gallery <- galleryPreference{client: client, hide: hide}:
+		case <-hub.done:
 		}
 	}
 }
 
+// hub 返回房間目前的 hub，沒有在線客戶端時返回 nil
+func (s *WebSocketService) hub(roomID uint) *roomHub {
+	s.hubsMux.Lock()
+	defer s.hubsMux.Unlock()
+
+	return s.hubs[roomID]
+}
+
+// roomFormat 查詢房間的辯論賽制，查詢失敗時使用預設賽制
+func (s *WebSocketService) roomFormat(roomID uint) *DebateFormat {
+	room, err := s.roomRepo.FindByID(roomID)
+	if err != nil {
+		return GetDebateFormat(DefaultFormat)
+	}
+	return GetDebateFormat(room.Format)
+}
+
+// addClient 將客戶端註冊到所屬房間的 hub，必要時啟動新的 hub
+func (s *WebSocketService) addClient(client *Client) {
+	s.hubsMux.Lock()
+	hub := s.hubs[client.RoomID]
+	if hub == nil {
+		hub = newRoomHub(client.RoomID)
+		s.hubs[client.RoomID] = hub
+		go hub.run()
+	}
+	// 在釋放鎖之前增加引用計數，確保 hub 在註冊完成前不會被停止
+	hub.refs++
+	s.hubsMux.Unlock()
+
+	hub.register <- client
+}
+
+// removeClient 將客戶端從所屬房間的 hub 註銷，房間空了就停止 hub
+func (s *WebSocketService) removeClient(client *Client) {
+	hub := s.hub(client.RoomID)
+	if hub == nil {
+		return
+	}
+
+	// 引用計數未歸零前 hub 不會結束，這裡可以安全地阻塞發送
+	hub.unregister <- client
+
+	s.hubsMux.Lock()
+	defer s.hubsMux.Unlock()
+
+	hub.refs--
+	if hub.refs == 0 {
+		delete(s.hubs, client.RoomID)
+		close(hub.quit)
+	}
+}
+
 // GetRoomClients 獲取指定房間的在線客戶端數量
 func (s *WebSocketService) GetRoomClients(roomID uint) int {
-	s.clientsMux.RLock()
-	defer s.clientsMux.RUnlock()
+	s.hubsMux.Lock()
+	defer s.hubsMux.Unlock()
+
+	if hub := s.hubs[roomID]; hub != nil {
+		return hub.refs
+	}
+	return 0
+}
+
+// newRoomHub 創建房間 hub，需要再呼叫 run 才會開始處理命令
+func newRoomHub(roomID uint) *roomHub {
+	return &roomHub{
+		roomID:     roomID,
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		broadcast:  make(chan outbound),
+		gallery:    make(chan galleryPreference),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// run 是 hub 的主循環，clients 只在這個 goroutine 中存取
+// 所有命令 channel 都不帶緩衝，發送方返回時命令已被 hub 接收，
+// 因此同一個 goroutine 先廣播再註銷時，消息一定會先送達
+func (h *roomHub) run() {
+	defer close(h.done)
+
+	clients := make(map[*Client]bool)
+
+	for {
+		select {
+		case client := <-h.register:
+			clients[client] = true
+			// 發送用戶加入通知
+			h.deliver(clients, outbound{message: newSystemMessage(h.roomID, client.displayName()+" 加入房間")})
+
+		case client := <-h.unregister:
+			// 已被踢出的客戶端不在集合中，SendChan 也已經關閉
+			if clients[client] {
+				delete(clients, client)
+				close(client.SendChan)
+			}
+
+		case out := <-h.broadcast:
+			h.deliver(clients, out)
 
-	return len(s.clients[roomID])
+		case pref := <-h.gallery:
+			if clients[pref.client] {
+				pref.client.hideGallery = pref.hide
+			}
+
+		case <-h.quit:
+			for client := range clients {
+				close(client.SendChan)
+			}
+			return
+		}
+	}
+}
+
+// deliver 將消息放入符合條件的客戶端的發送隊列，隱藏觀眾席的客戶端不會收到觀眾席消息
+func (h *roomHub) deliver(clients map[*Client]bool, out outbound) {
+	for client := range clients {
+		if out.to != nil && !out.to(client) {
+			continue
+		}
+		if client.hideGallery && out.message.Channel == models.ChannelGallery {
+			continue
+		}
+		h.send(clients, client, out.message)
+		// 關閉 SendChan 前已排隊的消息仍會由 writePump 送出
+		if out.evict && clients[client] {
+			delete(clients, client)
+			close(client.SendChan)
+		}
+	}
+}
+
+// send 將消息放入單一客戶端的發送隊列
+// 隊列已滿的客戶端視為過慢，會被移出房間並關閉其 SendChan，
+// 其 writePump 隨後關閉連接，readPump 結束後再照常註銷
+func (h *roomHub) send(clients map[*Client]bool, client *Client, message *models.Message) {
+	select {
+	case client.SendChan <- message:
+		// 消息成功加入發送隊列
+	default:
+		delete(clients, client)
+		close(client.SendChan)
+	}
+}
+
+// displayName 返回客戶端的顯示名稱，沒有名稱時以 ID 表示
+func (c *Client) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return fmt.Sprintf("用戶 %d", c.UserID)
 }
@@ -0,0 +1,658 @@
+package service
+
+import (
+	"debate_web/internal/repository"
+	"debate_web/internal/repository/models"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryRoomRepository 是只保存在記憶體中的 RoomRepository
+type memoryRoomRepository struct {
+	mu    sync.Mutex
+	rooms map[uint]*models.Room
+}
+
+func (r *memoryRoomRepository) Create(room *models.Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	room.ID = uint(len(r.rooms) + 1)
+	r.rooms[room.ID] = room
+	return nil
+}
+
+func (r *memoryRoomRepository) FindByID(id uint) (*models.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	room, ok := r.rooms[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *room
+	return &copied, nil
+}
+
+func (r *memoryRoomRepository) Update(room *models.Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.rooms[room.ID]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	r.rooms[room.ID] = room
+	return nil
+}
+
+func (r *memoryRoomRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.rooms, id)
+	return nil
+}
+
+func (r *memoryRoomRepository) FindAll() ([]models.Room, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rooms []models.Room
+	for _, room := range r.rooms {
+		rooms = append(rooms, *room)
+	}
+	return rooms, nil
+}
+
+func (r *memoryRoomRepository) FindByDebater(userID uint) ([]models.Room, error) {
+	rooms, _ := r.FindAll()
+	var found []models.Room
+	for _, room := range rooms {
+		if room.ProponentID == userID || room.OpponentID == userID {
+			found = append(found, room)
+		}
+	}
+	return found, nil
+}
+
+// memoryMessageRepository 是只保存在記憶體中的 MessageRepository
+type memoryMessageRepository struct {
+	mu       sync.Mutex
+	messages []models.Message
+}
+
+func (r *memoryMessageRepository) Create(message *models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if message.RoomID == 0 {
+		return errors.New("room id is required")
+	}
+	message.ID = uint(len(r.messages) + 1)
+	r.messages = append(r.messages, *message)
+	return nil
+}
+
+func (r *memoryMessageRepository) FindByRoom(roomID uint) ([]models.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var messages []models.Message
+	for _, message := range r.messages {
+		if message.RoomID == roomID && message.Status == models.MessageStatusPublished && !message.DeletedAt.Valid {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (r *memoryMessageRepository) FindByID(id uint) (*models.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.messages) || r.messages[id-1].DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	message := r.messages[id-1]
+	return &message, nil
+}
+
+func (r *memoryMessageRepository) EachByRoom(roomID uint, batchSize int, fn func([]models.Message) error) error {
+	messages, _ := r.FindByRoom(roomID)
+	for len(messages) > 0 {
+		n := min(batchSize, len(messages))
+		if err := fn(messages[:n]); err != nil {
+			return err
+		}
+		messages = messages[n:]
+	}
+	return nil
+}
+
+func (r *memoryMessageRepository) FindByIDs(ids []uint) ([]models.Message, error) {
+	var messages []models.Message
+	for _, id := range ids {
+		if message, err := r.FindByID(id); err == nil {
+			messages = append(messages, *message)
+		}
+	}
+	return messages, nil
+}
+
+func (r *memoryMessageRepository) FindHeldByRoom(roomID uint) ([]models.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var messages []models.Message
+	for _, message := range r.messages {
+		if message.RoomID == roomID && message.Status == models.MessageStatusHeld && !message.DeletedAt.Valid {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+func (r *memoryMessageRepository) FindBefore(roomID, messageID uint, limit int) ([]models.Message, error) {
+	messages, _ := r.FindByRoom(roomID)
+	n := 0
+	for n < len(messages) && messages[n].ID < messageID {
+		n++
+	}
+	return messages[max(n-limit, 0):n], nil
+}
+
+func (r *memoryMessageRepository) UpdateStatus(id uint, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[id-1].Status = status
+	return nil
+}
+
+func (r *memoryMessageRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[id-1].DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	return nil
+}
+
+// Search 需要 Postgres 的全文搜尋，記憶體版本不支援
+func (r *memoryMessageRepository) Search(filter repository.MessageSearchFilter) ([]repository.MessageSearchResult, error) {
+	return nil, errors.New("search is not supported")
+}
+
+func (r *memoryMessageRepository) IndexMissing(batchSize int) error {
+	return nil
+}
+
+// memoryUserRepository 是只保存在記憶體中的 UserRepository
+type memoryUserRepository struct {
+	mu    sync.Mutex
+	users []*models.User
+}
+
+func (r *memoryUserRepository) Create(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user.ID = uint(len(r.users) + 1)
+	r.users = append(r.users, user)
+	return nil
+}
+
+func (r *memoryUserRepository) FindByID(id uint) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	user := *r.users[id-1]
+	return &user, nil
+}
+
+func (r *memoryUserRepository) FindByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	for _, id := range ids {
+		if user, err := r.FindByID(id); err == nil {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
+func (r *memoryUserRepository) FindByUsername(username string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Username == username {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) FindByEmail(email string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if user.Email != nil && *user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) UpdateEmail(id uint, email *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[id-1].Email = email
+	r.users[id-1].EmailVerifiedAt = nil
+	return nil
+}
+
+func (r *memoryUserRepository) MarkEmailVerified(id uint, email string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user := r.users[id-1]
+	if user.Email == nil || *user.Email != email {
+		return false, nil
+	}
+	user.EmailVerifiedAt = &at
+	return true, nil
+}
+
+func (r *memoryUserRepository) Update(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user.ID == 0 || int(user.ID) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	r.users[user.ID-1] = user
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateProfile(user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if user.ID == 0 || int(user.ID) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	stored := r.users[user.ID-1]
+	stored.DisplayName = user.DisplayName
+	stored.Bio = user.Bio
+	stored.AvatarKey = user.AvatarKey
+	stored.Language = user.Language
+	stored.PreferredSides = user.PreferredSides
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateRole(id uint, role models.UserRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	r.users[id-1].Role = role
+	return nil
+}
+
+func (r *memoryUserRepository) UpdatePassword(id uint, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	r.users[id-1].Password = hash
+	return nil
+}
+
+func (r *memoryUserRepository) SetDisabled(id uint, disabledAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	r.users[id-1].DisabledAt = disabledAt
+	return nil
+}
+
+func (r *memoryUserRepository) Search(filter repository.UserSearchFilter) ([]models.User, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matched []models.User
+	for _, user := range r.users {
+		if filter.Query != "" && !strings.Contains(strings.ToLower(user.Username), strings.ToLower(filter.Query)) {
+			continue
+		}
+		if filter.Role != "" && user.Role != filter.Role {
+			continue
+		}
+		if filter.Disabled != nil && (user.DisabledAt != nil) != *filter.Disabled {
+			continue
+		}
+		matched = append(matched, *user)
+	}
+	total := int64(len(matched))
+	if filter.Offset >= len(matched) {
+		return nil, total, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matched) {
+		matched = matched[:filter.Limit]
+	}
+	return matched, total, nil
+}
+
+func (r *memoryUserRepository) IncrementFailedLogins(id uint) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return 0, gorm.ErrRecordNotFound
+	}
+	r.users[id-1].FailedLogins++
+	return r.users[id-1].FailedLogins, nil
+}
+
+func (r *memoryUserRepository) Lock(id uint, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	user := r.users[id-1]
+	user.FailedLogins = 0
+	user.Lockouts++
+	user.LockedUntil = &until
+	return nil
+}
+
+func (r *memoryUserRepository) ResetLoginFailures(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == 0 || int(id) > len(r.users) {
+		return gorm.ErrRecordNotFound
+	}
+	user := r.users[id-1]
+	user.FailedLogins = 0
+	user.Lockouts = 0
+	user.LockedUntil = nil
+	return nil
+}
+
+// gormModel 建立只設定 ID 的 gorm.Model
+func gormModel(id uint) gorm.Model {
+	return gorm.Model{ID: id}
+}
+
+// newTestWebSocketService 建立使用記憶體 repository 的 WebSocketService，沒有任何用戶資料
+func newTestWebSocketService() (*WebSocketService, *memoryRoomRepository, *memoryMessageRepository) {
+	rooms := &memoryRoomRepository{rooms: make(map[uint]*models.Room)}
+	messages := &memoryMessageRepository{}
+	return NewWebSocketService(rooms, messages, &memoryUserRepository{}), rooms, messages
+}
+
+// newTestClient 建立沒有實際連接的客戶端，只透過 SendChan 觀察 hub 的行為
+func newTestClient(roomID, userID uint, buffer int) *Client {
+	return &Client{
+		UserID:   userID,
+		RoomID:   roomID,
+		Role:     "spectator",
+		Format:   GetDebateFormat(DefaultFormat),
+		SendChan: make(chan *models.Message, buffer),
+		lastPost: make(map[string]time.Time),
+	}
+}
+
+// drain 持續讀取客戶端的消息直到 SendChan 被關閉，返回收到的消息數
+func drain(client *Client) <-chan int {
+	count := make(chan int, 1)
+	go func() {
+		n := 0
+		for range client.SendChan {
+			n++
+		}
+		count <- n
+	}()
+	return count
+}
+
+func TestConcurrentJoinsAndBroadcasts(t *testing.T) {
+	s, _, _ := newTestWebSocketService()
+
+	const (
+		rooms      = 4
+		perRoom    = 50
+		broadcasts = 100
+	)
+
+	var wg sync.WaitGroup
+	for room := uint(1); room <= rooms; room++ {
+		for i := 0; i < perRoom; i++ {
+			wg.Add(1)
+			go func(roomID, userID uint) {
+				defer wg.Done()
+				client := newTestClient(roomID, userID, 16)
+				received := drain(client)
+				s.addClient(client)
+				time.Sleep(time.Millisecond)
+				s.removeClient(client)
+				<-received
+			}(room, uint(i))
+		}
+
+		for i := 0; i < broadcasts; i++ {
+			wg.Add(1)
+			go func(roomID uint) {
+				defer wg.Done()
+				s.BroadcastSystemMessage(roomID, "broadcast")
+			}(room)
+		}
+	}
+	wg.Wait()
+
+	for room := uint(1); room <= rooms; room++ {
+		if n := s.GetRoomClients(room); n != 0 {
+			t.Errorf("room %d: expected 0 clients after all left, got %d", room, n)
+		}
+	}
+
+	s.hubsMux.Lock()
+	defer s.hubsMux.Unlock()
+	if len(s.hubs) != 0 {
+		t.Errorf("expected all hubs to stop, %d still running", len(s.hubs))
+	}
+}
+
+func TestBroadcastReachesEveryClient(t *testing.T) {
+	s, _, _ := newTestWebSocketService()
+
+	const clients = 20
+	var received []<-chan int
+	var joined []*Client
+	for i := 0; i < clients; i++ {
+		client := newTestClient(1, uint(i), 256)
+		received = append(received, drain(client))
+		joined = append(joined, client)
+		s.addClient(client)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.BroadcastSystemMessage(1, "hello")
+		}()
+	}
+	wg.Wait()
+
+	for _, client := range joined {
+		s.removeClient(client)
+	}
+
+	// 第 i 個客戶端會收到自己及之後所有客戶端的加入通知，再加上 10 則廣播
+	for i, count := range received {
+		want := clients - i + 10
+		if got := <-count; got != want {
+			t.Errorf("client %d: expected %d messages, got %d", i, want, got)
+		}
+	}
+}
+
+func TestSlowClientIsEvicted(t *testing.T) {
+	s, _, _ := newTestWebSocketService()
+
+	// 緩衝只容得下自己的加入通知，下一則消息就會讓它被踢出
+	slow := newTestClient(1, 1, 1)
+	s.addClient(slow)
+
+	fast := newTestClient(1, 2, 64)
+	s.addClient(fast)
+
+	s.BroadcastSystemMessage(1, "hello")
+	// 第二次廣播被 hub 接收時，前一次的投遞已經完成
+	s.BroadcastSystemMessage(1, "sync")
+
+	// 被踢出的客戶端保留已緩衝的消息，之後 SendChan 被關閉
+	if _, ok := <-slow.SendChan; !ok {
+		t.Fatal("expected the buffered join notification")
+	}
+	if _, ok := <-slow.SendChan; ok {
+		t.Fatal("slow client was not evicted")
+	}
+
+	// 被踢出後再註銷不應重複關閉 SendChan
+	s.removeClient(slow)
+	if n := s.GetRoomClients(1); n != 1 {
+		t.Errorf("expected 1 client left, got %d", n)
+	}
+
+	received := drain(fast)
+	s.removeClient(fast)
+	// 自己的加入通知加上兩則廣播
+	if got := <-received; got != 3 {
+		t.Errorf("fast client: expected 3 messages, got %d", got)
+	}
+}
+
+func TestBroadcastToEmptyRoom(t *testing.T) {
+	s, _, _ := newTestWebSocketService()
+
+	done := make(chan struct{})
+	go func() {
+		s.BroadcastSystemMessage(42, "nobody here")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast to empty room blocked")
+	}
+}
+
+// next 讀取客戶端的下一則消息
+func next(t *testing.T, client *Client) *models.Message {
+	t.Helper()
+	select {
+	case msg, ok := <-client.SendChan:
+		if !ok {
+			t.Fatal("send channel closed")
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+	return nil
+}
+
+func TestChatMessageIgnoresServerFields(t *testing.T) {
+	s, rooms, messages := newTestWebSocketService()
+	room := &models.Room{ProponentID: 1}
+	rooms.Create(room)
+
+	debater := newTestClient(room.ID, 1, 16)
+	debater.Role = "proponent"
+	spectator := newTestClient(room.ID, 2, 16)
+	s.addClient(debater)
+	s.addClient(spectator)
+	defer s.removeClient(debater)
+	defer s.removeClient(spectator)
+
+	// 客戶端偽造伺服器消息的類型、時間戳記、刪除狀態和發送者
+	raw := `{"type":"message_deleted","Content":"我方認為","Kind":"claim",
+		"ID":42,"CreatedAt":"2001-01-01T00:00:00Z","UpdatedAt":"2001-01-01T00:00:00Z","DeletedAt":"2001-01-01T00:00:00Z",
+		"UserID":9,"Role":"system","Status":"held","Data":{"forged":true}}`
+	var frame ClientFrame
+	if err := json.Unmarshal([]byte(raw), &frame); err != nil {
+		t.Fatal(err)
+	}
+	s.handleFrame(debater, &frame)
+
+	check := func(where string, msg *models.Message) {
+		t.Helper()
+		if msg.Type != models.MessageTypeChat || msg.ID != 1 || msg.UserID != 1 || msg.Role != "proponent" ||
+			msg.Status != models.MessageStatusPublished || msg.Data != nil {
+			t.Errorf("%s: message = %+v", where, msg)
+		}
+		if !msg.CreatedAt.IsZero() || !msg.UpdatedAt.IsZero() || msg.DeletedAt.Valid {
+			t.Errorf("%s: client timestamps kept: created %v, updated %v, deleted %v", where, msg.CreatedAt, msg.UpdatedAt, msg.DeletedAt)
+		}
+		if msg.Content != "我方認為" || msg.Kind != models.ArgumentClaim || msg.Channel != models.ChannelFloor {
+			t.Errorf("%s: client fields lost: %+v", where, msg)
+		}
+	}
+
+	if len(messages.messages) != 1 {
+		t.Fatalf("saved %d messages, want 1", len(messages.messages))
+	}
+	check("saved", &messages.messages[0])
+	check("broadcast", nextNonSystem(spectator))
+}
+
+func TestGalleryChannel(t *testing.T) {
+	s, rooms, messages := newTestWebSocketService()
+	room := &models.Room{Format: "standard"}
+	rooms.Create(room)
+
+	debater := newTestClient(room.ID, 1, 16)
+	debater.Role = "proponent"
+	spectator := newTestClient(room.ID, 2, 16)
+	s.addClient(debater)
+	s.addClient(spectator)
+	next(t, debater) // 自己的加入通知
+	next(t, debater) // 觀眾的加入通知
+	next(t, spectator)
+
+	// 辯手隱藏觀眾席後只會收到辯論場消息
+	s.handleFrame(debater, &ClientFrame{Message: models.Message{Type: "gallery_visibility", Content: "hide"}})
+	s.handleMessage(spectator, &models.Message{Type: "text", Channel: models.ChannelGallery, Content: "加油"})
+	s.handleMessage(debater, &models.Message{Type: "text", Content: "我方認為"})
+
+	if msg := next(t, spectator); msg.Channel != models.ChannelGallery {
+		t.Errorf("spectator: expected gallery message, got %q", msg.Channel)
+	}
+	if msg := next(t, spectator); msg.Channel != models.ChannelFloor || msg.Role != "proponent" {
+		t.Errorf("spectator: expected floor message from proponent, got %+v", msg)
+	}
+	if msg := next(t, debater); msg.Channel != models.ChannelFloor {
+		t.Errorf("debater: expected only the floor message, got %q", msg.Channel)
+	}
+
+	// 觀眾不能在辯論場發言，錯誤只發送給發送者
+	s.handleMessage(spectator, &models.Message{Type: "text", Channel: models.ChannelFloor, Content: "插話"})
+	if msg := next(t, spectator); msg.Type != "error" {
+		t.Errorf("expected error frame, got %+v", msg)
+	}
+
+	// 慢速模式下連續發言會被拒絕
+	s.handleMessage(spectator, &models.Message{Type: "text", Channel: models.ChannelGallery, Content: "再來"})
+	if msg := next(t, spectator); msg.Type != "error" {
+		t.Errorf("expected slow mode error, got %+v", msg)
+	}
+
+	stored, _ := messages.FindByRoom(room.ID)
+	if len(stored) != 2 {
+		t.Errorf("expected 2 persisted messages, got %d", len(stored))
+	}
+
+	s.removeClient(debater)
+	s.removeClient(spectator)
+}
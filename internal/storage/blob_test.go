@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	store, err := NewLocalBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put("avatars/1/a.png", strings.NewReader("data")); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	file, err := store.Open("avatars/1/a.png")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if string(data) != "data" {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	if err := store.Delete("avatars/1/a.png"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Open("avatars/1/a.png"); !errors.Is(err, ErrBlobNotFound) {
+		t.Fatalf("expected ErrBlobNotFound, got %v", err)
+	}
+
+	for _, key := range []string{"../escape", "a/../../b", "/abs", "", `a\b`} {
+		if err := store.Put(key, strings.NewReader("x")); err == nil {
+			t.Fatalf("expected key %q to be rejected", key)
+		}
+	}
+}
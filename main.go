@@ -2,11 +2,14 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"debate_web/internal/api"
 	"debate_web/internal/config"
+	"debate_web/internal/mail"
+	"debate_web/internal/middleware"
 	"debate_web/internal/repository"
 	"debate_web/internal/repository/models"
 	"debate_web/internal/service"
@@ -28,20 +31,85 @@ func main() {
 	defer db.Close()
 
 	// 自動遷移數據庫結構
-	if err := db.AutoMigrate(&models.User{}, &models.Room{}, &models.Message{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Room{}, &models.Message{}, &models.Question{}, &models.QuestionVote{}, &models.Reaction{}, &models.MessageReport{}, &models.Sanction{}, &models.RateLimitCounter{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.UserToken{}, &models.RecoveryCode{}, &models.UserIdentity{}, &models.OIDCState{}); err != nil {
 		log.Fatalf("Failed to auto migrate database: %v", err)
 	}
 
 	// 初始化 repositories
 	repos := repository.NewRepositories(db)
 
+	// 為升級前寫入的消息補建全文搜尋索引
+	if err := repos.Message.IndexMissing(500); err != nil {
+		log.Fatalf("Failed to index messages: %v", err)
+	}
+
+	// 載入 JWT 金鑰
+	keys, err := service.NewJWTKeySet(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	// 上傳檔案的存放位置
+	var blobs storage.BlobStore
+	switch cfg.Blob.Driver {
+	case "", "local":
+		if blobs, err = storage.NewLocalBlobStore(cfg.Blob.Dir); err != nil {
+			log.Fatalf("Failed to initialize blob store: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown blob driver: %s", cfg.Blob.Driver)
+	}
+
+	// 寄送郵件的方式
+	var mailer mail.Mailer
+	switch cfg.Mail.Driver {
+	case "", "log":
+		mailer = mail.LogMailer{}
+	case "file":
+		if mailer, err = mail.NewFileMailer(cfg.Mail.From, cfg.Mail.Dir); err != nil {
+			log.Fatalf("Failed to initialize mailer: %v", err)
+		}
+	case "smtp":
+		smtp := cfg.Mail.SMTP
+		if mailer, err = mail.NewSMTPMailer(smtp.Host, smtp.Port, smtp.Username, smtp.Password, smtp.Security, cfg.Mail.From); err != nil {
+			log.Fatalf("Failed to initialize mailer: %v", err)
+		}
+	default:
+		log.Fatalf("Unknown mail driver: %s", cfg.Mail.Driver)
+	}
+
 	// 初始化 services
-	services := service.NewServices(repos)
+	services := service.NewServices(repos, cfg, keys, blobs, mailer)
+
+	// 設定檔中指定的管理員
+	if err := services.User.PromoteAdmins(cfg.Auth.Admins); err != nil {
+		log.Fatalf("Failed to promote admins: %v", err)
+	}
+
+	// 選擇限流計數的存放位置，多個實例部署時使用資料庫共用計數
+	var limitStore middleware.RateLimitStore
+	switch cfg.RateLimit.HTTP.Store {
+	case "", "memory":
+		limitStore = middleware.NewMemoryRateLimitStore()
+	case "postgres":
+		limitStore = repos.RateLimit
+	default:
+		log.Fatalf("Unknown rate limit store: %s", cfg.RateLimit.HTTP.Store)
+	}
+	go middleware.PruneRateLimits(limitStore, time.Minute)
+
+	authLimit := middleware.RateLimit(limitStore,
+		middleware.LimitByIP(cfg.RateLimit.HTTP.IP),
+		middleware.LimitByUsername(cfg.RateLimit.HTTP.Username),
+	)
 
 	// 設置 Gin 路由
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
-	api.SetupRoutes(r, services)
+	api.SetupRoutes(r, services, authLimit)
 
 	// 啟動服務器
 	if err := r.Run(cfg.Server.Address); err != nil {